@@ -0,0 +1,43 @@
+// Command auditverify checks an audit log exported by audit.Service.Export
+// against a directory of public keys, without access to the service:
+//
+//	auditverify -keys ./keys audit-log.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/infrastructure/signing"
+)
+
+func main() {
+	keyDir := flag.String("keys", "keys", "directory containing the <key-id>.pub files")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: auditverify -keys DIR EXPORTED-LOG")
+		os.Exit(2)
+	}
+
+	keys, err := signing.LoadPublicKeys(*keyDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	checkpoint, verified, err := audit.VerifyExport(file, keys)
+	if err != nil {
+		log.Fatalf("verification failed: %v", err)
+	}
+
+	fmt.Printf("ok: %d entries verified up to sequence %d, checkpoint signed by %s at %s\n",
+		verified, checkpoint.Sequence, checkpoint.KeyID, checkpoint.CreatedAt.Format("2006-01-02 15:04:05"))
+}
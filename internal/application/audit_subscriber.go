@@ -0,0 +1,150 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"go-ddd/internal/application/eventbus"
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/domain/payment"
+)
+
+type auditRepositoryKey struct{}
+
+type userIDKey struct{}
+
+func withAuditRepository(ctx context.Context, audits audit.Repository) context.Context {
+	return context.WithValue(ctx, auditRepositoryKey{}, audits)
+}
+
+func withUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+func userIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	return userID
+}
+
+// AuditSubscriber records an audit entry for every payment event. Subscribed
+// in sync mode it writes through the publishing transaction's audit
+// repository, so entries commit or roll back with the payment change.
+type AuditSubscriber struct {
+	audits *audit.Service
+}
+
+// NewAuditSubscriber records with audits, bound to the transaction's
+// repository when there is one, so its options such as signing apply.
+func NewAuditSubscriber(audits *audit.Service) *AuditSubscriber {
+	return &AuditSubscriber{
+		audits: audits,
+	}
+}
+
+func (s *AuditSubscriber) Subscribe(bus *eventbus.Bus) (unsubscribe func()) {
+	unsubscribers := []func(){
+		eventbus.Subscribe(bus, eventbus.Sync, s.onCreated),
+		eventbus.Subscribe(bus, eventbus.Sync, func(ctx context.Context, e payment.PaymentProcessed) error {
+			return s.statusChanged(ctx, e.Header(), e.StatusChange)
+		}),
+		eventbus.Subscribe(bus, eventbus.Sync, func(ctx context.Context, e payment.PaymentCompleted) error {
+			return s.statusChanged(ctx, e.Header(), e.StatusChange)
+		}),
+		eventbus.Subscribe(bus, eventbus.Sync, func(ctx context.Context, e payment.PaymentFailed) error {
+			return s.statusChanged(ctx, e.Header(), e.StatusChange)
+		}),
+		eventbus.Subscribe(bus, eventbus.Sync, func(ctx context.Context, e payment.PaymentCancelled) error {
+			return s.statusChanged(ctx, e.Header(), e.StatusChange)
+		}),
+		eventbus.Subscribe(bus, eventbus.Sync, func(ctx context.Context, e payment.PaymentVoided) error {
+			return s.statusChanged(ctx, e.Header(), e.StatusChange)
+		}),
+		eventbus.Subscribe(bus, eventbus.Sync, s.onAuthorized),
+		eventbus.Subscribe(bus, eventbus.Sync, s.onCaptured),
+		eventbus.Subscribe(bus, eventbus.Sync, s.onRefunded),
+	}
+
+	return func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}
+}
+
+func (s *AuditSubscriber) service(ctx context.Context) *audit.Service {
+	if repository, ok := ctx.Value(auditRepositoryKey{}).(audit.Repository); ok {
+		return s.audits.WithRepository(repository)
+	}
+	return s.audits
+}
+
+func (s *AuditSubscriber) onCreated(ctx context.Context, e payment.PaymentCreated) error {
+	paymentData := map[string]interface{}{
+		"id":           e.AggregateID(),
+		"amount":       e.Amount.Decimal(),
+		"amount_minor": e.Amount.MinorUnits(),
+		"currency":     e.Amount.Currency(),
+		"description":  e.Description,
+		"status":       payment.PaymentStatusPending.String(),
+		"created_at":   e.OccurredAt,
+	}
+
+	return recorded(s.service(ctx).RecordPaymentCreated(ctx, e.AggregateID(), userIDFromContext(ctx), paymentData))
+}
+
+func (s *AuditSubscriber) statusChanged(ctx context.Context, header payment.EventHeader, change payment.StatusChange) error {
+	return recorded(s.service(ctx).RecordPaymentStatusChange(ctx, header.AggregateID(), userIDFromContext(ctx), change.From.String(), change.To.String()))
+}
+
+func (s *AuditSubscriber) onAuthorized(ctx context.Context, e payment.PaymentAuthorized) error {
+	oldData := map[string]interface{}{"status": e.From.String()}
+	newData := map[string]interface{}{
+		"status":     e.To.String(),
+		"amount":     e.Amount.Decimal(),
+		"currency":   e.Amount.Currency(),
+		"expires_at": e.ExpiresAt,
+	}
+
+	return recorded(s.service(ctx).RecordPaymentAuthorized(ctx, e.AggregateID(), userIDFromContext(ctx), oldData, newData))
+}
+
+func (s *AuditSubscriber) onCaptured(ctx context.Context, e payment.PaymentCaptured) error {
+	oldData := map[string]interface{}{"status": e.From.String()}
+	newData := map[string]interface{}{
+		"status":            e.To.String(),
+		"authorized_amount": e.Authorized.Decimal(),
+		"captured_amount":   e.Amount.Decimal(),
+		"currency":          e.Amount.Currency(),
+	}
+
+	return recorded(s.service(ctx).RecordPaymentCaptured(ctx, e.AggregateID(), userIDFromContext(ctx), oldData, newData))
+}
+
+func (s *AuditSubscriber) onRefunded(ctx context.Context, e payment.PaymentRefunded) error {
+	before, err := e.TotalRefunded.Subtract(e.Amount)
+	if err != nil {
+		return err
+	}
+
+	oldData := map[string]interface{}{
+		"status":          e.From.String(),
+		"refunded_amount": before.Decimal(),
+	}
+	newData := map[string]interface{}{
+		"status":          e.To.String(),
+		"refunded_amount": e.TotalRefunded.Decimal(),
+		"refund_id":       e.RefundID.String(),
+		"refund_amount":   e.Amount.Decimal(),
+		"currency":        e.Amount.Currency(),
+		"reason":          e.Reason,
+	}
+
+	return recorded(s.service(ctx).RecordPaymentRefunded(ctx, e.AggregateID(), userIDFromContext(ctx), oldData, newData))
+}
+
+func recorded(err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to record audit: %w", err)
+	}
+	return nil
+}
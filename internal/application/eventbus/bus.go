@@ -0,0 +1,290 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+)
+
+var ErrClosed = errors.New("event bus is closed")
+
+// Event is anything the bus can deliver. AggregateID keys ordering: async
+// handlers see the events of one aggregate in the order they were published.
+type Event interface {
+	EventType() string
+	AggregateID() string
+}
+
+type Handler func(ctx context.Context, event Event) error
+
+type Mode int
+
+const (
+	// Sync handlers run in the publisher's goroutine and their errors are
+	// returned to it.
+	Sync Mode = iota
+	// Async handlers run on the bus's workers after Publish returns; their
+	// errors go to the bus's error handler.
+	Async
+)
+
+const (
+	defaultWorkers   = 4
+	defaultQueueSize = 256
+)
+
+type subscription struct {
+	id      int
+	mode    Mode
+	matches func(Event) bool
+	handler Handler
+}
+
+type delivery struct {
+	ctx   context.Context
+	event Event
+}
+
+// Bus is an in-process publish/subscribe hub. Each handler is isolated from
+// the others: an error or panic in one does not stop delivery to the rest.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+	nextID        int
+
+	// closeMu guards closed and sending on queues; workers never take it, so
+	// a publisher blocked on a full queue cannot deadlock Close.
+	closeMu sync.RWMutex
+	closed  bool
+
+	queues    []chan delivery
+	workers   sync.WaitGroup
+	onError   func(event Event, err error)
+	queueSize int
+}
+
+type Option func(*Bus)
+
+// WithWorkers sets how many goroutines deliver async events. Events for the
+// same aggregate always go to the same worker. n below 1 keeps the default.
+func WithWorkers(n int) Option {
+	return func(b *Bus) {
+		if n > 0 {
+			b.queues = make([]chan delivery, n)
+		}
+	}
+}
+
+// WithQueueSize sets how many async events each worker buffers before
+// Publish blocks. n below 1 keeps the default.
+func WithQueueSize(n int) Option {
+	return func(b *Bus) {
+		if n > 0 {
+			b.queueSize = n
+		}
+	}
+}
+
+// WithErrorHandler receives errors returned by async handlers and those
+// passed to ReportUndelivered. By default they are logged.
+func WithErrorHandler(onError func(event Event, err error)) Option {
+	return func(b *Bus) {
+		b.onError = onError
+	}
+}
+
+func New(opts ...Option) *Bus {
+	b := &Bus{
+		queues:    make([]chan delivery, defaultWorkers),
+		queueSize: defaultQueueSize,
+		onError: func(event Event, err error) {
+			log.Printf("eventbus: %s handler for %s failed: %v", event.EventType(), event.AggregateID(), err)
+		},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	for i := range b.queues {
+		b.queues[i] = make(chan delivery, b.queueSize)
+		b.workers.Add(1)
+		go b.work(b.queues[i])
+	}
+
+	return b
+}
+
+// Subscribe registers handler for every published event of type T.
+func Subscribe[T Event](b *Bus, mode Mode, handler func(ctx context.Context, event T) error) (unsubscribe func()) {
+	return b.subscribe(mode,
+		func(event Event) bool {
+			_, ok := event.(T)
+			return ok
+		},
+		func(ctx context.Context, event Event) error {
+			return handler(ctx, event.(T))
+		},
+	)
+}
+
+// SubscribeType registers handler for events whose EventType is eventType.
+func (b *Bus) SubscribeType(eventType string, mode Mode, handler Handler) (unsubscribe func()) {
+	return b.subscribe(mode, func(event Event) bool { return event.EventType() == eventType }, handler)
+}
+
+func (b *Bus) subscribe(mode Mode, matches func(Event) bool, handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.subscriptions = append(b.subscriptions, subscription{id: id, mode: mode, matches: matches, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for i, s := range b.subscriptions {
+			if s.id == id {
+				b.subscriptions = append(b.subscriptions[:i:i], b.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers events to sync handlers and then queues them for async
+// handlers. Publishers inside a transaction should instead call PublishSync
+// within it and PublishAsync once it has committed.
+func (b *Bus) Publish(ctx context.Context, events ...Event) error {
+	if err := b.PublishSync(ctx, events...); err != nil {
+		return err
+	}
+	return b.PublishAsync(ctx, events...)
+}
+
+// PublishSync runs the sync handlers for events in order and returns all of
+// their errors joined.
+func (b *Bus) PublishSync(ctx context.Context, events ...Event) error {
+	b.closeMu.RLock()
+	closed := b.closed
+	b.closeMu.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+
+	subscriptions := b.snapshot()
+
+	var errs []error
+	for _, event := range events {
+		for _, s := range subscriptions {
+			if s.mode != Sync || !s.matches(event) {
+				continue
+			}
+			if err := call(ctx, s.handler, event); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// PublishAsync queues events for async handlers. The handlers get a context
+// that keeps ctx's values but not its cancellation.
+func (b *Bus) PublishAsync(ctx context.Context, events ...Event) error {
+	b.closeMu.RLock()
+	defer b.closeMu.RUnlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+
+	detached := context.WithoutCancel(ctx)
+	for _, event := range events {
+		queue := b.queues[shard(event.AggregateID(), len(b.queues))]
+		select {
+		case queue <- delivery{ctx: detached, event: event}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// ReportUndelivered passes err to the error handler for each of events, for
+// publishers that can no longer fail because of it, such as one whose
+// transaction has already committed when PublishAsync fails.
+func (b *Bus) ReportUndelivered(err error, events ...Event) {
+	for _, event := range events {
+		b.onError(event, err)
+	}
+}
+
+// Close stops accepting events and waits for queued async deliveries to
+// finish, or for ctx to end.
+func (b *Bus) Close(ctx context.Context) error {
+	b.closeMu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, queue := range b.queues {
+			close(queue)
+		}
+	}
+	b.closeMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bus) work(queue chan delivery) {
+	defer b.workers.Done()
+
+	for d := range queue {
+		for _, s := range b.snapshot() {
+			if s.mode != Async || !s.matches(d.event) {
+				continue
+			}
+			if err := call(d.ctx, s.handler, d.event); err != nil {
+				b.onError(d.event, err)
+			}
+		}
+	}
+}
+
+func (b *Bus) snapshot() []subscription {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.subscriptions
+}
+
+// call runs handler, turning a panic into an error.
+func call(ctx context.Context, handler Handler, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler for %s panicked: %v", event.EventType(), r)
+		}
+	}()
+	return handler(ctx, event)
+}
+
+func shard(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,203 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type orderPlaced struct {
+	orderID string
+	seq     int
+}
+
+func (orderPlaced) EventType() string     { return "order.placed" }
+func (e orderPlaced) AggregateID() string { return e.orderID }
+
+type orderShipped struct {
+	orderID string
+}
+
+func (orderShipped) EventType() string     { return "order.shipped" }
+func (e orderShipped) AggregateID() string { return e.orderID }
+
+func TestBus_PublishSync(t *testing.T) {
+	bus := New()
+	defer bus.Close(context.Background())
+	ctx := context.Background()
+
+	var typed []int
+	Subscribe(bus, Sync, func(ctx context.Context, e orderPlaced) error {
+		typed = append(typed, e.seq)
+		return nil
+	})
+	var byName []string
+	bus.SubscribeType("order.shipped", Sync, func(ctx context.Context, e Event) error {
+		byName = append(byName, e.AggregateID())
+		return nil
+	})
+
+	if err := bus.PublishSync(ctx, orderPlaced{orderID: "o-1", seq: 1}, orderShipped{orderID: "o-1"}, orderPlaced{orderID: "o-2", seq: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(typed, []int{1, 2}) {
+		t.Errorf("expected typed handler to see [1 2], got %v", typed)
+	}
+	if !reflect.DeepEqual(byName, []string{"o-1"}) {
+		t.Errorf("expected named handler to see [o-1], got %v", byName)
+	}
+}
+
+func TestBus_IsolatesHandlerFailures(t *testing.T) {
+	bus := New()
+	defer bus.Close(context.Background())
+
+	failure := errors.New("handler failed")
+	Subscribe(bus, Sync, func(ctx context.Context, e orderPlaced) error { return failure })
+	Subscribe(bus, Sync, func(ctx context.Context, e orderPlaced) error { panic("boom") })
+	calls := 0
+	Subscribe(bus, Sync, func(ctx context.Context, e orderPlaced) error {
+		calls++
+		return nil
+	})
+
+	err := bus.PublishSync(context.Background(), orderPlaced{orderID: "o-1"})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected handler error to be returned, got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "panicked: boom") {
+		t.Errorf("expected panic to be reported, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected remaining handler to run once, got %d", calls)
+	}
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := New()
+	defer bus.Close(context.Background())
+
+	calls := 0
+	unsubscribe := Subscribe(bus, Sync, func(ctx context.Context, e orderPlaced) error {
+		calls++
+		return nil
+	})
+	bus.PublishSync(context.Background(), orderPlaced{orderID: "o-1"})
+	unsubscribe()
+	bus.PublishSync(context.Background(), orderPlaced{orderID: "o-1"})
+
+	if calls != 1 {
+		t.Errorf("expected 1 call before unsubscribing, got %d", calls)
+	}
+}
+
+func TestBus_AsyncPreservesOrderPerAggregate(t *testing.T) {
+	var mu sync.Mutex
+	var failed []error
+	bus := New(WithWorkers(3), WithQueueSize(1), WithErrorHandler(func(event Event, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, err)
+	}))
+
+	seen := make(map[string][]int)
+	Subscribe(bus, Async, func(ctx context.Context, e orderPlaced) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[e.orderID] = append(seen[e.orderID], e.seq)
+		if e.seq == 0 {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+	syncCalls := 0
+	Subscribe(bus, Sync, func(ctx context.Context, e orderPlaced) error {
+		syncCalls++
+		return nil
+	})
+
+	// Cancelling the publishing context must not reach async handlers.
+	ctx, cancel := context.WithCancel(context.Background())
+	for seq := range 20 {
+		for _, id := range []string{"o-1", "o-2", "o-3"} {
+			if err := bus.PublishAsync(ctx, orderPlaced{orderID: id, seq: seq}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+	cancel()
+
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := make([]int, 20)
+	for i := range want {
+		want[i] = i
+	}
+	for _, id := range []string{"o-1", "o-2", "o-3"} {
+		if !reflect.DeepEqual(seen[id], want) {
+			t.Errorf("%s: expected events in publish order, got %v", id, seen[id])
+		}
+	}
+	if len(failed) != 3 {
+		t.Errorf("expected 3 async errors, got %d", len(failed))
+	}
+	if syncCalls != 0 {
+		t.Errorf("expected PublishAsync to skip sync handlers, got %d calls", syncCalls)
+	}
+}
+
+func TestBus_Close(t *testing.T) {
+	bus := New()
+	release := make(chan struct{})
+	Subscribe(bus, Async, func(ctx context.Context, e orderPlaced) error {
+		<-release
+		return nil
+	})
+
+	if err := bus.Publish(context.Background(), orderPlaced{orderID: "o-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bus.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Close to wait for the blocked handler, got %v", err)
+	}
+
+	if err := bus.Publish(context.Background(), orderPlaced{orderID: "o-1"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+
+	close(release)
+	if err := bus.Close(context.Background()); err != nil {
+		t.Errorf("expected Close to drain, got %v", err)
+	}
+}
+
+func TestBus_IgnoresNonPositiveSizes(t *testing.T) {
+	bus := New(WithWorkers(0), WithQueueSize(-1))
+	if len(bus.queues) != defaultWorkers || bus.queueSize != defaultQueueSize {
+		t.Fatalf("expected the defaults, got %d workers with queues of %d", len(bus.queues), bus.queueSize)
+	}
+
+	delivered := make(chan struct{})
+	Subscribe(bus, Async, func(ctx context.Context, e orderPlaced) error {
+		close(delivered)
+		return nil
+	})
+	if err := bus.Publish(context.Background(), orderPlaced{orderID: "o-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-delivered
+
+	if err := bus.Close(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrMessageNotFound = errors.New("outbox message not found")
+
+type Status string
+
+const (
+	StatusPending      Status = "pending"
+	StatusPublished    Status = "published"
+	StatusDeadLettered Status = "dead_lettered"
+)
+
+// Message is an event waiting to leave the service. It is written in the same
+// unit of work as the aggregate change it describes and later handed to a
+// Publisher by the Relay.
+type Message struct {
+	ID            string          `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Status        Status          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+}
+
+func NewMessage(aggregateType, aggregateID, eventType string, payload interface{}) (Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, err
+	}
+
+	now := time.Now()
+	return Message{
+		ID:            uuid.New().String(),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       data,
+		OccurredAt:    now,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+	}, nil
+}
+
+// Store persists outbox messages. Pending returns messages due for delivery at
+// or before now, oldest first. MarkFailed and MarkDeadLettered each count one
+// more delivery attempt.
+type Store interface {
+	Add(ctx context.Context, messages ...Message) error
+	Pending(ctx context.Context, now time.Time, limit int) ([]Message, error)
+	MarkPublished(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error
+	MarkDeadLettered(ctx context.Context, id string, reason string) error
+	DeadLettered(ctx context.Context) ([]Message, error)
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, message Message) error
+}
@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	defaultMaxAttempts  = 10
+)
+
+// Relay moves pending messages from a Store to a Publisher. Delivery is
+// at-least-once: a message is marked published only after Publish returns,
+// so a crash in between sends it again.
+type Relay struct {
+	store        Store
+	publisher    Publisher
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	backoff      func(attempt int) time.Duration
+	now          func() time.Time
+}
+
+type RelayOption func(*Relay)
+
+func WithBatchSize(size int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+func WithPollInterval(interval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = interval
+	}
+}
+
+// WithMaxAttempts sets how many failed publishes a message gets before it is
+// dead-lettered.
+func WithMaxAttempts(attempts int) RelayOption {
+	return func(r *Relay) {
+		r.maxAttempts = attempts
+	}
+}
+
+func WithBackoff(backoff func(attempt int) time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.backoff = backoff
+	}
+}
+
+func withClock(now func() time.Time) RelayOption {
+	return func(r *Relay) {
+		r.now = now
+	}
+}
+
+func NewRelay(store Store, publisher Publisher, opts ...RelayOption) *Relay {
+	r := &Relay{
+		store:        store,
+		publisher:    publisher,
+		batchSize:    defaultBatchSize,
+		pollInterval: defaultPollInterval,
+		maxAttempts:  defaultMaxAttempts,
+		backoff:      ExponentialBackoff(time.Second, 5*time.Minute),
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// ExponentialBackoff doubles the delay after every failed attempt, starting
+// at base and never exceeding max.
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+// Run relays messages every poll interval until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce publishes one batch of due messages and reports how many were
+// published. Publish failures are recorded on the message, not returned.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	messages, err := r.store.Pending(ctx, r.now(), r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, message := range messages {
+		if err := ctx.Err(); err != nil {
+			return published, err
+		}
+
+		if err := r.publisher.Publish(ctx, message); err != nil {
+			if err := r.fail(ctx, message, err); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		if err := r.store.MarkPublished(ctx, message.ID); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+func (r *Relay) fail(ctx context.Context, message Message, cause error) error {
+	attempts := message.Attempts + 1
+	if attempts >= r.maxAttempts {
+		return r.store.MarkDeadLettered(ctx, message.ID, cause.Error())
+	}
+	return r.store.MarkFailed(ctx, message.ID, cause.Error(), r.now().Add(r.backoff(attempts)))
+}
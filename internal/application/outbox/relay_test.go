@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 10*time.Second)
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.attempt); got != tt.want {
+			t.Errorf("attempt %d: expected %v, got %v", tt.attempt, tt.want, got)
+		}
+	}
+}
+
+func TestRelay_RunOnce(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	unavailable := errors.New("broker unavailable")
+
+	t.Run("publishes due messages in order", func(t *testing.T) {
+		store := newMockStore(now, "a", "b")
+		publisher := &mockPublisher{}
+		relay := NewRelay(store, publisher, withClock(func() time.Time { return now }))
+
+		published, err := relay.RunOnce(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if published != 2 || len(publisher.published) != 2 || publisher.published[0] != "a" {
+			t.Errorf("expected a and b to be published, got %v", publisher.published)
+		}
+		if store.messages["a"].Status != StatusPublished {
+			t.Errorf("expected a to be marked published, got %s", store.messages["a"].Status)
+		}
+	})
+
+	t.Run("failed publish is retried after backoff", func(t *testing.T) {
+		store := newMockStore(now, "a")
+		publisher := &mockPublisher{failures: []error{unavailable}}
+		clock := now
+		relay := NewRelay(store, publisher,
+			withClock(func() time.Time { return clock }),
+			WithBackoff(func(int) time.Duration { return time.Minute }),
+		)
+
+		published, _ := relay.RunOnce(context.Background())
+		message := store.messages["a"]
+		if published != 0 || message.Attempts != 1 || message.LastError != unavailable.Error() {
+			t.Fatalf("expected one recorded failure, got %+v", message)
+		}
+		if !message.NextAttemptAt.Equal(now.Add(time.Minute)) {
+			t.Errorf("expected next attempt at %v, got %v", now.Add(time.Minute), message.NextAttemptAt)
+		}
+
+		if published, _ := relay.RunOnce(context.Background()); published != 0 {
+			t.Error("expected message not to be retried before its backoff")
+		}
+
+		clock = now.Add(time.Minute)
+		if published, _ := relay.RunOnce(context.Background()); published != 1 {
+			t.Error("expected message to be published after its backoff")
+		}
+	})
+
+	t.Run("dead-letters after max attempts", func(t *testing.T) {
+		store := newMockStore(now, "a")
+		publisher := &mockPublisher{failures: []error{unavailable, unavailable}}
+		relay := NewRelay(store, publisher,
+			withClock(func() time.Time { return now }),
+			WithBackoff(func(int) time.Duration { return 0 }),
+			WithMaxAttempts(2),
+		)
+
+		relay.RunOnce(context.Background())
+		relay.RunOnce(context.Background())
+
+		dead, _ := store.DeadLettered(context.Background())
+		if len(dead) != 1 || dead[0].Attempts != 2 {
+			t.Errorf("expected a dead-lettered message after 2 attempts, got %+v", dead)
+		}
+		if published, _ := relay.RunOnce(context.Background()); published != 0 {
+			t.Error("expected dead-lettered message not to be published")
+		}
+	})
+}
+
+func TestRelay_RunStopsOnCancel(t *testing.T) {
+	store := newMockStore(time.Now(), "a")
+	publisher := &mockPublisher{}
+	relay := NewRelay(store, publisher, WithPollInterval(time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- relay.Run(ctx) }()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+type mockStore struct {
+	order    []string
+	messages map[string]Message
+}
+
+func newMockStore(at time.Time, ids ...string) *mockStore {
+	store := &mockStore{messages: make(map[string]Message)}
+	for _, id := range ids {
+		store.order = append(store.order, id)
+		store.messages[id] = Message{ID: id, Status: StatusPending, OccurredAt: at, NextAttemptAt: at}
+	}
+	return store
+}
+
+func (m *mockStore) Add(ctx context.Context, messages ...Message) error {
+	for _, message := range messages {
+		m.order = append(m.order, message.ID)
+		m.messages[message.ID] = message
+	}
+	return nil
+}
+
+func (m *mockStore) Pending(ctx context.Context, now time.Time, limit int) ([]Message, error) {
+	var pending []Message
+	for _, id := range m.order {
+		message := m.messages[id]
+		if message.Status == StatusPending && !message.NextAttemptAt.After(now) {
+			pending = append(pending, message)
+		}
+	}
+	return pending, nil
+}
+
+func (m *mockStore) MarkPublished(ctx context.Context, id string) error {
+	message := m.messages[id]
+	message.Status = StatusPublished
+	m.messages[id] = message
+	return nil
+}
+
+func (m *mockStore) MarkFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error {
+	message := m.messages[id]
+	message.Attempts++
+	message.LastError = reason
+	message.NextAttemptAt = nextAttemptAt
+	m.messages[id] = message
+	return nil
+}
+
+func (m *mockStore) MarkDeadLettered(ctx context.Context, id string, reason string) error {
+	message := m.messages[id]
+	message.Attempts++
+	message.LastError = reason
+	message.Status = StatusDeadLettered
+	m.messages[id] = message
+	return nil
+}
+
+func (m *mockStore) DeadLettered(ctx context.Context) ([]Message, error) {
+	var dead []Message
+	for _, id := range m.order {
+		if m.messages[id].Status == StatusDeadLettered {
+			dead = append(dead, m.messages[id])
+		}
+	}
+	return dead, nil
+}
+
+type mockPublisher struct {
+	failures  []error
+	published []string
+}
+
+func (m *mockPublisher) Publish(ctx context.Context, message Message) error {
+	if len(m.failures) > 0 {
+		err := m.failures[0]
+		m.failures = m.failures[1:]
+		return err
+	}
+	m.published = append(m.published, message.ID)
+	return nil
+}
@@ -0,0 +1,63 @@
+package application
+
+import (
+	"context"
+	"errors"
+
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/domain/payment"
+)
+
+// NewPaymentReconciler checks payments rebuilt from the audit log against the
+// ones payments holds.
+func NewPaymentReconciler(audits audit.Repository, payments payment.Repository, opts ...audit.ReconcilerOption) *audit.Reconciler {
+	return audit.NewReconciler(audits, audit.EntityTypePayment, &PaymentFields{payments: payments}, opts...)
+}
+
+// PaymentFields is the audit.EntitySource of stored payments. It reads the
+// fields the AuditSubscriber records for them.
+type PaymentFields struct {
+	payments payment.Repository
+}
+
+func (f *PaymentFields) Fields(ctx context.Context, paymentID string) (map[string]interface{}, error) {
+	p, err := f.payments.FindByID(ctx, payment.PaymentIDFromString(paymentID))
+	if errors.Is(err, payment.ErrPaymentNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return paymentFields(p)
+}
+
+func (f *PaymentFields) AllFields(ctx context.Context) (map[string]map[string]interface{}, error) {
+	payments, err := f.payments.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]map[string]interface{}, len(payments))
+	for _, p := range payments {
+		if fields[p.ID().String()], err = paymentFields(p); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+func paymentFields(p *payment.Payment) (map[string]interface{}, error) {
+	refunded, err := p.RefundedAmount()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"status":          p.Status().String(),
+		"amount":          p.Amount().Decimal(),
+		"amount_minor":    p.Amount().MinorUnits(),
+		"currency":        p.Amount().Currency(),
+		"description":     p.Description(),
+		"captured_amount": p.CapturedAmount().Decimal(),
+		"refunded_amount": refunded.Decimal(),
+	}, nil
+}
@@ -0,0 +1,64 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"go-ddd/internal/domain/payment"
+)
+
+func TestPaymentReconciler(t *testing.T) {
+	_, unitOfWork := createTestServices()
+	service := newTestApplicationService(t, unitOfWork)
+	repos := unitOfWork.(*mockUnitOfWork).repos
+	ctx := context.Background()
+
+	agreeing, err := service.CreatePayment(ctx, "100.50", "USD", "agrees", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.ProcessPayment(ctx, agreeing.ID().String(), "user-123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	diverged, err := service.CreatePayment(ctx, "100.50", "USD", "diverged", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Changed behind the audit log's back.
+	diverged.Process()
+
+	amount, _ := payment.NewAmount(100, "USD")
+	unaudited := payment.NewPayment(amount, "unaudited")
+	repos.Payments.Save(ctx, unaudited)
+
+	reconciler := NewPaymentReconciler(repos.Audits, repos.Payments)
+
+	got, err := reconciler.Reconcile(ctx, agreeing.ID().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no inconsistencies, got %v", got)
+	}
+
+	got, err = reconciler.Reconcile(ctx, diverged.ID().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Field != "status" || got[0].Expected != "pending" || got[0].Actual != "processing" {
+		t.Errorf("expected status inconsistency, got %v", got)
+	}
+
+	all, err := reconciler.ReconcileAll(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("expected 2 inconsistencies, got %v", all)
+	}
+	for _, inconsistency := range all {
+		if inconsistency.EntityID == unaudited.ID().String() && inconsistency.Reason != "stored payment has no audit history" {
+			t.Errorf("expected unaudited payment to be reported, got %q", inconsistency.Reason)
+		}
+	}
+}
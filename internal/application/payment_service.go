@@ -1,96 +1,286 @@
 package application
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
+	"go-ddd/internal/application/eventbus"
+	"go-ddd/internal/application/outbox"
 	"go-ddd/internal/domain/audit"
 	"go-ddd/internal/domain/payment"
 )
 
 type PaymentApplicationService struct {
-	paymentService *payment.Service
-	auditService   *audit.Service
+	unitOfWork     UnitOfWork
+	bus            *eventbus.Bus
+	paymentOptions []payment.ServiceOption
+	redactor       *audit.Redactor
+	payments       *payment.Service
+	audits         *audit.Service
 }
 
-func NewPaymentApplicationService(paymentService *payment.Service, auditService *audit.Service) *PaymentApplicationService {
-	return &PaymentApplicationService{
-		paymentService: paymentService,
-		auditService:   auditService,
+type ServiceOption func(*PaymentApplicationService)
+
+// WithPaymentOptions configures the payment services commands and queries run
+// with.
+func WithPaymentOptions(opts ...payment.ServiceOption) ServiceOption {
+	return func(s *PaymentApplicationService) {
+		s.paymentOptions = append(s.paymentOptions, opts...)
 	}
 }
 
-func (s *PaymentApplicationService) CreatePayment(ctx context.Context, amount float64, currency, description, userID string) (*payment.Payment, error) {
-	amountVO, err := payment.NewAmount(amount, currency)
-	if err != nil {
-		return nil, fmt.Errorf("invalid amount: %w", err)
+// WithPayloadRedactor redacts the payloads of outbox messages under the
+// payment policy of redactor, as the audit log redacts payment data, so
+// events leave the service without the personal data they carry.
+func WithPayloadRedactor(redactor *audit.Redactor) ServiceOption {
+	return func(s *PaymentApplicationService) {
+		s.redactor = redactor
+	}
+}
+
+// NewPaymentApplicationService runs payment commands in unitOfWork and
+// publishes the resulting events on bus: to sync subscribers inside the
+// transaction, to async subscribers after it commits. Once committed, a
+// command succeeds even if its events cannot be queued for async subscribers;
+// the failure goes to the bus's error handler. Queries read from reads.
+func NewPaymentApplicationService(unitOfWork UnitOfWork, reads ReadModel, bus *eventbus.Bus, opts ...ServiceOption) *PaymentApplicationService {
+	s := &PaymentApplicationService{
+		unitOfWork: unitOfWork,
+		bus:        bus,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.payments = payment.NewService(reads.Payments, s.paymentOptions...)
+	s.audits = audit.NewService(reads.Audits)
+	return s
+}
 
-	p, err := s.paymentService.CreatePayment(ctx, amountVO, description)
+// transaction holds domain services bound to a single unit of work, so a
+// payment change, the outbox messages for its events and whatever sync
+// subscribers write are stored together or not at all.
+type transaction struct {
+	payments *payment.Service
+	audits   audit.Repository
+	outbox   outbox.Store
+	redactor *audit.Redactor
+	bus      *eventbus.Bus
+	events   []eventbus.Event
+}
+
+func (s *PaymentApplicationService) inTransaction(ctx context.Context, fn func(ctx context.Context, tx *transaction) error) error {
+	var committed []eventbus.Event
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		tx := &transaction{
+			audits:   repos.Audits,
+			outbox:   repos.Outbox,
+			redactor: s.redactor,
+			bus:      s.bus,
+		}
+		options := append([]payment.ServiceOption{payment.WithEventDispatcher(tx)}, s.paymentOptions...)
+		tx.payments = payment.NewService(repos.Payments, options...)
+
+		if err := fn(ctx, tx); err != nil {
+			return err
+		}
+		committed = tx.events
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create payment: %w", err)
+		return err
 	}
 
-	paymentData := map[string]interface{}{
-		"id":          p.ID().String(),
-		"amount":      p.Amount().Value(),
-		"currency":    p.Amount().Currency(),
-		"description": p.Description(),
-		"status":      p.Status().String(),
-		"created_at":  p.CreatedAt(),
+	// The change is stored and its events are queued in the outbox, which
+	// delivers them anyway, so a failure here must not fail the command.
+	if err := s.bus.PublishAsync(ctx, committed...); err != nil {
+		s.bus.ReportUndelivered(fmt.Errorf("failed to publish events: %w", err), committed...)
 	}
 
-	if err := s.auditService.RecordPaymentCreated(ctx, p.ID().String(), userID, paymentData); err != nil {
-		return nil, fmt.Errorf("failed to record audit: %w", err)
+	return nil
+}
+
+// Dispatch queues the events raised by a payment command in the outbox and
+// delivers them to sync subscribers, which see this transaction's audit
+// repository through the context.
+func (tx *transaction) Dispatch(ctx context.Context, events []payment.DomainEvent) error {
+	published := make([]eventbus.Event, 0, len(events))
+	for _, event := range events {
+		payload, err := tx.payload(event)
+		if err != nil {
+			return err
+		}
+		message, err := outbox.NewMessage("payment", event.AggregateID(), event.EventType(), payload)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.outbox.Add(ctx, message); err != nil {
+			return fmt.Errorf("failed to queue event: %w", err)
+		}
+		published = append(published, event)
 	}
 
-	return p, nil
+	if err := tx.bus.PublishSync(withAuditRepository(ctx, tx.audits), published...); err != nil {
+		return err
+	}
+
+	tx.events = append(tx.events, published...)
+	return nil
 }
 
-func (s *PaymentApplicationService) ProcessPayment(ctx context.Context, paymentID string, userID string) error {
-	id := payment.PaymentIDFromString(paymentID)
+// payload returns what an outbox message for event carries: the event
+// itself, or its JSON fields redacted if the service has a redactor.
+func (tx *transaction) payload(event payment.DomainEvent) (interface{}, error) {
+	if tx.redactor == nil {
+		return event, nil
+	}
 
-	p, err := s.paymentService.GetPayment(ctx, id)
+	data, err := json.Marshal(event)
 	if err != nil {
-		return fmt.Errorf("failed to get payment: %w", err)
+		return nil, err
 	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return tx.redactor.RedactData(audit.EntityTypePayment, fields), nil
+}
 
-	oldStatus := p.Status().String()
-
-	err = s.paymentService.ProcessPayment(ctx, id)
+func (s *PaymentApplicationService) CreatePayment(ctx context.Context, amount, currency, description, userID string) (*payment.Payment, error) {
+	amountVO, err := payment.ParseAmount(amount, currency)
 	if err != nil {
-		return fmt.Errorf("failed to process payment: %w", err)
+		return nil, fmt.Errorf("invalid amount: %w", err)
 	}
 
-	if err := s.auditService.RecordPaymentStatusChange(ctx, paymentID, userID, oldStatus, "processing"); err != nil {
-		return fmt.Errorf("failed to record audit: %w", err)
+	var p *payment.Payment
+	err = s.inTransaction(withUserID(ctx, userID), func(ctx context.Context, tx *transaction) error {
+		var err error
+		p, err = tx.payments.CreatePayment(ctx, amountVO, description)
+		if err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return p, nil
+}
+
+func (s *PaymentApplicationService) ProcessPayment(ctx context.Context, paymentID string, userID string) error {
+	return s.run(ctx, paymentID, userID, "process", (*payment.Service).ProcessPayment)
 }
 
 func (s *PaymentApplicationService) CompletePayment(ctx context.Context, paymentID string, userID string) error {
+	return s.run(ctx, paymentID, userID, "complete", (*payment.Service).CompletePayment)
+}
+
+func (s *PaymentApplicationService) VoidPayment(ctx context.Context, paymentID string, userID string) error {
+	return s.run(ctx, paymentID, userID, "void", (*payment.Service).VoidPayment)
+}
+
+func (s *PaymentApplicationService) AuthorizePayment(ctx context.Context, paymentID string, expiresAt time.Time, userID string) error {
+	return s.run(ctx, paymentID, userID, "authorize", func(payments *payment.Service, ctx context.Context, id payment.PaymentID) error {
+		return payments.AuthorizePayment(ctx, id, expiresAt)
+	})
+}
+
+// run executes a payment command that needs nothing but the payment ID.
+func (s *PaymentApplicationService) run(ctx context.Context, paymentID, userID, verb string, command func(*payment.Service, context.Context, payment.PaymentID) error) error {
 	id := payment.PaymentIDFromString(paymentID)
 
-	p, err := s.paymentService.GetPayment(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to get payment: %w", err)
-	}
+	return s.inTransaction(withUserID(ctx, userID), func(ctx context.Context, tx *transaction) error {
+		if err := command(tx.payments, ctx, id); err != nil {
+			return fmt.Errorf("failed to %s payment: %w", verb, err)
+		}
+		return nil
+	})
+}
+
+// CapturePayment settles an authorized payment. An empty amount captures the
+// full authorized amount.
+func (s *PaymentApplicationService) CapturePayment(ctx context.Context, paymentID, amount, userID string) error {
+	id := payment.PaymentIDFromString(paymentID)
 
-	oldStatus := p.Status().String()
+	return s.inTransaction(withUserID(ctx, userID), func(ctx context.Context, tx *transaction) error {
+		p, err := tx.payments.GetPayment(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get payment: %w", err)
+		}
 
-	err = s.paymentService.CompletePayment(ctx, id)
+		captureAmount := p.Amount()
+		if amount != "" {
+			captureAmount, err = payment.ParseAmount(amount, p.Amount().Currency())
+			if err != nil {
+				return fmt.Errorf("invalid amount: %w", err)
+			}
+		}
+
+		if err := tx.payments.CapturePayment(ctx, id, captureAmount); err != nil {
+			return fmt.Errorf("failed to capture payment: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *PaymentApplicationService) RefundPayment(ctx context.Context, paymentID, amount, reason, userID string) (payment.Refund, error) {
+	id := payment.PaymentIDFromString(paymentID)
+
+	var refund payment.Refund
+	err := s.inTransaction(withUserID(ctx, userID), func(ctx context.Context, tx *transaction) error {
+		p, err := tx.payments.GetPayment(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get payment: %w", err)
+		}
+
+		refundAmount, err := payment.ParseAmount(amount, p.Amount().Currency())
+		if err != nil {
+			return fmt.Errorf("invalid amount: %w", err)
+		}
+
+		refund, err = tx.payments.RefundPayment(ctx, id, refundAmount, reason)
+		if err != nil {
+			return fmt.Errorf("failed to refund payment: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to complete payment: %w", err)
+		return payment.Refund{}, err
 	}
 
-	if err := s.auditService.RecordPaymentStatusChange(ctx, paymentID, userID, oldStatus, "completed"); err != nil {
-		return fmt.Errorf("failed to record audit: %w", err)
-	}
+	return refund, nil
+}
 
-	return nil
+// ListPayments returns a page of the payments matching filter; see
+// payment.Service.ListPayments.
+func (s *PaymentApplicationService) ListPayments(ctx context.Context, filter payment.PaymentFilter) (payment.PaymentPage, error) {
+	return s.payments.ListPayments(ctx, filter)
 }
 
+// GetPaymentAuditHistory returns the payment's whole audit history, oldest
+// entry first.
 func (s *PaymentApplicationService) GetPaymentAuditHistory(ctx context.Context, paymentID string) ([]*audit.AuditEntry, error) {
-	return s.auditService.GetAuditHistory(ctx, audit.EntityTypePayment, paymentID)
+	return s.audits.GetAuditHistory(ctx, audit.EntityTypePayment, paymentID)
+}
+
+// GetPaymentAuditPage returns up to limit entries of the payment's audit
+// history, oldest first, starting after cursor. An empty cursor starts at the
+// beginning; the page's NextCursor continues it.
+func (s *PaymentApplicationService) GetPaymentAuditPage(ctx context.Context, paymentID, cursor string, limit int) (audit.AuditPage, error) {
+	entityType := audit.EntityTypePayment
+	return s.audits.GetAuditPage(ctx, audit.AuditFilter{
+		EntityType: &entityType,
+		EntityID:   &paymentID,
+		Sort:       audit.SortAscending,
+		Limit:      limit,
+		Cursor:     cursor,
+	})
 }
@@ -2,9 +2,15 @@ package application
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"go-ddd/internal/application/eventbus"
+	"go-ddd/internal/application/outbox"
 	"go-ddd/internal/domain/audit"
 	"go-ddd/internal/domain/payment"
 )
@@ -12,7 +18,7 @@ import (
 func TestPaymentApplicationService_CreatePayment(t *testing.T) {
 	tests := []struct {
 		name        string
-		amount      float64
+		amount      string
 		currency    string
 		description string
 		userID      string
@@ -20,7 +26,7 @@ func TestPaymentApplicationService_CreatePayment(t *testing.T) {
 	}{
 		{
 			name:        "successful payment creation",
-			amount:      100.50,
+			amount:      "100.50",
 			currency:    "USD",
 			description: "Test payment",
 			userID:      "user-123",
@@ -28,7 +34,7 @@ func TestPaymentApplicationService_CreatePayment(t *testing.T) {
 		},
 		{
 			name:        "successful payment creation with zero amount",
-			amount:      0,
+			amount:      "0",
 			currency:    "EUR",
 			description: "Zero amount payment",
 			userID:      "user-456",
@@ -36,7 +42,7 @@ func TestPaymentApplicationService_CreatePayment(t *testing.T) {
 		},
 		{
 			name:        "invalid amount - negative",
-			amount:      -10.50,
+			amount:      "-10.50",
 			currency:    "USD",
 			description: "Invalid payment",
 			userID:      "user-123",
@@ -44,7 +50,7 @@ func TestPaymentApplicationService_CreatePayment(t *testing.T) {
 		},
 		{
 			name:        "invalid currency - empty",
-			amount:      100.50,
+			amount:      "100.50",
 			currency:    "",
 			description: "Invalid currency payment",
 			userID:      "user-123",
@@ -54,9 +60,9 @@ func TestPaymentApplicationService_CreatePayment(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			paymentSvc, auditSvc := createTestServices()
+			_, unitOfWork := createTestServices()
 
-			service := NewPaymentApplicationService(paymentSvc, auditSvc)
+			service := newTestApplicationService(t, unitOfWork)
 
 			ctx := context.Background()
 			result, err := service.CreatePayment(ctx, tt.amount, tt.currency, tt.description, tt.userID)
@@ -79,8 +85,9 @@ func TestPaymentApplicationService_CreatePayment(t *testing.T) {
 				return
 			}
 
-			if result.Amount().Value() != tt.amount {
-				t.Errorf("expected amount %f, got %f", tt.amount, result.Amount().Value())
+			expectedAmount, _ := payment.ParseAmount(tt.amount, tt.currency)
+			if !result.Amount().Equal(expectedAmount) {
+				t.Errorf("expected amount %s, got %s", expectedAmount, result.Amount())
 			}
 
 			if result.Amount().Currency() != tt.currency {
@@ -126,8 +133,8 @@ func TestPaymentApplicationService_ProcessPayment(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			paymentSvc, auditSvc := createTestServices()
-			service := NewPaymentApplicationService(paymentSvc, auditSvc)
+			paymentSvc, unitOfWork := createTestServices()
+			service := newTestApplicationService(t, unitOfWork)
 
 			var paymentID string
 			if tt.setupPayment {
@@ -201,8 +208,8 @@ func TestPaymentApplicationService_CompletePayment(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			paymentSvc, auditSvc := createTestServices()
-			service := NewPaymentApplicationService(paymentSvc, auditSvc)
+			paymentSvc, unitOfWork := createTestServices()
+			service := newTestApplicationService(t, unitOfWork)
 
 			var paymentID string
 			if tt.setupPayment {
@@ -238,6 +245,325 @@ func TestPaymentApplicationService_CompletePayment(t *testing.T) {
 	}
 }
 
+func TestPaymentApplicationService_AuthorizeCaptureVoid(t *testing.T) {
+	tests := []struct {
+		name            string
+		capture         *string
+		void            bool
+		wantErr         bool
+		expectedStatus  payment.PaymentStatus
+		expectedActions []audit.ActionType
+	}{
+		{
+			name:            "authorize and capture in full",
+			capture:         &[]string{""}[0],
+			expectedStatus:  payment.PaymentStatusCompleted,
+			expectedActions: []audit.ActionType{audit.ActionTypeCreated, audit.ActionTypeAuthorized, audit.ActionTypeCaptured},
+		},
+		{
+			name:            "authorize and capture partially",
+			capture:         &[]string{"40.00"}[0],
+			expectedStatus:  payment.PaymentStatusCompleted,
+			expectedActions: []audit.ActionType{audit.ActionTypeCreated, audit.ActionTypeAuthorized, audit.ActionTypeCaptured},
+		},
+		{
+			name:    "over capture",
+			capture: &[]string{"150.00"}[0],
+			wantErr: true,
+		},
+		{
+			name:            "authorize and void",
+			void:            true,
+			expectedStatus:  payment.PaymentStatusVoided,
+			expectedActions: []audit.ActionType{audit.ActionTypeCreated, audit.ActionTypeAuthorized, audit.ActionTypeVoided},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paymentSvc, unitOfWork := createTestServices()
+			service := newTestApplicationService(t, unitOfWork)
+			ctx := context.Background()
+
+			p, err := service.CreatePayment(ctx, "100.00", "USD", "card payment", "user-123")
+			if err != nil {
+				t.Fatalf("failed to create payment: %v", err)
+			}
+			paymentID := p.ID().String()
+
+			if err := service.AuthorizePayment(ctx, paymentID, time.Now().Add(time.Hour), "user-123"); err != nil {
+				t.Fatalf("failed to authorize payment: %v", err)
+			}
+
+			if tt.capture != nil {
+				err = service.CapturePayment(ctx, paymentID, *tt.capture, "user-123")
+			}
+			if tt.void {
+				err = service.VoidPayment(ctx, paymentID, "user-123")
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			p, _ = paymentSvc.GetPayment(ctx, p.ID())
+			if p.Status() != tt.expectedStatus {
+				t.Errorf("expected status %v, got %v", tt.expectedStatus, p.Status())
+			}
+
+			history, _ := service.GetPaymentAuditHistory(ctx, paymentID)
+			if len(history) != len(tt.expectedActions) {
+				t.Fatalf("expected %d audit entries, got %d", len(tt.expectedActions), len(history))
+			}
+			recorded := make(map[audit.ActionType]bool)
+			for _, entry := range history {
+				recorded[entry.Action()] = true
+			}
+			for _, action := range tt.expectedActions {
+				if !recorded[action] {
+					t.Errorf("expected %q audit entry", action)
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentApplicationService_RefundPayment(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupPayment   bool
+		completed      bool
+		refunds        []string
+		wantErr        bool
+		expectedStatus payment.PaymentStatus
+	}{
+		{
+			name:           "full refund",
+			setupPayment:   true,
+			completed:      true,
+			refunds:        []string{"100.00"},
+			expectedStatus: payment.PaymentStatusRefunded,
+		},
+		{
+			name:           "partial refunds",
+			setupPayment:   true,
+			completed:      true,
+			refunds:        []string{"25.00", "25.00"},
+			expectedStatus: payment.PaymentStatusPartiallyRefunded,
+		},
+		{
+			name:         "over refund",
+			setupPayment: true,
+			completed:    true,
+			refunds:      []string{"100.01"},
+			wantErr:      true,
+		},
+		{
+			name:         "invalid amount",
+			setupPayment: true,
+			completed:    true,
+			refunds:      []string{"1.001"},
+			wantErr:      true,
+		},
+		{
+			name:         "payment not completed",
+			setupPayment: true,
+			refunds:      []string{"10.00"},
+			wantErr:      true,
+		},
+		{
+			name:         "payment not found",
+			setupPayment: false,
+			refunds:      []string{"10.00"},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paymentSvc, unitOfWork := createTestServices()
+			service := newTestApplicationService(t, unitOfWork)
+			ctx := context.Background()
+
+			paymentID := "non-existent-payment"
+			if tt.setupPayment {
+				amount, _ := payment.NewAmount(100.0, "USD")
+				createdPayment, _ := paymentSvc.CreatePayment(ctx, amount, "test payment")
+				paymentID = createdPayment.ID().String()
+
+				if tt.completed {
+					paymentSvc.ProcessPayment(ctx, createdPayment.ID())
+					paymentSvc.CompletePayment(ctx, createdPayment.ID())
+				}
+			}
+
+			var err error
+			for _, value := range tt.refunds {
+				if _, err = service.RefundPayment(ctx, paymentID, value, "customer request", "user-123"); err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			p, _ := paymentSvc.GetPayment(ctx, payment.PaymentIDFromString(paymentID))
+			if p.Status() != tt.expectedStatus {
+				t.Errorf("expected status %v, got %v", tt.expectedStatus, p.Status())
+			}
+
+			history, _ := service.GetPaymentAuditHistory(ctx, paymentID)
+			refundEntries := 0
+			for _, entry := range history {
+				if entry.Action() == audit.ActionTypeRefunded {
+					refundEntries++
+				}
+			}
+			if refundEntries != len(tt.refunds) {
+				t.Errorf("expected %d refund audit entries, got %d", len(tt.refunds), refundEntries)
+			}
+		})
+	}
+}
+
+func TestPaymentApplicationService_GetPaymentAuditPage(t *testing.T) {
+	_, unitOfWork := createTestServices()
+	service := newTestApplicationService(t, unitOfWork)
+	ctx := context.Background()
+
+	p, err := service.CreatePayment(ctx, "100.00", "USD", "Test payment", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	paymentID := p.ID().String()
+	service.ProcessPayment(ctx, paymentID, "user-123")
+	service.CompletePayment(ctx, paymentID, "user-123")
+
+	var actions []audit.ActionType
+	cursor, pages := "", 0
+	for {
+		page, err := service.GetPaymentAuditPage(ctx, paymentID, cursor, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, entry := range page.Entries {
+			actions = append(actions, entry.Action())
+		}
+		pages++
+		if cursor = page.NextCursor; cursor == "" {
+			break
+		}
+	}
+
+	want := []audit.ActionType{audit.ActionTypeCreated, audit.ActionTypeProcessed, audit.ActionTypeCompleted}
+	if pages != 2 || !slices.Equal(actions, want) {
+		t.Errorf("expected %v over 2 pages, got %v over %d", want, actions, pages)
+	}
+
+	history, _ := service.GetPaymentAuditHistory(ctx, paymentID)
+	for i, entry := range history {
+		if entry.Action() != want[i] {
+			t.Errorf("expected history in chronological order, got %s at %d", entry.Action(), i)
+		}
+	}
+
+	if _, err := service.GetPaymentAuditPage(ctx, paymentID, "not a cursor", 2); !errors.Is(err, audit.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestPaymentApplicationService_ListPayments(t *testing.T) {
+	_, unitOfWork := createTestServices()
+	service := newTestApplicationService(t, unitOfWork)
+	ctx := context.Background()
+
+	for _, amount := range []string{"30.00", "10.00", "20.00"} {
+		p, err := service.CreatePayment(ctx, amount, "USD", "Order", "user-123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if amount != "20.00" {
+			service.ProcessPayment(ctx, p.ID().String(), "user-123")
+		}
+	}
+
+	filter := payment.PaymentFilter{
+		Statuses: []payment.PaymentStatus{payment.PaymentStatusProcessing},
+		SortBy:   payment.SortByAmount,
+		Limit:    1,
+	}
+	var amounts []string
+	for {
+		page, err := service.ListPayments(ctx, filter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, p := range page.Payments {
+			amounts = append(amounts, p.Amount().Decimal())
+		}
+		if filter.Cursor = page.NextCursor; filter.Cursor == "" {
+			break
+		}
+	}
+
+	if want := []string{"10.00", "30.00"}; !slices.Equal(amounts, want) {
+		t.Errorf("expected %v, got %v", want, amounts)
+	}
+}
+
+func TestPaymentApplicationService_ReadsOutsideUnitOfWork(t *testing.T) {
+	_, unitOfWork := createTestServices()
+	service := newTestApplicationService(t, unitOfWork)
+	ctx := context.Background()
+
+	p, err := service.CreatePayment(ctx, "100.50", "USD", "Test payment", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Queries must not need a unit of work at all.
+	bus := eventbus.New()
+	defer bus.Close(ctx)
+	readOnly := NewPaymentApplicationService(unavailableUnitOfWork{}, readModel(unitOfWork), bus)
+	if page, err := readOnly.ListPayments(ctx, payment.PaymentFilter{}); err != nil || len(page.Payments) != 1 {
+		t.Errorf("expected the payment listed, got %v, %v", page.Payments, err)
+	}
+	if history, err := readOnly.GetPaymentAuditHistory(ctx, p.ID().String()); err != nil || len(history) != 1 {
+		t.Errorf("expected the payment's history, got %d entries, %v", len(history), err)
+	}
+	if page, err := readOnly.GetPaymentAuditPage(ctx, p.ID().String(), "", 10); err != nil || len(page.Entries) != 1 {
+		t.Errorf("expected a page of history, got %v", err)
+	}
+	if err := readOnly.ProcessPayment(ctx, p.ID().String(), "user-123"); !errors.Is(err, errUnavailable) {
+		t.Errorf("expected commands to need the unit of work, got %v", err)
+	}
+}
+
+var errUnavailable = errors.New("unit of work unavailable")
+
+type unavailableUnitOfWork struct{}
+
+func (unavailableUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	return errUnavailable
+}
+
 func TestPaymentApplicationService_GetPaymentAuditHistory(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -258,8 +584,8 @@ func TestPaymentApplicationService_GetPaymentAuditHistory(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			paymentSvc, auditSvc := createTestServices()
-			service := NewPaymentApplicationService(paymentSvc, auditSvc)
+			_, unitOfWork := createTestServices()
+			service := newTestApplicationService(t, unitOfWork)
 
 			ctx := context.Background()
 			result, err := service.GetPaymentAuditHistory(ctx, tt.paymentID)
@@ -285,8 +611,143 @@ func TestPaymentApplicationService_GetPaymentAuditHistory(t *testing.T) {
 	}
 }
 
+func TestPaymentApplicationService_QueuesOutboxMessages(t *testing.T) {
+	_, unitOfWork := createTestServices()
+	service := newTestApplicationService(t, unitOfWork)
+	ctx := context.Background()
+
+	p, err := service.CreatePayment(ctx, "100.50", "USD", "Test payment", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.ProcessPayment(ctx, p.ID().String(), "user-123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := unitOfWork.(*mockUnitOfWork).repos.Outbox.(*mockOutbox).messages
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 outbox messages, got %d", len(messages))
+	}
+	if messages[0].EventType != "payment.created" || messages[1].EventType != "payment.processed" {
+		t.Errorf("unexpected event types %q and %q", messages[0].EventType, messages[1].EventType)
+	}
+
+	var event payment.PaymentProcessed
+	if err := json.Unmarshal(messages[1].Payload, &event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.PaymentID != p.ID() || event.From != payment.PaymentStatusPending || event.To != payment.PaymentStatusProcessing {
+		t.Errorf("expected pending to processing event for %s, got %+v", p.ID(), event)
+	}
+}
+
+func TestPaymentApplicationService_RedactsOutboxPayloads(t *testing.T) {
+	_, unitOfWork := createTestServices()
+	bus := eventbus.New()
+	defer bus.Close(context.Background())
+	redactor, err := audit.NewRedactor([]byte("salt"), audit.WithDefaultPolicy(audit.RedactionPolicy{
+		Detectors: audit.DefaultDetectors(),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	service := NewPaymentApplicationService(unitOfWork, readModel(unitOfWork), bus, WithPayloadRedactor(redactor))
+	ctx := context.Background()
+
+	p, err := service.CreatePayment(ctx, "100.50", "USD", "Online purchase for jane@example.com", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := unitOfWork.(*mockUnitOfWork).repos.Outbox.(*mockOutbox).messages
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 outbox message, got %d", len(messages))
+	}
+	if strings.Contains(string(messages[0].Payload), "jane@example.com") {
+		t.Errorf("expected email redacted from payload, got %s", messages[0].Payload)
+	}
+
+	var event payment.PaymentCreated
+	if err := json.Unmarshal(messages[0].Payload, &event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.PaymentID != p.ID() || !event.Amount.Equal(p.Amount()) {
+		t.Errorf("expected the rest of the event kept, got %+v", event)
+	}
+}
+
+func TestPaymentApplicationService_PublishesEvents(t *testing.T) {
+	_, unitOfWork := createTestServices()
+	bus := eventbus.New()
+	defer bus.Close(context.Background())
+	service := NewPaymentApplicationService(unitOfWork, readModel(unitOfWork), bus)
+	ctx := context.Background()
+
+	delivered := make(chan payment.PaymentProcessed, 1)
+	eventbus.Subscribe(bus, eventbus.Async, func(ctx context.Context, e payment.PaymentProcessed) error {
+		delivered <- e
+		return nil
+	})
+	rejected := errors.New("audit store unavailable")
+	unsubscribe := eventbus.Subscribe(bus, eventbus.Sync, func(ctx context.Context, e payment.PaymentCreated) error {
+		return rejected
+	})
+
+	if _, err := service.CreatePayment(ctx, "100.50", "USD", "Test payment", "user-123"); !errors.Is(err, rejected) {
+		t.Fatalf("expected sync subscriber error, got %v", err)
+	}
+	unsubscribe()
+
+	p, err := service.CreatePayment(ctx, "100.50", "USD", "Test payment", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.ProcessPayment(ctx, p.ID().String(), "user-123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case event := <-delivered:
+		if event.PaymentID != p.ID() {
+			t.Errorf("expected event for %s, got %s", p.ID(), event.PaymentID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected async subscriber to receive payment.processed")
+	}
+}
+
+func TestPaymentApplicationService_SucceedsWhenPublishFailsAfterCommit(t *testing.T) {
+	_, unitOfWork := createTestServices()
+	ctx := context.Background()
+	var undelivered []error
+	bus := eventbus.New(eventbus.WithErrorHandler(func(event eventbus.Event, err error) {
+		undelivered = append(undelivered, err)
+	}))
+	service := NewPaymentApplicationService(unitOfWork, readModel(unitOfWork), bus)
+
+	// Closing the bus from a sync subscriber shuts it down between the
+	// commit and the async publish.
+	eventbus.Subscribe(bus, eventbus.Sync, func(ctx context.Context, e payment.PaymentCreated) error {
+		return bus.Close(ctx)
+	})
+
+	p, err := service.CreatePayment(ctx, "100.50", "USD", "Test payment", "user-123")
+	if err != nil {
+		t.Fatalf("expected the committed command to succeed, got %v", err)
+	}
+	if _, err := unitOfWork.(*mockUnitOfWork).repos.Payments.FindByID(ctx, p.ID()); err != nil {
+		t.Errorf("expected the payment stored, got %v", err)
+	}
+	if messages := unitOfWork.(*mockUnitOfWork).repos.Outbox.(*mockOutbox).messages; len(messages) != 1 {
+		t.Errorf("expected the event queued in the outbox, got %d messages", len(messages))
+	}
+	if len(undelivered) != 1 || !errors.Is(undelivered[0], eventbus.ErrClosed) {
+		t.Errorf("expected the publish failure reported to the error handler, got %v", undelivered)
+	}
+}
+
 // Create a simple test setup using the actual services with in-memory repositories
-func createTestServices() (*payment.Service, *audit.Service) {
+func createTestServices() (*payment.Service, UnitOfWork) {
 	paymentRepo := &mockPaymentRepository{
 		payments: make(map[string]*payment.Payment),
 	}
@@ -295,9 +756,66 @@ func createTestServices() (*payment.Service, *audit.Service) {
 	}
 
 	paymentService := payment.NewService(paymentRepo)
-	auditService := audit.NewService(auditRepo)
+	unitOfWork := &mockUnitOfWork{repos: Repositories{Payments: paymentRepo, Audits: auditRepo, Outbox: &mockOutbox{}}}
+
+	return paymentService, unitOfWork
+}
 
-	return paymentService, auditService
+// newTestApplicationService wires an application service to a bus with the
+// audit subscriber attached, as main does.
+func newTestApplicationService(t *testing.T, unitOfWork UnitOfWork) *PaymentApplicationService {
+	t.Helper()
+
+	bus := eventbus.New()
+	t.Cleanup(func() { bus.Close(context.Background()) })
+	NewAuditSubscriber(audit.NewService(unitOfWork.(*mockUnitOfWork).repos.Audits)).Subscribe(bus)
+
+	return NewPaymentApplicationService(unitOfWork, readModel(unitOfWork), bus)
+}
+
+// readModel reads from the mocks unitOfWork writes to.
+func readModel(unitOfWork UnitOfWork) ReadModel {
+	repos := unitOfWork.(*mockUnitOfWork).repos
+	return ReadModel{Payments: repos.Payments, Audits: repos.Audits}
+}
+
+// mockUnitOfWork runs the callback directly against the shared mocks; it
+// cannot roll back.
+type mockUnitOfWork struct {
+	repos Repositories
+}
+
+func (m *mockUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	return fn(ctx, m.repos)
+}
+
+type mockOutbox struct {
+	messages []outbox.Message
+}
+
+func (m *mockOutbox) Add(ctx context.Context, messages ...outbox.Message) error {
+	m.messages = append(m.messages, messages...)
+	return nil
+}
+
+func (m *mockOutbox) Pending(ctx context.Context, now time.Time, limit int) ([]outbox.Message, error) {
+	return m.messages, nil
+}
+
+func (m *mockOutbox) MarkPublished(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockOutbox) MarkFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error {
+	return nil
+}
+
+func (m *mockOutbox) MarkDeadLettered(ctx context.Context, id string, reason string) error {
+	return nil
+}
+
+func (m *mockOutbox) DeadLettered(ctx context.Context) ([]outbox.Message, error) {
+	return nil, nil
 }
 
 type mockPaymentRepository struct {
@@ -312,7 +830,7 @@ func (m *mockPaymentRepository) Save(ctx context.Context, p *payment.Payment) er
 func (m *mockPaymentRepository) FindByID(ctx context.Context, id payment.PaymentID) (*payment.Payment, error) {
 	p, exists := m.payments[id.String()]
 	if !exists {
-		return nil, errors.New("payment not found")
+		return nil, payment.ErrPaymentNotFound
 	}
 	return p, nil
 }
@@ -325,9 +843,19 @@ func (m *mockPaymentRepository) FindAll(ctx context.Context) ([]*payment.Payment
 	return result, nil
 }
 
+func (m *mockPaymentRepository) FindByFilter(ctx context.Context, filter payment.PaymentFilter) ([]*payment.Payment, error) {
+	var result []*payment.Payment
+	for _, p := range m.payments {
+		if filter.Matches(p) {
+			result = append(result, p)
+		}
+	}
+	return filter.Paginate(result)
+}
+
 func (m *mockPaymentRepository) Update(ctx context.Context, p *payment.Payment) error {
 	if _, exists := m.payments[p.ID().String()]; !exists {
-		return errors.New("payment not found")
+		return payment.ErrPaymentNotFound
 	}
 	m.payments[p.ID().String()] = p
 	return nil
@@ -335,7 +863,7 @@ func (m *mockPaymentRepository) Update(ctx context.Context, p *payment.Payment)
 
 func (m *mockPaymentRepository) Delete(ctx context.Context, id payment.PaymentID) error {
 	if _, exists := m.payments[id.String()]; !exists {
-		return errors.New("payment not found")
+		return payment.ErrPaymentNotFound
 	}
 	delete(m.payments, id.String())
 	return nil
@@ -353,7 +881,7 @@ func (m *mockAuditRepository) Save(ctx context.Context, entry *audit.AuditEntry)
 func (m *mockAuditRepository) FindByID(ctx context.Context, id audit.AuditID) (*audit.AuditEntry, error) {
 	entry, exists := m.entries[id.String()]
 	if !exists {
-		return nil, errors.New("audit entry not found")
+		return nil, audit.ErrAuditEntryNotFound
 	}
 	return entry, nil
 }
@@ -365,13 +893,17 @@ func (m *mockAuditRepository) FindByEntityID(ctx context.Context, entityType aud
 			result = append(result, entry)
 		}
 	}
+	audit.SortEntries(result, audit.SortAscending)
 	return result, nil
 }
 
 func (m *mockAuditRepository) FindByFilter(ctx context.Context, filter audit.AuditFilter) ([]*audit.AuditEntry, error) {
 	result := make([]*audit.AuditEntry, 0)
 	for _, entry := range m.entries {
+		if filter.EntityID != nil && entry.EntityID() != *filter.EntityID {
+			continue
+		}
 		result = append(result, entry)
 	}
-	return result, nil
+	return filter.Paginate(result)
 }
@@ -0,0 +1,31 @@
+package application
+
+import (
+	"context"
+
+	"go-ddd/internal/application/outbox"
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/domain/payment"
+)
+
+// Repositories are the transaction-scoped repositories handed to a unit of
+// work callback. They must not be used after the callback returns.
+type Repositories struct {
+	Payments payment.Repository
+	Audits   audit.Repository
+	Outbox   outbox.Store
+}
+
+// UnitOfWork runs fn so that every write it makes through repos is committed
+// together, or not at all if fn returns an error.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
+
+// ReadModel holds the repositories queries read from directly, outside any
+// unit of work, so reads neither wait for one nor publish anything. Only
+// their read methods are used; every write goes through a UnitOfWork.
+type ReadModel struct {
+	Payments payment.Repository
+	Audits   audit.Repository
+}
@@ -15,6 +15,10 @@ func NewAuditID() AuditID {
 	return AuditID{value: uuid.New().String()}
 }
 
+func AuditIDFromString(id string) AuditID {
+	return AuditID{value: id}
+}
+
 func (id AuditID) String() string {
 	return id.value
 }
@@ -22,19 +26,25 @@ func (id AuditID) String() string {
 type EntityType string
 
 const (
-	EntityTypePayment EntityType = "payment"
+	EntityTypePayment  EntityType = "payment"
+	EntityTypeAuditLog EntityType = "audit_log"
 )
 
 type ActionType string
 
 const (
-	ActionTypeCreated   ActionType = "created"
-	ActionTypeUpdated   ActionType = "updated"
-	ActionTypeDeleted   ActionType = "deleted"
-	ActionTypeProcessed ActionType = "processed"
-	ActionTypeCompleted ActionType = "completed"
-	ActionTypeFailed    ActionType = "failed"
-	ActionTypeCancelled ActionType = "cancelled"
+	ActionTypeCreated    ActionType = "created"
+	ActionTypeUpdated    ActionType = "updated"
+	ActionTypeDeleted    ActionType = "deleted"
+	ActionTypeProcessed  ActionType = "processed"
+	ActionTypeCompleted  ActionType = "completed"
+	ActionTypeFailed     ActionType = "failed"
+	ActionTypeCancelled  ActionType = "cancelled"
+	ActionTypeRefunded   ActionType = "refunded"
+	ActionTypeAuthorized ActionType = "authorized"
+	ActionTypeCaptured   ActionType = "captured"
+	ActionTypeVoided     ActionType = "voided"
+	ActionTypeArchived   ActionType = "archived"
 )
 
 type AuditEntry struct {
@@ -47,6 +57,14 @@ type AuditEntry struct {
 	userID     string
 	timestamp  time.Time
 	metadata   map[string]string
+
+	sequence     int64
+	previousHash string
+	hash         string
+	keyID        string
+	signature    []byte
+
+	changes []Change
 }
 
 func NewAuditEntry(entityType EntityType, entityID string, action ActionType, userID string) *AuditEntry {
@@ -99,6 +117,30 @@ func (a *AuditEntry) Metadata() map[string]string {
 	return a.metadata
 }
 
+// Changes is the field-level difference between OldData and NewData, kept
+// up to date by SetOldData and SetNewData.
+func (a *AuditEntry) Changes() []Change {
+	return copyChanges(a.changes)
+}
+
+// Sequence is the entry's position in the audit log, starting at 1. It is
+// zero until the entry is linked into the log's hash chain.
+func (a *AuditEntry) Sequence() int64 {
+	return a.sequence
+}
+
+// PreviousHash is the Hash of the entry before this one, or empty for the
+// first entry of the log.
+func (a *AuditEntry) PreviousHash() string {
+	return a.previousHash
+}
+
+// Hash is the SHA-256 of the entry's canonical serialization, which covers
+// its content, sequence number and PreviousHash.
+func (a *AuditEntry) Hash() string {
+	return a.hash
+}
+
 func (a *AuditEntry) SetOldData(data interface{}) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -111,6 +153,7 @@ func (a *AuditEntry) SetOldData(data interface{}) error {
 	}
 
 	a.oldData = dataMap
+	a.changes = Diff(a.oldData, a.newData)
 	return nil
 }
 
@@ -126,6 +169,7 @@ func (a *AuditEntry) SetNewData(data interface{}) error {
 	}
 
 	a.newData = dataMap
+	a.changes = Diff(a.oldData, a.newData)
 	return nil
 }
 
@@ -133,6 +177,8 @@ func (a *AuditEntry) AddMetadata(key, value string) {
 	a.metadata[key] = value
 }
 
+// AuditFilter selects audit entries; an entry must satisfy every field that
+// is set. The single-value pointer fields and the Match sets can be combined.
 type AuditFilter struct {
 	EntityType *EntityType
 	EntityID   *string
@@ -140,4 +186,17 @@ type AuditFilter struct {
 	UserID     *string
 	FromDate   *time.Time
 	ToDate     *time.Time
+
+	EntityTypes Match[EntityType]
+	EntityIDs   Match[string]
+	Actions     Match[ActionType]
+	UserIDs     Match[string]
+	Predicates  []Predicate
+
+	// Sort orders results by timestamp, then ID. Limit caps how many are
+	// returned, with zero meaning all. Cursor resumes after the entry it was
+	// taken from; see AuditPage.
+	Sort   SortOrder
+	Limit  int
+	Cursor string
 }
@@ -321,6 +321,31 @@ func TestActionType_Values(t *testing.T) {
 			action: ActionTypeCancelled,
 			want:   "cancelled",
 		},
+		{
+			name:   "refunded action",
+			action: ActionTypeRefunded,
+			want:   "refunded",
+		},
+		{
+			name:   "authorized action",
+			action: ActionTypeAuthorized,
+			want:   "authorized",
+		},
+		{
+			name:   "captured action",
+			action: ActionTypeCaptured,
+			want:   "captured",
+		},
+		{
+			name:   "voided action",
+			action: ActionTypeVoided,
+			want:   "voided",
+		},
+		{
+			name:   "archived action",
+			action: ActionTypeArchived,
+			want:   "archived",
+		},
 	}
 
 	for _, tt := range tests {
@@ -344,6 +369,11 @@ func TestEntityType_Values(t *testing.T) {
 			entityType: EntityTypePayment,
 			want:       "payment",
 		},
+		{
+			name:       "audit log entity type",
+			entityType: EntityTypeAuditLog,
+			want:       "audit_log",
+		},
 	}
 
 	for _, tt := range tests {
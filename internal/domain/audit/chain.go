@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// canonicalEntry fixes the field order and formats hashed for an entry.
+// encoding/json sorts map keys, so equal entries always serialize equally.
+type canonicalEntry struct {
+	ID           string                 `json:"id"`
+	Sequence     int64                  `json:"sequence"`
+	PreviousHash string                 `json:"previous_hash"`
+	EntityType   string                 `json:"entity_type"`
+	EntityID     string                 `json:"entity_id"`
+	Action       string                 `json:"action"`
+	UserID       string                 `json:"user_id"`
+	Timestamp    string                 `json:"timestamp"`
+	OldData      map[string]interface{} `json:"old_data"`
+	NewData      map[string]interface{} `json:"new_data"`
+	Metadata     map[string]string      `json:"metadata"`
+	KeyID        string                 `json:"key_id,omitempty"`
+	Signature    []byte                 `json:"signature,omitempty"`
+}
+
+func (a *AuditEntry) canonical() canonicalEntry {
+	return canonicalEntry{
+		ID:           a.id.String(),
+		Sequence:     a.sequence,
+		PreviousHash: a.previousHash,
+		EntityType:   string(a.entityType),
+		EntityID:     a.entityID,
+		Action:       string(a.action),
+		UserID:       a.userID,
+		Timestamp:    a.timestamp.UTC().Format(time.RFC3339Nano),
+		OldData:      copyData(a.oldData),
+		NewData:      copyData(a.newData),
+		Metadata:     copyMetadata(a.metadata),
+		KeyID:        a.keyID,
+		Signature:    a.signature,
+	}
+}
+
+// ComputeHash returns the hash the entry should carry given its current
+// content, signature and chain position.
+func (a *AuditEntry) ComputeHash() (string, error) {
+	data, err := json.Marshal(a.canonical())
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize audit entry %s: %w", a.id, err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Chain appends the entry to a log whose last entry is previous, or which is
+// empty if previous is nil. An entry not yet in any chain is linked: given
+// the next sequence number, previous's hash and its own hash. An entry that
+// is already linked, such as one copied from another store, must continue
+// previous exactly, or ErrOutOfSequence is returned.
+func (a *AuditEntry) Chain(previous *AuditEntry) error {
+	sequence, previousHash := int64(1), ""
+	if previous != nil {
+		sequence, previousHash = previous.sequence+1, previous.hash
+	}
+
+	if a.sequence != 0 {
+		if a.sequence != sequence || a.previousHash != previousHash {
+			return fmt.Errorf("%w: entry %s has sequence %d, expected %d", ErrOutOfSequence, a.id, a.sequence, sequence)
+		}
+		if reason := a.checkHash(); reason != "" {
+			return fmt.Errorf("%w: entry %s %s", ErrOutOfSequence, a.id, reason)
+		}
+		return nil
+	}
+
+	a.sequence, a.previousHash = sequence, previousHash
+	hash, err := a.ComputeHash()
+	if err != nil {
+		a.sequence, a.previousHash = 0, ""
+		return err
+	}
+	a.hash = hash
+	return nil
+}
+
+// BrokenLinkError reports the first entry at which the audit chain no longer
+// holds. It matches ErrChainBroken with errors.Is.
+type BrokenLinkError struct {
+	Sequence int64
+	EntryID  AuditID
+	Reason   string
+}
+
+func (e *BrokenLinkError) Error() string {
+	return fmt.Sprintf("audit chain broken at sequence %d (entry %s): %s", e.Sequence, e.EntryID, e.Reason)
+}
+
+func (e *BrokenLinkError) Unwrap() error {
+	return ErrChainBroken
+}
+
+// verifyLog checks entries, ordered by sequence, from the start of the log.
+// It returns a *BrokenLinkError for the first entry that does not follow its
+// predecessor or, when keys is not nil, a *SignatureError for the first whose
+// signature does not verify, whichever comes first. Gaps left by archival are
+// bridged with the hashes the archival records list. Removing entries from the
+// end of the log goes unnoticed: only a Checkpoint can show that.
+func verifyLog(entries []*AuditEntry, keys KeyResolver) error {
+	archived := archivedHashes(entries)
+	sequence, previousHash := int64(1), ""
+	for _, entry := range entries {
+		for hash, ok := archived[sequence]; ok && sequence < entry.sequence; hash, ok = archived[sequence] {
+			sequence, previousHash = sequence+1, hash
+		}
+
+		var reason string
+		switch {
+		case entry.sequence == 0:
+			reason = "entry is not linked"
+		case entry.sequence < sequence:
+			reason = fmt.Sprintf("sequence %d appears more than once", entry.sequence)
+		case entry.sequence > sequence:
+			reason = fmt.Sprintf("entries %d to %d are missing", sequence, entry.sequence-1)
+		case entry.previousHash != previousHash:
+			reason = "previous hash does not match the preceding entry"
+		default:
+			reason = entry.checkHash()
+		}
+		if reason != "" {
+			return &BrokenLinkError{Sequence: sequence, EntryID: entry.id, Reason: reason}
+		}
+
+		if keys != nil {
+			if err := entry.VerifySignature(keys); err != nil {
+				return &SignatureError{Sequence: sequence, EntryID: entry.id, Err: err}
+			}
+		}
+
+		sequence, previousHash = entry.sequence+1, entry.hash
+	}
+
+	return nil
+}
+
+func sortBySequence(entries []*AuditEntry) {
+	slices.SortStableFunc(entries, func(a, b *AuditEntry) int {
+		return cmp.Compare(a.sequence, b.sequence)
+	})
+}
+
+func (a *AuditEntry) checkHash() string {
+	hash, err := a.ComputeHash()
+	if err != nil {
+		return err.Error()
+	}
+	if hash != a.hash {
+		return "content does not match its hash"
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// chained returns n entries linked into a fresh chain.
+func chained(t *testing.T, n int) []*AuditEntry {
+	t.Helper()
+
+	var entries []*AuditEntry
+	var previous *AuditEntry
+	for i := range n {
+		entry := NewAuditEntry(EntityTypePayment, "pay-1", ActionTypeUpdated, "user-123")
+		entry.SetNewData(map[string]interface{}{"step": i, "amount": "10.00"})
+		entry.AddMetadata("source", "test")
+		if err := entry.Chain(previous); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		entries = append(entries, entry)
+		previous = entry
+	}
+	return entries
+}
+
+func TestAuditEntry_Chain(t *testing.T) {
+	entries := chained(t, 3)
+
+	for i, entry := range entries {
+		if entry.Sequence() != int64(i+1) {
+			t.Errorf("expected sequence %d, got %d", i+1, entry.Sequence())
+		}
+		if i > 0 && entry.PreviousHash() != entries[i-1].Hash() {
+			t.Errorf("entry %d does not point at its predecessor", i+1)
+		}
+	}
+	if entries[0].PreviousHash() != "" || len(entries[0].Hash()) != 64 {
+		t.Errorf("unexpected first link %q -> %q", entries[0].PreviousHash(), entries[0].Hash())
+	}
+
+	// The hash survives a trip through storage.
+	data, _ := json.Marshal(entries[1].ToSnapshot())
+	var snapshot EntrySnapshot
+	json.Unmarshal(data, &snapshot)
+	restored, err := FromSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hash, _ := restored.ComputeHash(); hash != entries[1].Hash() {
+		t.Errorf("expected hash %s after round trip, got %s", entries[1].Hash(), hash)
+	}
+
+	// A linked entry can only be appended where it belongs.
+	if err := restored.Chain(entries[0]); err != nil {
+		t.Errorf("expected entry to continue its predecessor, got %v", err)
+	}
+	if err := restored.Chain(entries[2]); !errors.Is(err, ErrOutOfSequence) {
+		t.Errorf("expected ErrOutOfSequence, got %v", err)
+	}
+	restored.AddMetadata("source", "forged")
+	if err := restored.Chain(entries[0]); !errors.Is(err, ErrOutOfSequence) {
+		t.Errorf("expected altered entry to be rejected, got %v", err)
+	}
+}
+
+func TestService_Verify(t *testing.T) {
+	tests := []struct {
+		name         string
+		tamper       func(entries []*AuditEntry) []*AuditEntry
+		wantSequence int64
+		wantReason   string
+	}{
+		{
+			name:   "intact",
+			tamper: func(entries []*AuditEntry) []*AuditEntry { return entries },
+		},
+		{
+			name: "altered data",
+			tamper: func(entries []*AuditEntry) []*AuditEntry {
+				entries[2].NewData()["amount"] = "1000.00"
+				return entries
+			},
+			wantSequence: 3,
+			wantReason:   "content does not match its hash",
+		},
+		{
+			name: "altered timestamp",
+			tamper: func(entries []*AuditEntry) []*AuditEntry {
+				entries[1].timestamp = entries[1].timestamp.Add(-time.Hour)
+				return entries
+			},
+			wantSequence: 2,
+			wantReason:   "content does not match its hash",
+		},
+		{
+			name: "deleted entry",
+			tamper: func(entries []*AuditEntry) []*AuditEntry {
+				return append(entries[:1], entries[2:]...)
+			},
+			wantSequence: 2,
+			wantReason:   "entries 2 to 2 are missing",
+		},
+		{
+			name: "rehashed after alteration",
+			tamper: func(entries []*AuditEntry) []*AuditEntry {
+				entries[1].AddMetadata("source", "forged")
+				entries[1].hash, _ = entries[1].ComputeHash()
+				return entries
+			},
+			wantSequence: 3,
+			wantReason:   "previous hash does not match the preceding entry",
+		},
+		{
+			name: "duplicated entry",
+			tamper: func(entries []*AuditEntry) []*AuditEntry {
+				return append(entries[:2], entries[1:]...)
+			},
+			wantSequence: 3,
+			wantReason:   "sequence 2 appears more than once",
+		},
+		{
+			name: "unlinked entry",
+			tamper: func(entries []*AuditEntry) []*AuditEntry {
+				return append(entries, NewAuditEntry(EntityTypePayment, "pay-1", ActionTypeUpdated, "user-123"))
+			},
+			wantSequence: 1,
+			wantReason:   "entry is not linked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &entryLog{entries: tt.tamper(chained(t, 4))}
+
+			err := NewService(log).Verify(context.Background())
+
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var broken *BrokenLinkError
+			if !errors.As(err, &broken) || !errors.Is(err, ErrChainBroken) {
+				t.Fatalf("expected BrokenLinkError, got %v", err)
+			}
+			if broken.Sequence != tt.wantSequence || broken.Reason != tt.wantReason {
+				t.Errorf("expected break at %d (%s), got %d (%s)", tt.wantSequence, tt.wantReason, broken.Sequence, broken.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is one difference between an entry's OldData and NewData. Path
+// names the field with dots for nested maps and [i] for slice elements, as
+// in "refunds[0].amount".
+type Change struct {
+	Path string      `json:"path"`
+	Kind ChangeKind  `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// String renders the change for people, as in "status: pending → processing".
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s: added %v", c.Path, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("%s: removed %v", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("%s: %v → %v", c.Path, c.Old, c.New)
+	}
+}
+
+// Diff returns the changes that turn oldData into newData, ordered by path.
+// Nested maps and slices are compared element by element; values of
+// different kinds at the same path are reported as a single change.
+func Diff(oldData, newData map[string]interface{}) []Change {
+	var changes []Change
+	diffMaps("", oldData, newData, &changes)
+	return changes
+}
+
+func diffMaps(prefix string, oldData, newData map[string]interface{}, changes *[]Change) {
+	keys := slices.Sorted(maps.Keys(oldData))
+	for key := range newData {
+		if _, ok := oldData[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		path := joinPath(prefix, key)
+		oldValue, inOld := oldData[key]
+		newValue, inNew := newData[key]
+		switch {
+		case !inOld:
+			*changes = append(*changes, Change{Path: path, Kind: ChangeAdded, New: newValue})
+		case !inNew:
+			*changes = append(*changes, Change{Path: path, Kind: ChangeRemoved, Old: oldValue})
+		default:
+			diffValues(path, oldValue, newValue, changes)
+		}
+	}
+}
+
+func diffValues(path string, oldValue, newValue interface{}, changes *[]Change) {
+	switch oldValue := oldValue.(type) {
+	case map[string]interface{}:
+		if newValue, ok := newValue.(map[string]interface{}); ok {
+			diffMaps(path, oldValue, newValue, changes)
+			return
+		}
+	case []interface{}:
+		if newValue, ok := newValue.([]interface{}); ok {
+			diffSlices(path, oldValue, newValue, changes)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*changes = append(*changes, Change{Path: path, Kind: ChangeChanged, Old: oldValue, New: newValue})
+	}
+}
+
+func diffSlices(path string, oldValue, newValue []interface{}, changes *[]Change) {
+	for i := range max(len(oldValue), len(newValue)) {
+		elementPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(oldValue):
+			*changes = append(*changes, Change{Path: elementPath, Kind: ChangeAdded, New: newValue[i]})
+		case i >= len(newValue):
+			*changes = append(*changes, Change{Path: elementPath, Kind: ChangeRemoved, Old: oldValue[i]})
+		default:
+			diffValues(elementPath, oldValue[i], newValue[i], changes)
+		}
+	}
+}
+
+// joinPath quotes keys that would otherwise read as path syntax.
+func joinPath(prefix, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]\"") {
+		return prefix + "[" + strconv.Quote(key) + "]"
+	}
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldData map[string]interface{}
+		newData map[string]interface{}
+		want    []string
+	}{
+		{
+			name:    "no changes",
+			oldData: map[string]interface{}{"status": "pending"},
+			newData: map[string]interface{}{"status": "pending"},
+			want:    nil,
+		},
+		{
+			name:    "changed, added and removed",
+			oldData: map[string]interface{}{"status": "pending", "note": "first"},
+			newData: map[string]interface{}{"status": "processing", "amount": "10.00"},
+			want:    []string{"amount: added 10.00", "note: removed first", "status: pending → processing"},
+		},
+		{
+			name:    "nested maps",
+			oldData: map[string]interface{}{"card": map[string]interface{}{"brand": "visa", "last4": "4242"}},
+			newData: map[string]interface{}{"card": map[string]interface{}{"brand": "visa", "last4": "1881", "expiry": "12/30"}},
+			want:    []string{"card.expiry: added 12/30", "card.last4: 4242 → 1881"},
+		},
+		{
+			name: "slices",
+			oldData: map[string]interface{}{"refunds": []interface{}{
+				map[string]interface{}{"amount": "5.00"},
+				map[string]interface{}{"amount": "2.00"},
+			}},
+			newData: map[string]interface{}{"refunds": []interface{}{
+				map[string]interface{}{"amount": "5.00"},
+				map[string]interface{}{"amount": "3.00"},
+				map[string]interface{}{"amount": "1.00"},
+			}},
+			want: []string{"refunds[1].amount: 2.00 → 3.00", "refunds[2]: added map[amount:1.00]"},
+		},
+		{
+			name:    "kind changes",
+			oldData: map[string]interface{}{"tags": []interface{}{"a"}},
+			newData: map[string]interface{}{"tags": "a"},
+			want:    []string{"tags: [a] → a"},
+		},
+		{
+			name:    "keys that look like paths",
+			oldData: map[string]interface{}{"a.b": float64(1)},
+			newData: map[string]interface{}{"a.b": float64(2)},
+			want:    []string{`["a.b"]: 1 → 2`},
+		},
+		{
+			name:    "nothing before",
+			oldData: nil,
+			newData: map[string]interface{}{"status": "pending"},
+			want:    []string{"status: added pending"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, change := range Diff(tt.oldData, tt.newData) {
+				got = append(got, change.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuditEntry_Changes(t *testing.T) {
+	entry := NewAuditEntry(EntityTypePayment, "pay-1", ActionTypeProcessed, "user-123")
+	entry.SetOldData(map[string]interface{}{"status": "pending"})
+	entry.SetNewData(map[string]interface{}{"status": "processing"})
+
+	want := []Change{{Path: "status", Kind: ChangeChanged, Old: "pending", New: "processing"}}
+	if !reflect.DeepEqual(entry.Changes(), want) {
+		t.Errorf("expected %v, got %v", want, entry.Changes())
+	}
+
+	restored, err := FromSnapshot(entry.ToSnapshot())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(restored.Changes(), want) {
+		t.Errorf("expected changes to survive a snapshot, got %v", restored.Changes())
+	}
+
+	entry.Changes()[0].New = "tampered"
+	if entry.Changes()[0].New != "processing" {
+		t.Error("expected Changes to return a copy")
+	}
+}
@@ -0,0 +1,21 @@
+package audit
+
+import "errors"
+
+var (
+	ErrAuditEntryNotFound   = errors.New("audit entry not found")
+	ErrUnknownPaymentStatus = errors.New("unknown payment status")
+	ErrInvalidSnapshot      = errors.New("invalid audit entry snapshot")
+	ErrDuplicateEntry       = errors.New("audit entry already exists")
+	ErrOutOfSequence        = errors.New("audit entry does not continue the log")
+	ErrChainBroken          = errors.New("audit chain is broken")
+	ErrInvalidSignature     = errors.New("invalid audit signature")
+	ErrUnknownKey           = errors.New("unknown audit signing key")
+	ErrNoKeyProvider        = errors.New("audit service has no key provider")
+	ErrInvalidRedactionRule = errors.New("invalid redaction rule")
+	ErrLegalHoldNotFound    = errors.New("legal hold not found")
+	ErrUnderLegalHold       = errors.New("audit entity is under legal hold")
+	ErrInvalidCursor        = errors.New("invalid audit cursor")
+	ErrInvalidFilter        = errors.New("invalid audit filter")
+	ErrInvalidQuery         = errors.New("invalid audit query")
+)
@@ -0,0 +1,255 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Match restricts a field to any of Values or, with Not, to none of them.
+// The zero Match allows every value.
+type Match[T comparable] struct {
+	Values []T
+	Not    bool
+}
+
+func AnyOf[T comparable](values ...T) Match[T] {
+	return Match[T]{Values: values}
+}
+
+func NoneOf[T comparable](values ...T) Match[T] {
+	return Match[T]{Values: values, Not: true}
+}
+
+func (m Match[T]) IsZero() bool {
+	return len(m.Values) == 0
+}
+
+func (m Match[T]) Matches(value T) bool {
+	return m.IsZero() || slices.Contains(m.Values, value) != m.Not
+}
+
+type Operator string
+
+const (
+	OpEqual          Operator = "="
+	OpNotEqual       Operator = "!="
+	OpGreater        Operator = ">"
+	OpGreaterOrEqual Operator = ">="
+	OpLess           Operator = "<"
+	OpLessOrEqual    Operator = "<="
+	OpContains       Operator = "~"
+	OpExists         Operator = "exists"
+	OpNotExists      Operator = "not exists"
+)
+
+// Predicate tests a value inside an entry. Path starts with "metadata."
+// followed by a metadata key, or with "newData." or "oldData." followed by a
+// path into the data written as in Change.Path, where "[*]" matches any
+// element. When a path reaches several values the predicate holds if any of
+// them satisfies it; OpNotEqual and OpNotExists hold if none is equal or
+// present. Values are compared as exact decimals when both sides are
+// numbers, including numeric strings such as recorded amounts, and as
+// strings otherwise.
+type Predicate struct {
+	Path  string
+	Op    Operator
+	Value interface{}
+}
+
+const (
+	metadataRoot = "metadata"
+	newDataRoot  = "newData"
+	oldDataRoot  = "oldData"
+)
+
+// Validate reports malformed sort orders, limits, cursors and predicates,
+// which match nothing, with ErrInvalidFilter or ErrInvalidCursor.
+func (f AuditFilter) Validate() error {
+	if f.Sort != "" && f.Sort != SortAscending && f.Sort != SortDescending {
+		return fmt.Errorf("%w: unknown sort order %q", ErrInvalidFilter, f.Sort)
+	}
+	if f.Limit < 0 {
+		return fmt.Errorf("%w: limit cannot be negative", ErrInvalidFilter)
+	}
+	if f.Cursor != "" {
+		if _, err := DecodeCursor(f.Cursor); err != nil {
+			return err
+		}
+	}
+	for _, predicate := range f.Predicates {
+		if _, _, err := predicate.parse(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether entry satisfies every condition of the filter.
+// Sort, Limit and Cursor are not conditions; see Paginate.
+func (f AuditFilter) Matches(entry *AuditEntry) bool {
+	switch {
+	case f.EntityType != nil && entry.entityType != *f.EntityType,
+		f.EntityID != nil && entry.entityID != *f.EntityID,
+		f.Action != nil && entry.action != *f.Action,
+		f.UserID != nil && entry.userID != *f.UserID,
+		f.FromDate != nil && entry.timestamp.Before(*f.FromDate),
+		f.ToDate != nil && entry.timestamp.After(*f.ToDate),
+		!f.EntityTypes.Matches(entry.entityType),
+		!f.EntityIDs.Matches(entry.entityID),
+		!f.Actions.Matches(entry.action),
+		!f.UserIDs.Matches(entry.userID):
+		return false
+	}
+
+	for _, predicate := range f.Predicates {
+		if !predicate.Matches(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether the predicate holds for entry. Malformed
+// predicates hold for none.
+func (p Predicate) Matches(entry *AuditEntry) bool {
+	root, segments, err := p.parse()
+	if err != nil {
+		return false
+	}
+
+	var values []interface{}
+	switch root {
+	case metadataRoot:
+		if value, ok := entry.metadata[segments[0]]; ok {
+			values = []interface{}{value}
+		}
+	case newDataRoot:
+		values = lookup(entry.newData, segments)
+	case oldDataRoot:
+		values = lookup(entry.oldData, segments)
+	}
+
+	switch p.Op {
+	case OpExists:
+		return len(values) > 0
+	case OpNotExists:
+		return len(values) == 0
+	case OpNotEqual:
+		return !slices.ContainsFunc(values, func(v interface{}) bool { return compare(OpEqual, v, p.Value) })
+	}
+	return slices.ContainsFunc(values, func(v interface{}) bool { return compare(p.Op, v, p.Value) })
+}
+
+// parse splits the predicate's path into its root and the segments below it.
+// A metadata key is a single segment, however many dots it holds.
+func (p Predicate) parse() (string, []string, error) {
+	switch p.Op {
+	case OpEqual, OpNotEqual, OpGreater, OpGreaterOrEqual, OpLess, OpLessOrEqual, OpContains, OpExists, OpNotExists:
+	default:
+		return "", nil, fmt.Errorf("unknown operator %q", p.Op)
+	}
+
+	root, rest, _ := strings.Cut(p.Path, ".")
+	if rest == "" {
+		return "", nil, fmt.Errorf("path %q needs a field after %q", p.Path, root)
+	}
+	switch root {
+	case metadataRoot:
+		return root, []string{rest}, nil
+	case newDataRoot, oldDataRoot:
+		segments, err := parsePath(rest)
+		return root, segments, err
+	}
+	return "", nil, fmt.Errorf("path %q must start with metadata, newData or oldData", p.Path)
+}
+
+// lookup returns the values at segments below value; more than one only
+// when a segment is "[*]".
+func lookup(value interface{}, segments []string) []interface{} {
+	if len(segments) == 0 {
+		return []interface{}{value}
+	}
+
+	segment, rest := segments[0], segments[1:]
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if child, ok := v[segment]; ok {
+			return lookup(child, rest)
+		}
+	case []interface{}:
+		if segment == "[*]" {
+			var values []interface{}
+			for _, item := range v {
+				values = append(values, lookup(item, rest)...)
+			}
+			return values
+		}
+		index, err := strconv.Atoi(strings.Trim(segment, "[]"))
+		if err == nil && strings.HasPrefix(segment, "[") && index >= 0 && index < len(v) {
+			return lookup(v[index], rest)
+		}
+	}
+	return nil
+}
+
+func compare(op Operator, actual, expected interface{}) bool {
+	if op == OpContains {
+		s, ok := actual.(string)
+		return ok && strings.Contains(s, stringify(expected))
+	}
+
+	var order int
+	a, aNumeric := decimal(actual)
+	b, bNumeric := decimal(expected)
+	switch {
+	case aNumeric && bNumeric:
+		order = a.Cmp(b)
+	case op == OpEqual:
+		return stringify(actual) == stringify(expected)
+	default:
+		s, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		order = strings.Compare(s, stringify(expected))
+	}
+
+	switch op {
+	case OpEqual:
+		return order == 0
+	case OpGreater:
+		return order > 0
+	case OpGreaterOrEqual:
+		return order >= 0
+	case OpLess:
+		return order < 0
+	case OpLessOrEqual:
+		return order <= 0
+	}
+	return false
+}
+
+// decimal reads numbers and numeric strings exactly.
+func decimal(value interface{}) (*big.Rat, bool) {
+	switch v := value.(type) {
+	case float64:
+		r := new(big.Rat).SetFloat64(v)
+		return r, r != nil
+	case int:
+		return new(big.Rat).SetInt64(int64(v)), true
+	case int64:
+		return new(big.Rat).SetInt64(v), true
+	case json.Number:
+		return decimal(string(v))
+	case string:
+		if v == "" || strings.ContainsAny(v, "/eE") {
+			return nil, false
+		}
+		return new(big.Rat).SetString(v)
+	}
+	return nil, false
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuditFilter_Matches(t *testing.T) {
+	entry := NewAuditEntry(EntityTypePayment, "pay-1", ActionTypeRefunded, "user-123")
+	entry.AddMetadata("source", "api")
+	entry.AddMetadata("request.id", "req-9")
+	entry.SetOldData(map[string]interface{}{"status": "completed", "refunded_amount": "0.00"})
+	entry.SetNewData(map[string]interface{}{
+		"status":       "refunded",
+		"amount":       "1500.00",
+		"amount_minor": 150000,
+		"reason":       "customer asked for a refund",
+		"refunds":      []interface{}{map[string]interface{}{"amount": "10.00"}, map[string]interface{}{"amount": "1490.00"}},
+	})
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   bool
+	}{
+		{"any of several actions", AuditFilter{Actions: AnyOf(ActionTypeCaptured, ActionTypeRefunded)}, true},
+		{"none of several actions", AuditFilter{Actions: NoneOf(ActionTypeCaptured, ActionTypeRefunded)}, false},
+		{"excluded user", AuditFilter{UserIDs: NoneOf("admin")}, true},
+		{"entity sets", AuditFilter{EntityTypes: AnyOf(EntityTypePayment), EntityIDs: AnyOf("pay-2")}, false},
+		{"metadata value", AuditFilter{Predicates: []Predicate{{Path: "metadata.source", Op: OpEqual, Value: "api"}}}, true},
+		{"metadata key with dots", AuditFilter{Predicates: []Predicate{{Path: "metadata.request.id", Op: OpEqual, Value: "req-9"}}}, true},
+		{"missing metadata", AuditFilter{Predicates: []Predicate{{Path: "metadata.tenant", Op: OpExists}}}, false},
+		{"missing metadata is not equal", AuditFilter{Predicates: []Predicate{{Path: "metadata.tenant", Op: OpNotEqual, Value: "x"}}}, true},
+		{"decimal string above", AuditFilter{Predicates: []Predicate{{Path: "newData.amount", Op: OpGreater, Value: "1000"}}}, true},
+		{"decimal string compared exactly", AuditFilter{Predicates: []Predicate{{Path: "newData.amount", Op: OpLessOrEqual, Value: 1500}}}, true},
+		{"number below", AuditFilter{Predicates: []Predicate{{Path: "newData.amount_minor", Op: OpLess, Value: "100000"}}}, false},
+		{"old data", AuditFilter{Predicates: []Predicate{{Path: "oldData.status", Op: OpEqual, Value: "completed"}}}, true},
+		{"contains", AuditFilter{Predicates: []Predicate{{Path: "newData.reason", Op: OpContains, Value: "refund"}}}, true},
+		{"any element", AuditFilter{Predicates: []Predicate{{Path: "newData.refunds[*].amount", Op: OpGreater, Value: "1000"}}}, true},
+		{"no element equal", AuditFilter{Predicates: []Predicate{{Path: "newData.refunds[*].amount", Op: OpNotEqual, Value: "10.00"}}}, false},
+		{"indexed element", AuditFilter{Predicates: []Predicate{{Path: "newData.refunds[0].amount", Op: OpEqual, Value: "10"}}}, true},
+		{"string ordering", AuditFilter{Predicates: []Predicate{{Path: "newData.status", Op: OpGreater, Value: "pending"}}}, true},
+		{"not exists", AuditFilter{Predicates: []Predicate{{Path: "oldData.reason", Op: OpNotExists}}}, true},
+		{"all predicates must hold", AuditFilter{Predicates: []Predicate{
+			{Path: "newData.status", Op: OpEqual, Value: "refunded"},
+			{Path: "metadata.source", Op: OpEqual, Value: "batch"},
+		}}, false},
+		{"malformed predicate", AuditFilter{Predicates: []Predicate{{Path: "data.status", Op: OpEqual, Value: "refunded"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(entry); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuditFilter_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  AuditFilter
+		wantErr error
+	}{
+		{"valid", AuditFilter{Predicates: []Predicate{{Path: "newData.refunds[*].amount", Op: OpGreater, Value: 1}}}, nil},
+		{"unknown root", AuditFilter{Predicates: []Predicate{{Path: "data.amount", Op: OpEqual}}}, ErrInvalidFilter},
+		{"no field", AuditFilter{Predicates: []Predicate{{Path: "metadata", Op: OpExists}}}, ErrInvalidFilter},
+		{"unknown operator", AuditFilter{Predicates: []Predicate{{Path: "newData.amount", Op: "like"}}}, ErrInvalidFilter},
+		{"bad index", AuditFilter{Predicates: []Predicate{{Path: "newData.refunds[x]", Op: OpExists}}}, ErrInvalidFilter},
+		{"unknown sort", AuditFilter{Sort: "random"}, ErrInvalidFilter},
+		{"bad cursor", AuditFilter{Cursor: "%"}, ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
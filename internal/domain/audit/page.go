@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+// AuditPage is one page of a query. NextCursor, set as the next query's
+// AuditFilter.Cursor, continues where the page ends; it is empty on the last
+// page.
+type AuditPage struct {
+	Entries    []*AuditEntry
+	NextCursor string
+}
+
+// Cursor is the position an AuditFilter.Cursor encodes: the timestamp and ID
+// of the last entry already returned. Callers treat cursors as opaque;
+// adapters that resume queries themselves decode them with DecodeCursor.
+type Cursor struct {
+	Timestamp time.Time
+	ID        AuditID
+}
+
+// CursorAfter returns the cursor that resumes a query after entry.
+func CursorAfter(entry *AuditEntry) string {
+	raw := strconv.FormatInt(entry.timestamp.UnixNano(), 10) + ":" + entry.id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return Cursor{Timestamp: time.Unix(0, n), ID: AuditIDFromString(id)}, nil
+}
+
+// compareEntries orders entries by timestamp, then ID, ascending. It compares
+// wall-clock nanoseconds, as cursors do, not monotonic clock readings.
+func compareEntries(a, b *AuditEntry) int {
+	return cmp.Or(cmp.Compare(a.timestamp.UnixNano(), b.timestamp.UnixNano()), cmp.Compare(a.id.String(), b.id.String()))
+}
+
+// SortEntries sorts entries in place by timestamp, then ID, in order.
+func SortEntries(entries []*AuditEntry, order SortOrder) {
+	slices.SortFunc(entries, comparer(order))
+}
+
+func comparer(order SortOrder) func(a, b *AuditEntry) int {
+	if order == SortDescending {
+		return func(a, b *AuditEntry) int { return compareEntries(b, a) }
+	}
+	return compareEntries
+}
+
+// Paginate sorts entries, which already match the filter, in the filter's
+// order and returns those after its cursor, at most Limit of them. It fails
+// if the filter does not Validate.
+func (f AuditFilter) Paginate(entries []*AuditEntry) ([]*AuditEntry, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	compare := comparer(f.Sort)
+	sorted := slices.SortedFunc(slices.Values(entries), compare)
+
+	if f.Cursor != "" {
+		cursor, err := DecodeCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		position := &AuditEntry{id: cursor.ID, timestamp: cursor.Timestamp}
+		start, _ := slices.BinarySearchFunc(sorted, position, compare)
+		if start < len(sorted) && compare(sorted[start], position) == 0 {
+			start++
+		}
+		sorted = sorted[start:]
+	}
+
+	if f.Limit > 0 && len(sorted) > f.Limit {
+		sorted = sorted[:f.Limit]
+	}
+	return sorted, nil
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAuditFilter_Paginate(t *testing.T) {
+	at := func(minute int, id string) *AuditEntry {
+		return &AuditEntry{id: AuditIDFromString(id), timestamp: base.Add(time.Duration(minute) * time.Minute)}
+	}
+	entries := []*AuditEntry{at(2, "c"), at(1, "b"), at(3, "d"), at(1, "a")}
+
+	tests := []struct {
+		name    string
+		filter  AuditFilter
+		want    []string
+		wantErr error
+	}{
+		{"ascending by default, ties by ID", AuditFilter{}, []string{"a", "b", "c", "d"}, nil},
+		{"descending", AuditFilter{Sort: SortDescending}, []string{"d", "c", "b", "a"}, nil},
+		{"limit", AuditFilter{Limit: 2}, []string{"a", "b"}, nil},
+		{"cursor", AuditFilter{Cursor: CursorAfter(at(1, "b"))}, []string{"c", "d"}, nil},
+		{"descending cursor", AuditFilter{Sort: SortDescending, Cursor: CursorAfter(at(2, "c")), Limit: 1}, []string{"b"}, nil},
+		{"cursor of a removed entry", AuditFilter{Cursor: CursorAfter(at(2, "bb"))}, []string{"c", "d"}, nil},
+		{"cursor past the end", AuditFilter{Cursor: CursorAfter(at(9, "z"))}, nil, nil},
+		{"invalid cursor", AuditFilter{Cursor: "!"}, nil, ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := tt.filter.Paginate(entries)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			var got []string
+			for _, entry := range page {
+				got = append(got, entry.ID().String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestService_GetAuditPage(t *testing.T) {
+	ctx := context.Background()
+	log := &entryLog{}
+	for i := range 5 {
+		log.add(t, "pay-1", ActionTypeUpdated, base.Add(time.Duration(i)*time.Minute), nil, map[string]interface{}{"step": i})
+	}
+	service := NewService(log)
+
+	page, err := service.GetAuditPage(ctx, AuditFilter{Sort: SortDescending, Limit: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Entries) != 3 || page.Entries[0].Timestamp() != base.Add(4*time.Minute) || page.NextCursor == "" {
+		t.Fatalf("expected the 3 newest entries and a cursor, got %d entries", len(page.Entries))
+	}
+
+	page, err = service.GetAuditPage(ctx, AuditFilter{Sort: SortDescending, Limit: 3, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Entries) != 2 || page.Entries[1].Timestamp() != base || page.NextCursor != "" {
+		t.Errorf("expected the 2 oldest entries and no cursor, got %d entries and %q", len(page.Entries), page.NextCursor)
+	}
+
+	var streamed []time.Time
+	for entry, err := range service.Stream(ctx, AuditFilter{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		streamed = append(streamed, entry.Timestamp())
+	}
+	if len(streamed) != 5 || !slices.IsSortedFunc(streamed, time.Time.Compare) {
+		t.Errorf("expected all 5 entries in order, got %v", streamed)
+	}
+}
@@ -0,0 +1,344 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseQuery parses the audit query language into a filter. A query is a
+// list of conditions joined by AND:
+//
+//	action IN (processed, completed) AND user_id != admin
+//	entity_type = payment AND timestamp >= 2024-01-01
+//	metadata.source = api AND newData.amount > 1000
+//	NOT EXISTS oldData.status AND newData.description ~ "gift card"
+//
+// entity_type, entity_id, action and user_id take =, !=, IN and NOT IN, each
+// at most once. timestamp takes =, >, >=, < and <= with an RFC 3339 time or a
+// date, which stands for the whole UTC day: = matches any time that day and
+// <= includes it. metadata.<key>, newData.<path> and oldData.<path> take =, !=, >, >=,
+// <, <= and ~ (contains), and EXISTS or NOT EXISTS before them; see
+// Predicate. Keywords are case-insensitive. Values are bare words or
+// double-quoted Go strings.
+func ParseQuery(query string) (AuditFilter, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return AuditFilter{}, err
+	}
+
+	p := &queryParser{tokens: tokens, end: len(query)}
+	if len(tokens) == 0 {
+		return p.filter, nil
+	}
+	for {
+		if err := p.condition(); err != nil {
+			return AuditFilter{}, err
+		}
+		if p.done() {
+			return p.filter, nil
+		}
+		if !p.keyword("AND") {
+			return AuditFilter{}, p.errorf("expected AND, got %q", p.peek().text)
+		}
+	}
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	stringToken
+	symbolToken
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(query) && query[end] != '"' {
+				if query[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(query) {
+				return nil, fmt.Errorf("%w: unterminated string at offset %d", ErrInvalidQuery, i)
+			}
+			text, err := strconv.Unquote(query[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid string at offset %d", ErrInvalidQuery, i)
+			}
+			tokens = append(tokens, token{stringToken, text, i})
+			i = end + 1
+		case strings.HasPrefix(query[i:], "!=") || strings.HasPrefix(query[i:], ">=") || strings.HasPrefix(query[i:], "<="):
+			tokens = append(tokens, token{symbolToken, query[i : i+2], i})
+			i += 2
+		case strings.IndexByte("()=<>~,", c) >= 0:
+			tokens = append(tokens, token{symbolToken, query[i : i+1], i})
+			i++
+		default:
+			end := i
+			for end < len(query) && !unicode.IsSpace(rune(query[end])) && strings.IndexByte(`()=<>~,!"`, query[end]) < 0 {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrInvalidQuery, c, i)
+			}
+			tokens = append(tokens, token{wordToken, query[i:end], i})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []token
+	pos    int
+	end    int
+	filter AuditFilter
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *queryParser) peek() token {
+	if p.done() {
+		return token{kind: symbolToken, offset: p.end}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrInvalidQuery, fmt.Sprintf(format, args...), p.peek().offset)
+}
+
+// keyword consumes the next token if it is the keyword word.
+func (p *queryParser) keyword(word string) bool {
+	if t := p.peek(); t.kind == wordToken && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) value() (string, error) {
+	t := p.peek()
+	if t.kind == symbolToken {
+		if p.done() {
+			return "", p.errorf("expected a value")
+		}
+		return "", p.errorf("expected a value, got %q", t.text)
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func (p *queryParser) list() ([]string, error) {
+	if p.next().text != "(" {
+		p.pos--
+		return nil, p.errorf("expected (")
+	}
+
+	var values []string
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		switch p.next().text {
+		case ",":
+		case ")":
+			return values, nil
+		default:
+			p.pos--
+			return nil, p.errorf("expected , or )")
+		}
+	}
+}
+
+func (p *queryParser) condition() error {
+	switch {
+	case p.keyword("EXISTS"):
+		return p.exists(OpExists)
+	case p.keyword("NOT"):
+		if !p.keyword("EXISTS") {
+			return p.errorf("expected EXISTS after NOT")
+		}
+		return p.exists(OpNotExists)
+	}
+
+	field := p.peek()
+	if field.kind != wordToken {
+		return p.errorf("expected a field")
+	}
+	p.pos++
+
+	var op Operator
+	var values []string
+	switch {
+	case p.keyword("IN"):
+		op = OpEqual
+	case p.keyword("NOT"):
+		if !p.keyword("IN") {
+			return p.errorf("expected IN after NOT")
+		}
+		op = OpNotEqual
+	default:
+		symbol := p.peek()
+		if symbol.kind != symbolToken || symbol.text == "" || strings.Contains("(),", symbol.text) {
+			return p.errorf("expected an operator after %s", field.text)
+		}
+		p.pos++
+		op = Operator(symbol.text)
+
+		value, err := p.value()
+		if err != nil {
+			return err
+		}
+		values = []string{value}
+	}
+	if values == nil {
+		var err error
+		if values, err = p.list(); err != nil {
+			return err
+		}
+	}
+
+	return p.apply(field, op, values)
+}
+
+func (p *queryParser) exists(op Operator) error {
+	path := p.peek()
+	if path.kind != wordToken {
+		return p.errorf("expected a path after EXISTS")
+	}
+	p.pos++
+
+	predicate := Predicate{Path: path.text, Op: op}
+	if _, _, err := predicate.parse(); err != nil {
+		return fmt.Errorf("%w: %w at offset %d", ErrInvalidQuery, err, path.offset)
+	}
+	p.filter.Predicates = append(p.filter.Predicates, predicate)
+	return nil
+}
+
+func (p *queryParser) apply(field token, op Operator, values []string) error {
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s at offset %d", ErrInvalidQuery, fmt.Sprintf(format, args...), field.offset)
+	}
+
+	switch name := strings.ToLower(field.text); name {
+	case "entity_type", "entity_id", "action", "user_id":
+		if op != OpEqual && op != OpNotEqual {
+			return fail("%s only takes =, !=, IN and NOT IN", name)
+		}
+		match := Match[string]{Values: values, Not: op == OpNotEqual}
+		var set bool
+		switch name {
+		case "entity_type":
+			set, p.filter.EntityTypes = !p.filter.EntityTypes.IsZero(), convertMatch[EntityType](match)
+		case "entity_id":
+			set, p.filter.EntityIDs = !p.filter.EntityIDs.IsZero(), match
+		case "action":
+			set, p.filter.Actions = !p.filter.Actions.IsZero(), convertMatch[ActionType](match)
+		case "user_id":
+			set, p.filter.UserIDs = !p.filter.UserIDs.IsZero(), match
+		}
+		if set {
+			return fail("%s appears more than once", name)
+		}
+		return nil
+
+	case "timestamp":
+		if len(values) != 1 {
+			return fail("timestamp does not take IN")
+		}
+		start, end, err := parseQueryTime(values[0])
+		if err != nil {
+			return fail("%v", err)
+		}
+		return p.timestamp(op, start, end, fail)
+	}
+
+	if len(values) != 1 {
+		return fail("%s does not take IN", field.text)
+	}
+	predicate := Predicate{Path: field.text, Op: op, Value: values[0]}
+	if _, _, err := predicate.parse(); err != nil {
+		return fail("%v", err)
+	}
+	p.filter.Predicates = append(p.filter.Predicates, predicate)
+	return nil
+}
+
+// timestamp bounds the filter by the period from start to end, inclusive.
+func (p *queryParser) timestamp(op Operator, start, end time.Time, fail func(string, ...interface{}) error) error {
+	from, to := &p.filter.FromDate, &p.filter.ToDate
+	set := func(bound **time.Time, at time.Time) error {
+		if *bound != nil {
+			return fail("timestamp bound appears more than once")
+		}
+		*bound = &at
+		return nil
+	}
+
+	switch op {
+	case OpEqual:
+		if err := set(from, start); err != nil {
+			return err
+		}
+		return set(to, end)
+	case OpGreaterOrEqual:
+		return set(from, start)
+	case OpGreater:
+		return set(from, end.Add(time.Nanosecond))
+	case OpLessOrEqual:
+		return set(to, end)
+	case OpLess:
+		return set(to, start.Add(-time.Nanosecond))
+	}
+	return fail("timestamp does not take %s", op)
+}
+
+// parseQueryTime returns the first and last instant value stands for: the
+// same one for a time, and the whole day for a date.
+func parseQueryTime(value string) (start, end time.Time, err error) {
+	if at, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return at, at, nil
+	}
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		return day, day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+func convertMatch[T ~string](m Match[string]) Match[T] {
+	values := make([]T, len(m.Values))
+	for i, value := range m.Values {
+		values[i] = T(value)
+	}
+	return Match[T]{Values: values, Not: m.Not}
+}
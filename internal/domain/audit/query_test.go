@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	instant := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	at := func(t time.Time) *time.Time { return &t }
+	endOfDay := date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+
+	tests := []struct {
+		name  string
+		query string
+		want  AuditFilter
+	}{
+		{"empty", "  ", AuditFilter{}},
+		{
+			name:  "sets and negation",
+			query: `action IN (processed, completed) and user_id != admin AND entity_type NOT IN (refund)`,
+			want: AuditFilter{
+				Actions:     AnyOf(ActionTypeProcessed, ActionTypeCompleted),
+				UserIDs:     NoneOf("admin"),
+				EntityTypes: NoneOf(EntityType("refund")),
+			},
+		},
+		{
+			name:  "timestamps",
+			query: `timestamp >= 2024-01-01 AND timestamp < 2024-03-01T12:30:00Z`,
+			want:  AuditFilter{FromDate: &date, ToDate: &[]time.Time{instant.Add(-time.Nanosecond)}[0]},
+		},
+		{"date equals the whole day", `timestamp = 2024-01-01`, AuditFilter{FromDate: &date, ToDate: &endOfDay}},
+		{"date after the whole day", `timestamp > 2024-01-01`, AuditFilter{FromDate: at(date.AddDate(0, 0, 1))}},
+		{"date from the start of the day", `timestamp >= 2024-01-01`, AuditFilter{FromDate: &date}},
+		{"date until the end of the day", `timestamp <= 2024-01-01`, AuditFilter{ToDate: &endOfDay}},
+		{"date before the day", `timestamp < 2024-01-01`, AuditFilter{ToDate: at(date.Add(-time.Nanosecond))}},
+		{"instant equals itself", `timestamp = 2024-03-01T12:30:00Z`, AuditFilter{FromDate: &instant, ToDate: &instant}},
+		{
+			name:  "predicates",
+			query: `metadata.source = api AND newData.amount > 1000 AND newData.reason ~ "gift card" AND not exists oldData.status`,
+			want: AuditFilter{Predicates: []Predicate{
+				{Path: "metadata.source", Op: OpEqual, Value: "api"},
+				{Path: "newData.amount", Op: OpGreater, Value: "1000"},
+				{Path: "newData.reason", Op: OpContains, Value: "gift card"},
+				{Path: "oldData.status", Op: OpNotExists},
+			}},
+		},
+		{
+			name:  "quoted values",
+			query: `entity_id = "pay 1" AND newData.refunds[*].reason != "said \"no\""`,
+			want: AuditFilter{
+				EntityIDs:  AnyOf("pay 1"),
+				Predicates: []Predicate{{Path: "newData.refunds[*].reason", Op: OpNotEqual, Value: `said "no"`}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"missing operator", "action processed"},
+		{"missing value", "action ="},
+		{"missing AND", "action = processed user_id = admin"},
+		{"unknown field", "status = pending"},
+		{"repeated set", "action = processed AND action = completed"},
+		{"ordering a set", "action > processed"},
+		{"unclosed list", "action IN (processed, completed"},
+		{"empty list", "action IN ()"},
+		{"IN on a path", "newData.status IN (a, b)"},
+		{"bad time", "timestamp >= yesterday"},
+		{"contains on a timestamp", "timestamp ~ 2024-01-01"},
+		{"repeated bound", "timestamp >= 2024-01-01 AND timestamp > 2024-02-01"},
+		{"NOT without EXISTS", "NOT action = processed"},
+		{"unterminated string", `entity_id = "pay`},
+		{"stray symbol", "action = processed !"},
+		{"trailing AND", "action = processed AND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseQuery(tt.query); !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("expected ErrInvalidQuery, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// EntitySource reads stored entities of one type as the fields their audit
+// entries record, so a Reconciler can check them against the log without
+// knowing their model.
+type EntitySource interface {
+	// Fields returns the fields of the stored entity, or nil if it is not
+	// stored.
+	Fields(ctx context.Context, entityID string) (map[string]interface{}, error)
+	// AllFields returns the fields of every stored entity by ID.
+	AllFields(ctx context.Context) (map[string]map[string]interface{}, error)
+}
+
+// Reconciler checks entities rebuilt from the audit log against the ones an
+// EntitySource holds. Only fields the source reads are compared.
+type Reconciler struct {
+	replayer   *Replayer
+	entityType EntityType
+	source     EntitySource
+	redactor   *Redactor
+}
+
+type ReconcilerOption func(*Reconciler)
+
+// WithReconcileRedactor compares stored entities as redactor would have
+// recorded them, so redacted fields do not show up as inconsistencies. It
+// should be the redactor the audit service records with.
+func WithReconcileRedactor(redactor *Redactor) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.redactor = redactor
+	}
+}
+
+func NewReconciler(audits Repository, entityType EntityType, source EntitySource, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		replayer:   NewReplayer(audits),
+		entityType: entityType,
+		source:     source,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Reconcile reports where the entity's audit history contradicts itself or
+// the stored entity. No inconsistencies means the two agree.
+func (r *Reconciler) Reconcile(ctx context.Context, entityID string) ([]Inconsistency, error) {
+	state, err := r.replayer.StateAt(ctx, r.entityType, entityID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := r.source.Fields(ctx, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.compare(entityID, state, stored), nil
+}
+
+// ReconcileAll reconciles every entity that is in the audit log, the source
+// or both.
+func (r *Reconciler) ReconcileAll(ctx context.Context) ([]Inconsistency, error) {
+	now := time.Now()
+	states, err := r.replayer.StatesAt(ctx, r.entityType, now)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := r.source.AllFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := make(map[string]*EntityState, len(states))
+	for _, state := range states {
+		replayed[state.EntityID] = state
+	}
+
+	ids := slices.Collect(maps.Keys(replayed))
+	for id := range stored {
+		if _, ok := replayed[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	var inconsistencies []Inconsistency
+	for _, id := range ids {
+		inconsistencies = append(inconsistencies, r.compare(id, replayed[id], stored[id])...)
+	}
+
+	return inconsistencies, nil
+}
+
+func (r *Reconciler) compare(entityID string, state *EntityState, stored map[string]interface{}) []Inconsistency {
+	missing := func(format string) []Inconsistency {
+		return []Inconsistency{{EntityType: r.entityType, EntityID: entityID, Reason: fmt.Sprintf(format, r.entityType)}}
+	}
+
+	switch {
+	case state == nil && stored == nil:
+		return nil
+	case state == nil:
+		return missing("stored %s has no audit history")
+	case state.Deleted && stored != nil:
+		return append(state.Issues, missing("%s is deleted in the audit log but still stored")...)
+	case state.Deleted:
+		return state.Issues
+	case stored == nil:
+		return append(state.Issues, missing("%s in the audit log is not stored")...)
+	}
+
+	inconsistencies := state.Issues
+	for _, field := range slices.Sorted(maps.Keys(state.Data)) {
+		actual, ok := stored[field]
+		if !ok {
+			continue
+		}
+		if r.redactor != nil {
+			if actual, ok = r.redactor.RedactField(r.entityType, field, actual); !ok {
+				continue
+			}
+		}
+		if !sameValue(state.Data[field], actual) {
+			inconsistencies = append(inconsistencies, Inconsistency{
+				EntityType: r.entityType,
+				EntityID:   entityID,
+				Field:      field,
+				Expected:   state.Data[field],
+				Actual:     actual,
+				Reason:     fmt.Sprintf("stored %s differs from audit log", r.entityType),
+			})
+		}
+	}
+
+	return inconsistencies
+}
@@ -0,0 +1,408 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type RedactionAction string
+
+const (
+	// RedactDrop removes the field. Slice elements become null so later
+	// indexes keep their meaning.
+	RedactDrop RedactionAction = "drop"
+	// RedactMask replaces every character but the last Keep with '*'.
+	RedactMask RedactionAction = "mask"
+	// RedactHash replaces the value with a salted HMAC-SHA256, so equal values
+	// can still be correlated without being readable.
+	RedactHash RedactionAction = "hash"
+	// RedactTruncate keeps the first Keep characters of a string.
+	RedactTruncate RedactionAction = "truncate"
+)
+
+const hashPrefix = "hmac-sha256:"
+
+// RedactionRule redacts the value at Path, written as in Change.Path. A "*"
+// segment matches any key and "[*]" any slice index, so "refunds[*].reason"
+// covers the reason of every refund. Keys containing '.' or '[' cannot be
+// addressed.
+type RedactionRule struct {
+	Path   string
+	Action RedactionAction
+	Keep   int
+}
+
+// Detector finds sensitive values by their shape wherever they appear in
+// string fields, including inside free text, and redacts each match with
+// spaces and dashes removed. Valid, if set, filters out matches that only look
+// right, such as digit runs that fail the Luhn check.
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Valid   func(match string) bool
+	Action  RedactionAction
+	Keep    int
+}
+
+// RedactionPolicy says how to redact the data of one entity type. Rules take
+// precedence; detectors scan the string values that no rule covers.
+type RedactionPolicy struct {
+	Rules     []RedactionRule
+	Detectors []Detector
+}
+
+// PANDetector masks payment card numbers, leaving the last four digits.
+func PANDetector() Detector {
+	return Detector{
+		Name:    "pan",
+		Pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Valid:   luhn,
+		Action:  RedactMask,
+		Keep:    4,
+	}
+}
+
+// EmailDetector hashes email addresses.
+func EmailDetector() Detector {
+	return Detector{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		Action:  RedactHash,
+	}
+}
+
+// IBANDetector masks international bank account numbers, leaving the last
+// four characters.
+func IBANDetector() Detector {
+	return Detector{
+		Name:    "iban",
+		Pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		Valid:   ibanChecksum,
+		Action:  RedactMask,
+		Keep:    4,
+	}
+}
+
+// DefaultDetectors returns the PAN, IBAN and email detectors.
+func DefaultDetectors() []Detector {
+	return []Detector{PANDetector(), IBANDetector(), EmailDetector()}
+}
+
+// Redactor applies redaction policies to audit entries before they are
+// stored. Entity types without a policy of their own get the default one.
+type Redactor struct {
+	salt          []byte
+	policies      map[EntityType]compiledPolicy
+	defaultPolicy compiledPolicy
+}
+
+type RedactorOption func(*redactorConfig)
+
+type redactorConfig struct {
+	policies      map[EntityType]RedactionPolicy
+	defaultPolicy RedactionPolicy
+}
+
+func WithPolicy(entityType EntityType, policy RedactionPolicy) RedactorOption {
+	return func(c *redactorConfig) {
+		c.policies[entityType] = policy
+	}
+}
+
+func WithDefaultPolicy(policy RedactionPolicy) RedactorOption {
+	return func(c *redactorConfig) {
+		c.defaultPolicy = policy
+	}
+}
+
+// NewRedactor checks the policies' rules and returns a Redactor hashing with
+// salt. Rules or detectors that hash need a non-empty salt.
+func NewRedactor(salt []byte, opts ...RedactorOption) (*Redactor, error) {
+	config := redactorConfig{policies: make(map[EntityType]RedactionPolicy)}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	r := &Redactor{
+		salt:     slices.Clone(salt),
+		policies: make(map[EntityType]compiledPolicy, len(config.policies)),
+	}
+
+	var err error
+	if r.defaultPolicy, err = r.compile(config.defaultPolicy); err != nil {
+		return nil, err
+	}
+	for entityType, policy := range config.policies {
+		if r.policies[entityType], err = r.compile(policy); err != nil {
+			return nil, fmt.Errorf("%s policy: %w", entityType, err)
+		}
+	}
+
+	return r, nil
+}
+
+// Redact rewrites the entry's OldData, NewData and Metadata under its entity
+// type's policy. Metadata keys are matched by the rules for top-level data
+// keys. It must run before the entry is signed or chained, since both cover
+// the data.
+func (r *Redactor) Redact(entry *AuditEntry) error {
+	if entry.sequence != 0 || len(entry.signature) > 0 {
+		return fmt.Errorf("audit entry %s is already sealed", entry.id)
+	}
+
+	policy := r.policy(entry.entityType)
+	entry.oldData = r.redactMap(policy, nil, entry.oldData)
+	entry.newData = r.redactMap(policy, nil, entry.newData)
+	entry.changes = Diff(entry.oldData, entry.newData)
+
+	metadata := make(map[string]string, len(entry.metadata))
+	for key, value := range entry.metadata {
+		if value, keep := r.redactValue(policy, []string{key}, value); keep {
+			metadata[key] = stringify(value)
+		}
+	}
+	entry.metadata = metadata
+	return nil
+}
+
+// RedactData returns data as Redact would record it for an entry of
+// entityType, for copies of an entity's data kept outside the audit log.
+func (r *Redactor) RedactData(entityType EntityType, data map[string]interface{}) map[string]interface{} {
+	return r.redactMap(r.policy(entityType), nil, data)
+}
+
+// RedactField returns value as Redact would record it under the top-level
+// key field of an entry for entityType, and false if it would be dropped.
+func (r *Redactor) RedactField(entityType EntityType, field string, value interface{}) (interface{}, bool) {
+	return r.redactValue(r.policy(entityType), []string{field}, value)
+}
+
+func (r *Redactor) policy(entityType EntityType) compiledPolicy {
+	if policy, ok := r.policies[entityType]; ok {
+		return policy
+	}
+	return r.defaultPolicy
+}
+
+type compiledRule struct {
+	RedactionRule
+	segments []string
+}
+
+type compiledPolicy struct {
+	rules     []compiledRule
+	detectors []Detector
+}
+
+func (r *Redactor) compile(policy RedactionPolicy) (compiledPolicy, error) {
+	compiled := compiledPolicy{detectors: slices.Clone(policy.Detectors)}
+	for _, rule := range policy.Rules {
+		if err := r.checkAction(rule.Action, rule.Keep); err != nil {
+			return compiledPolicy{}, fmt.Errorf("%w: %s: %w", ErrInvalidRedactionRule, rule.Path, err)
+		}
+		segments, err := parsePath(rule.Path)
+		if err != nil {
+			return compiledPolicy{}, fmt.Errorf("%w: %w", ErrInvalidRedactionRule, err)
+		}
+		compiled.rules = append(compiled.rules, compiledRule{RedactionRule: rule, segments: segments})
+	}
+	for _, detector := range policy.Detectors {
+		if detector.Pattern == nil {
+			return compiledPolicy{}, fmt.Errorf("%w: detector %q has no pattern", ErrInvalidRedactionRule, detector.Name)
+		}
+		if err := r.checkAction(detector.Action, detector.Keep); err != nil {
+			return compiledPolicy{}, fmt.Errorf("%w: detector %q: %w", ErrInvalidRedactionRule, detector.Name, err)
+		}
+	}
+	return compiled, nil
+}
+
+func (r *Redactor) checkAction(action RedactionAction, keep int) error {
+	switch action {
+	case RedactDrop, RedactMask, RedactTruncate:
+	case RedactHash:
+		if len(r.salt) == 0 {
+			return fmt.Errorf("hashing needs a salt")
+		}
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+	if keep < 0 {
+		return fmt.Errorf("keep cannot be negative")
+	}
+	return nil
+}
+
+// parsePath splits "refunds[*].reason" into "refunds", "[*]" and "reason".
+func parsePath(path string) ([]string, error) {
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		key, indexes, _ := strings.Cut(part, "[")
+		if key == "" && (len(segments) == 0 || indexes == "") {
+			return nil, fmt.Errorf("path %q has an empty key", path)
+		}
+		if key != "" {
+			segments = append(segments, key)
+		}
+		if indexes == "" {
+			continue
+		}
+		for _, index := range strings.Split(strings.TrimSuffix(indexes, "]"), "][") {
+			if _, err := strconv.Atoi(index); err != nil && index != "*" {
+				return nil, fmt.Errorf("path %q has an invalid index %q", path, index)
+			}
+			segments = append(segments, "["+index+"]")
+		}
+	}
+	return segments, nil
+}
+
+func (p compiledPolicy) match(path []string) *compiledRule {
+	for i, rule := range p.rules {
+		if len(rule.segments) != len(path) {
+			continue
+		}
+		matched := true
+		for j, segment := range rule.segments {
+			isIndex := strings.HasPrefix(path[j], "[")
+			if segment != path[j] && !(segment == "*" && !isIndex) && !(segment == "[*]" && isIndex) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &p.rules[i]
+		}
+	}
+	return nil
+}
+
+func (r *Redactor) redactMap(policy compiledPolicy, path []string, data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+
+	redacted := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if value, keep := r.redactValue(policy, append(slices.Clip(path), key), value); keep {
+			redacted[key] = value
+		}
+	}
+	return redacted
+}
+
+// redactValue returns the value to store at path and whether to store it.
+func (r *Redactor) redactValue(policy compiledPolicy, path []string, value interface{}) (interface{}, bool) {
+	if rule := policy.match(path); rule != nil {
+		return r.apply(rule.Action, rule.Keep, value)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return r.redactMap(policy, path, v), true
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i], _ = r.redactValue(policy, append(slices.Clip(path), "["+strconv.Itoa(i)+"]"), item)
+		}
+		return redacted, true
+	case string:
+		return r.detect(policy, v), true
+	default:
+		return value, true
+	}
+}
+
+func (r *Redactor) detect(policy compiledPolicy, s string) string {
+	for _, detector := range policy.detectors {
+		s = detector.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if detector.Valid != nil && !detector.Valid(match) {
+				return match
+			}
+			normalized := strings.NewReplacer(" ", "", "-", "").Replace(match)
+			redacted, keep := r.apply(detector.Action, detector.Keep, normalized)
+			if !keep {
+				return "[redacted]"
+			}
+			return redacted.(string)
+		})
+	}
+	return s
+}
+
+func (r *Redactor) apply(action RedactionAction, keep int, value interface{}) (interface{}, bool) {
+	switch action {
+	case RedactDrop:
+		return nil, false
+	case RedactMask:
+		runes := []rune(stringify(value))
+		visible := min(keep, len(runes))
+		return strings.Repeat("*", len(runes)-visible) + string(runes[len(runes)-visible:]), true
+	case RedactHash:
+		data, _ := json.Marshal(value)
+		mac := hmac.New(sha256.New, r.salt)
+		mac.Write(data)
+		return hashPrefix + hex.EncodeToString(mac.Sum(nil)), true
+	case RedactTruncate:
+		s, ok := value.(string)
+		if !ok || len([]rune(s)) <= keep {
+			return value, true
+		}
+		return string([]rune(s)[:keep]), true
+	}
+	return value, true
+}
+
+func stringify(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+func luhn(match string) bool {
+	sum, double := 0, false
+	for i := len(match) - 1; i >= 0; i-- {
+		c := match[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ibanChecksum applies the ISO 13616 mod-97 check.
+func ibanChecksum(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	rearranged := iban[4:] + iban[:4]
+
+	var digits strings.Builder
+	for _, c := range rearranged {
+		if unicode.IsLetter(c) {
+			digits.WriteString(strconv.Itoa(int(c-'A') + 10))
+		} else {
+			digits.WriteRune(c)
+		}
+	}
+
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
@@ -0,0 +1,231 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	salt := []byte("test-salt")
+	hashed := func(value interface{}) interface{} {
+		r, _ := NewRedactor(salt)
+		v, _ := r.apply(RedactHash, 0, value)
+		return v
+	}
+
+	tests := []struct {
+		name   string
+		policy RedactionPolicy
+		data   map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "drop",
+			policy: RedactionPolicy{Rules: []RedactionRule{{Path: "card.cvv", Action: RedactDrop}}},
+			data:   map[string]interface{}{"card": map[string]interface{}{"cvv": "123", "brand": "visa"}},
+			want:   map[string]interface{}{"card": map[string]interface{}{"brand": "visa"}},
+		},
+		{
+			name:   "mask keeps the tail",
+			policy: RedactionPolicy{Rules: []RedactionRule{{Path: "account", Action: RedactMask, Keep: 3}}},
+			data:   map[string]interface{}{"account": "12345678"},
+			want:   map[string]interface{}{"account": "*****678"},
+		},
+		{
+			name:   "mask shorter than keep",
+			policy: RedactionPolicy{Rules: []RedactionRule{{Path: "pin", Action: RedactMask, Keep: 8}}},
+			data:   map[string]interface{}{"pin": "12"},
+			want:   map[string]interface{}{"pin": "12"},
+		},
+		{
+			name:   "hash non-string values",
+			policy: RedactionPolicy{Rules: []RedactionRule{{Path: "customer", Action: RedactHash}}},
+			data:   map[string]interface{}{"customer": map[string]interface{}{"name": "Jane"}},
+			want:   map[string]interface{}{"customer": hashed(map[string]interface{}{"name": "Jane"})},
+		},
+		{
+			name:   "truncate",
+			policy: RedactionPolicy{Rules: []RedactionRule{{Path: "description", Action: RedactTruncate, Keep: 4}}},
+			data:   map[string]interface{}{"description": "Überweisung"},
+			want:   map[string]interface{}{"description": "Über"},
+		},
+		{
+			name:   "wildcards",
+			policy: RedactionPolicy{Rules: []RedactionRule{{Path: "refunds[*].reason", Action: RedactDrop}, {Path: "card.*", Action: RedactMask}}},
+			data: map[string]interface{}{
+				"refunds": []interface{}{map[string]interface{}{"reason": "a", "amount": "1.00"}, map[string]interface{}{"reason": "b"}},
+				"card":    map[string]interface{}{"brand": "visa"},
+			},
+			want: map[string]interface{}{
+				"refunds": []interface{}{map[string]interface{}{"amount": "1.00"}, map[string]interface{}{}},
+				"card":    map[string]interface{}{"brand": "****"},
+			},
+		},
+		{
+			name:   "dropped slice elements become null",
+			policy: RedactionPolicy{Rules: []RedactionRule{{Path: "notes[0]", Action: RedactDrop}}},
+			data:   map[string]interface{}{"notes": []interface{}{"secret", "public"}},
+			want:   map[string]interface{}{"notes": []interface{}{nil, "public"}},
+		},
+		{
+			name:   "detectors inside free text",
+			policy: RedactionPolicy{Detectors: DefaultDetectors()},
+			data: map[string]interface{}{
+				"description": "card 4242 4242 4242 4242, iban GB82 WEST 1234 5698 7654 32",
+				"note":        "contact jane@example.com",
+			},
+			want: map[string]interface{}{
+				"description": "card ************4242, iban ******************5432",
+				"note":        "contact " + hashed("jane@example.com").(string),
+			},
+		},
+		{
+			name:   "rules take precedence over detectors",
+			policy: RedactionPolicy{Rules: []RedactionRule{{Path: "email", Action: RedactDrop}}, Detectors: DefaultDetectors()},
+			data:   map[string]interface{}{"email": "jane@example.com", "status": "pending"},
+			want:   map[string]interface{}{"status": "pending"},
+		},
+		{
+			name:   "look-alikes are kept",
+			policy: RedactionPolicy{Detectors: DefaultDetectors()},
+			data:   map[string]interface{}{"reference": "4242 4242 4242 4241", "iban": "GB00 WEST 1234 5698 7654 32", "amount_minor": 4242424242424242},
+			want:   map[string]interface{}{"reference": "4242 4242 4242 4241", "iban": "GB00 WEST 1234 5698 7654 32", "amount_minor": 4242424242424242},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor, err := NewRedactor(salt, WithPolicy(EntityTypePayment, tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			entry := NewAuditEntry(EntityTypePayment, "pay-1", ActionTypeCreated, "user-123")
+			entry.newData = tt.data
+			if err := redactor.Redact(entry); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(entry.NewData(), tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, entry.NewData())
+			}
+		})
+	}
+}
+
+func TestRedactor_RedactsMetadata(t *testing.T) {
+	redactor, _ := NewRedactor([]byte("salt"), WithPolicy(EntityTypePayment, RedactionPolicy{
+		Rules:     []RedactionRule{{Path: "ip", Action: RedactDrop}},
+		Detectors: DefaultDetectors(),
+	}))
+
+	entry := NewAuditEntry(EntityTypePayment, "pay-1", ActionTypeCreated, "user-123")
+	entry.AddMetadata("ip", "203.0.113.7")
+	entry.AddMetadata("requested_by", "jane@example.com")
+	entry.AddMetadata("channel", "web")
+	if err := redactor.Redact(entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	metadata := entry.Metadata()
+	if _, ok := metadata["ip"]; ok {
+		t.Errorf("expected ip dropped, got %v", metadata)
+	}
+	if !strings.HasPrefix(metadata["requested_by"], hashPrefix) || metadata["channel"] != "web" {
+		t.Errorf("expected email hashed and channel kept, got %v", metadata)
+	}
+}
+
+func TestRedactor_PerEntityType(t *testing.T) {
+	redactor, err := NewRedactor(nil,
+		WithPolicy(EntityType("customer"), RedactionPolicy{Rules: []RedactionRule{{Path: "name", Action: RedactDrop}}}),
+		WithDefaultPolicy(RedactionPolicy{Rules: []RedactionRule{{Path: "name", Action: RedactTruncate, Keep: 1}}}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	user := NewAuditEntry(EntityType("customer"), "user-1", ActionTypeCreated, "admin")
+	user.newData = map[string]interface{}{"name": "Jane"}
+	redactor.Redact(user)
+	other := NewAuditEntry(EntityTypePayment, "pay-1", ActionTypeCreated, "admin")
+	other.newData = map[string]interface{}{"name": "Jane"}
+	redactor.Redact(other)
+
+	if _, ok := user.NewData()["name"]; ok {
+		t.Errorf("expected user name dropped, got %v", user.NewData())
+	}
+	if other.NewData()["name"] != "J" {
+		t.Errorf("expected default policy to truncate, got %v", other.NewData())
+	}
+}
+
+func TestNewRedactor_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		salt   []byte
+		policy RedactionPolicy
+	}{
+		{"unknown action", []byte("salt"), RedactionPolicy{Rules: []RedactionRule{{Path: "a", Action: "scramble"}}}},
+		{"hash without salt", nil, RedactionPolicy{Rules: []RedactionRule{{Path: "a", Action: RedactHash}}}},
+		{"detector hash without salt", nil, RedactionPolicy{Detectors: []Detector{EmailDetector()}}},
+		{"empty key", []byte("salt"), RedactionPolicy{Rules: []RedactionRule{{Path: "a..b", Action: RedactDrop}}}},
+		{"bad index", []byte("salt"), RedactionPolicy{Rules: []RedactionRule{{Path: "a[x]", Action: RedactDrop}}}},
+		{"negative keep", []byte("salt"), RedactionPolicy{Rules: []RedactionRule{{Path: "a", Action: RedactMask, Keep: -1}}}},
+		{"detector without pattern", []byte("salt"), RedactionPolicy{Detectors: []Detector{{Name: "none", Action: RedactMask}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRedactor(tt.salt, WithPolicy(EntityTypePayment, tt.policy))
+			if !errors.Is(err, ErrInvalidRedactionRule) {
+				t.Errorf("expected ErrInvalidRedactionRule, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_RedactsBeforeSave(t *testing.T) {
+	ctx := context.Background()
+	redactor, _ := NewRedactor([]byte("salt"), WithPolicy(EntityTypePayment, RedactionPolicy{
+		Rules:     []RedactionRule{{Path: "description", Action: RedactTruncate, Keep: 6}},
+		Detectors: DefaultDetectors(),
+	}))
+	log := &entryLog{}
+	service := NewService(log, WithKeyProvider(newTestKeys(t)), WithRedactor(redactor))
+
+	description := "Refund to jane@example.com"
+	err := service.RecordPaymentCreated(ctx, "pay-1", "user-123", map[string]interface{}{
+		"status": "pending", "description": description, "card": "4242424242424242",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saved := log.entries[0].NewData()
+	if saved["description"] != "Refund" || saved["card"] != "************4242" {
+		t.Errorf("expected data redacted before save, got %v", saved)
+	}
+	for _, change := range log.entries[0].Changes() {
+		if strings.Contains(change.String(), "4242424242424242") {
+			t.Errorf("expected changes derived from redacted data, got %s", change)
+		}
+	}
+	if err := service.Verify(ctx); err != nil {
+		t.Errorf("expected redacted entries to verify, got %v", err)
+	}
+	if err := redactor.Redact(log.entries[0]); err == nil {
+		t.Error("expected sealed entry to be rejected")
+	}
+
+	payments := fieldStore{"pay-1": {"status": "pending", "description": description}}
+	if got, _ := NewReconciler(log, EntityTypePayment, payments).Reconcile(ctx, "pay-1"); len(got) != 1 || got[0].Field != "description" {
+		t.Errorf("expected unredacted comparison to differ on description, got %v", got)
+	}
+	if got, _ := NewReconciler(log, EntityTypePayment, payments, WithReconcileRedactor(redactor)).Reconcile(ctx, "pay-1"); len(got) != 0 {
+		t.Errorf("expected no inconsistencies, got %v", got)
+	}
+}
@@ -0,0 +1,217 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+)
+
+// EntityState is an entity as the audit log describes it at a point in time:
+// the NewData of every entry up to then, later fields overriding earlier
+// ones.
+type EntityState struct {
+	EntityType EntityType
+	EntityID   string
+	Data       map[string]interface{}
+	LastAction ActionType
+	UpdatedAt  time.Time
+	Entries    int
+	Deleted    bool
+
+	// Issues are the places where the log contradicts itself, such as an
+	// entry whose OldData does not match the state replayed before it.
+	Issues []Inconsistency
+}
+
+// Inconsistency is a field whose value differs between two accounts of the
+// same entity. Expected is what the audit log says.
+type Inconsistency struct {
+	EntityType EntityType
+	EntityID   string
+	EntryID    AuditID
+	Field      string
+	Expected   interface{}
+	Actual     interface{}
+	Reason     string
+}
+
+func (i Inconsistency) String() string {
+	if i.Field == "" {
+		return fmt.Sprintf("%s %s: %s", i.EntityType, i.EntityID, i.Reason)
+	}
+	return fmt.Sprintf("%s %s: %s: %s (audit log %v, actual %v)", i.EntityType, i.EntityID, i.Field, i.Reason, i.Expected, i.Actual)
+}
+
+// Replayer rebuilds entity state from the audit log.
+type Replayer struct {
+	repository Repository
+}
+
+func NewReplayer(repository Repository) *Replayer {
+	return &Replayer{
+		repository: repository,
+	}
+}
+
+// StateAt replays the entity's history up to and including at. It returns
+// nil if the entity had no audit entries by then.
+func (r *Replayer) StateAt(ctx context.Context, entityType EntityType, entityID string, at time.Time) (*EntityState, error) {
+	archived, err := r.archived(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.stateAt(ctx, entityType, entityID, at, archived)
+}
+
+// StatesAt replays every entity of entityType that had audit entries by at,
+// ordered by entity ID.
+func (r *Replayer) StatesAt(ctx context.Context, entityType EntityType, at time.Time) ([]*EntityState, error) {
+	entries, err := r.repository.FindByFilter(ctx, AuditFilter{EntityType: &entityType, ToDate: &at})
+	if err != nil {
+		return nil, err
+	}
+	archived, err := r.archived(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{})
+	for _, entry := range entries {
+		ids[entry.EntityID()] = struct{}{}
+	}
+
+	states := make([]*EntityState, 0, len(ids))
+	for _, id := range slices.Sorted(maps.Keys(ids)) {
+		state, err := r.stateAt(ctx, entityType, id, at, archived)
+		if err != nil {
+			return nil, err
+		}
+		if state != nil {
+			states = append(states, state)
+		}
+	}
+
+	return states, nil
+}
+
+func (r *Replayer) stateAt(ctx context.Context, entityType EntityType, entityID string, at time.Time, archived archivedSequences) (*EntityState, error) {
+	entries, err := r.repository.FindByEntityID(ctx, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	return replay(entityType, entityID, entries, at, archived.of(entityType, entityID)), nil
+}
+
+// archived reads which entries have been archived from the archival records.
+func (r *Replayer) archived(ctx context.Context) (archivedSequences, error) {
+	entityType, action := EntityTypeAuditLog, ActionTypeArchived
+	records, err := r.repository.FindByFilter(ctx, AuditFilter{EntityType: &entityType, Action: &action})
+	if err != nil {
+		return nil, err
+	}
+	return archivedEntries(records), nil
+}
+
+// replay applies entries in time order up to at. archived holds, in
+// ascending order, the sequences of the entity's entries that were archived,
+// whose changes are no longer in the log to replay.
+func replay(entityType EntityType, entityID string, entries []*AuditEntry, at time.Time, archived []int64) *EntityState {
+	entries = slices.Clone(entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp().Before(entries[j].Timestamp())
+	})
+
+	state := &EntityState{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Data:       make(map[string]interface{}),
+	}
+	var previous int64
+	for _, entry := range entries {
+		if entry.Timestamp().After(at) {
+			break
+		}
+		state.apply(entry, archivedBetween(archived, previous, entry.sequence))
+		previous = entry.sequence
+	}
+
+	if state.Entries == 0 {
+		return nil
+	}
+	return state
+}
+
+// archivedBetween reports whether archived, in ascending order, holds a
+// sequence after previous and before next.
+func archivedBetween(archived []int64, previous, next int64) bool {
+	i, _ := slices.BinarySearch(archived, previous+1)
+	return i < len(archived) && archived[i] < next
+}
+
+// apply adds entry to the state. After a gap left by archived entries, the
+// entry's OldData is taken as the state before it rather than checked
+// against the state replayed so far.
+func (s *EntityState) apply(entry *AuditEntry, afterGap bool) {
+	if afterGap {
+		maps.Copy(s.Data, entry.OldData())
+	}
+
+	switch {
+	case s.Entries == 0 && entry.Action() != ActionTypeCreated && !afterGap:
+		s.issue(entry, "", nil, nil, fmt.Sprintf("history starts with %s instead of %s", entry.Action(), ActionTypeCreated))
+	case s.Entries > 0 && entry.Action() == ActionTypeCreated:
+		s.issue(entry, "", nil, nil, "created more than once")
+	case s.Deleted:
+		s.issue(entry, "", nil, nil, fmt.Sprintf("%s after deletion", entry.Action()))
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(entry.OldData())) {
+		current, known := s.Data[field]
+		if known && !sameValue(entry.OldData()[field], current) {
+			s.issue(entry, field, current, entry.OldData()[field], fmt.Sprintf("%s entry expected a different previous value", entry.Action()))
+		}
+	}
+
+	maps.Copy(s.Data, entry.NewData())
+	s.LastAction = entry.Action()
+	s.UpdatedAt = entry.Timestamp()
+	s.Entries++
+	if entry.Action() == ActionTypeDeleted {
+		s.Deleted = true
+	}
+}
+
+func (s *EntityState) issue(entry *AuditEntry, field string, expected, actual interface{}, reason string) {
+	s.Issues = append(s.Issues, Inconsistency{
+		EntityType: s.EntityType,
+		EntityID:   s.EntityID,
+		EntryID:    entry.ID(),
+		Field:      field,
+		Expected:   expected,
+		Actual:     actual,
+		Reason:     reason,
+	})
+}
+
+// sameValue compares an audit log value with any other value as they would
+// both be stored in an entry, so 100 and float64(100) are equal.
+func sameValue(logged, other interface{}) bool {
+	data, err := json.Marshal(other)
+	if err != nil {
+		return false
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(logged, normalized)
+}
@@ -0,0 +1,265 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestReplayer_StateAt(t *testing.T) {
+	audits := &entryLog{}
+	audits.add(t, "pay-1", ActionTypeCreated, base, nil, map[string]interface{}{"status": "pending", "amount": "100.00", "currency": "USD"})
+	audits.add(t, "pay-1", ActionTypeProcessed, base.Add(time.Minute), map[string]interface{}{"status": "pending"}, map[string]interface{}{"status": "processing"})
+	audits.add(t, "pay-1", ActionTypeCompleted, base.Add(2*time.Minute), map[string]interface{}{"status": "processing"}, map[string]interface{}{"status": "completed"})
+	replayer := NewReplayer(audits)
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		at         time.Time
+		wantNil    bool
+		wantStatus string
+		wantAction ActionType
+	}{
+		{name: "before creation", at: base.Add(-time.Second), wantNil: true},
+		{name: "at creation", at: base, wantStatus: "pending", wantAction: ActionTypeCreated},
+		{name: "between changes", at: base.Add(90 * time.Second), wantStatus: "processing", wantAction: ActionTypeProcessed},
+		{name: "now", at: time.Now(), wantStatus: "completed", wantAction: ActionTypeCompleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := replayer.StateAt(ctx, EntityTypePayment, "pay-1", tt.at)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNil {
+				if state != nil {
+					t.Errorf("expected no state, got %+v", state)
+				}
+				return
+			}
+			if state.Data["status"] != tt.wantStatus || state.LastAction != tt.wantAction {
+				t.Errorf("expected %s after %s, got %v after %s", tt.wantStatus, tt.wantAction, state.Data["status"], state.LastAction)
+			}
+			if state.Data["amount"] != "100.00" {
+				t.Errorf("expected fields from creation to persist, got %v", state.Data)
+			}
+			if len(state.Issues) != 0 {
+				t.Errorf("expected no issues, got %v", state.Issues)
+			}
+		})
+	}
+}
+
+func TestReplayer_ReportsContradictions(t *testing.T) {
+	audits := &entryLog{}
+	audits.add(t, "pay-1", ActionTypeProcessed, base, map[string]interface{}{"status": "pending"}, map[string]interface{}{"status": "processing"})
+	audits.add(t, "pay-1", ActionTypeCompleted, base.Add(time.Minute), map[string]interface{}{"status": "authorized"}, map[string]interface{}{"status": "completed"})
+	audits.add(t, "pay-2", ActionTypeCreated, base, nil, map[string]interface{}{"status": "pending"})
+
+	states, err := NewReplayer(audits).StatesAt(context.Background(), EntityTypePayment, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(states) != 2 || states[0].EntityID != "pay-1" || states[1].EntityID != "pay-2" {
+		t.Fatalf("expected states for pay-1 and pay-2, got %v", states)
+	}
+
+	issues := states[0].Issues
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %v", issues)
+	}
+	if !strings.Contains(issues[0].Reason, "starts with processed") {
+		t.Errorf("expected missing creation to be reported, got %q", issues[0].Reason)
+	}
+	if issues[1].Field != "status" || issues[1].Expected != "processing" || issues[1].Actual != "authorized" {
+		t.Errorf("expected status contradiction, got %+v", issues[1])
+	}
+	if len(states[1].Issues) != 0 {
+		t.Errorf("expected no issues for pay-2, got %v", states[1].Issues)
+	}
+}
+
+func TestReconciler(t *testing.T) {
+	ctx := context.Background()
+	created := func(description, status string) map[string]interface{} {
+		return map[string]interface{}{
+			"status": status, "amount": "100.00", "amount_minor": 10000, "currency": "USD", "description": description,
+		}
+	}
+	payments := fieldStore{
+		"pay-agrees":    created("agrees", "processing"),
+		"pay-diverged":  created("diverged", "failed"),
+		"pay-unaudited": created("unaudited", "pending"),
+	}
+
+	audits := &entryLog{}
+	for _, id := range []string{"pay-agrees", "pay-diverged"} {
+		audits.add(t, id, ActionTypeCreated, base, nil, created(strings.TrimPrefix(id, "pay-"), "pending"))
+		audits.add(t, id, ActionTypeProcessed, base.Add(time.Minute), map[string]interface{}{"status": "pending"}, map[string]interface{}{"status": "processing"})
+	}
+	audits.add(t, "lost", ActionTypeCreated, base, nil, map[string]interface{}{"status": "pending"})
+
+	reconciler := NewReconciler(audits, EntityTypePayment, payments)
+
+	got, err := reconciler.Reconcile(ctx, "pay-agrees")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no inconsistencies, got %v", got)
+	}
+
+	got, err = reconciler.Reconcile(ctx, "pay-diverged")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Field != "status" || got[0].Expected != "processing" || got[0].Actual != "failed" {
+		t.Errorf("expected status inconsistency, got %v", got)
+	}
+
+	all, err := reconciler.ReconcileAll(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reasons := make(map[string]string)
+	for _, inconsistency := range all {
+		reasons[inconsistency.EntityID] = inconsistency.Reason
+	}
+	if len(all) != 3 {
+		t.Errorf("expected 3 inconsistencies, got %v", all)
+	}
+	if reasons["lost"] != "payment in the audit log is not stored" {
+		t.Errorf("expected unstored payment to be reported, got %q", reasons["lost"])
+	}
+	if reasons["pay-unaudited"] != "stored payment has no audit history" {
+		t.Errorf("expected unaudited payment to be reported, got %q", reasons["pay-unaudited"])
+	}
+}
+
+func TestReconciler_AfterArchival(t *testing.T) {
+	ctx := context.Background()
+	id := "pay-archived"
+
+	audits := &entryLog{}
+	service := NewService(audits)
+	service.RecordAction(ctx, EntityTypePayment, id, ActionTypeCreated, "user-123", nil, map[string]interface{}{
+		"status": "pending", "amount": "100.00", "amount_minor": 10000, "currency": "USD", "description": "archived",
+	})
+	service.RecordAction(ctx, EntityTypePayment, id, ActionTypeProcessed, "user-123", map[string]interface{}{"status": "pending"}, map[string]interface{}{"status": "processing"})
+	service.RecordAction(ctx, EntityTypePayment, id, ActionTypeCompleted, "user-123", map[string]interface{}{"status": "processing"}, map[string]interface{}{"status": "completed"})
+	reconciler := NewReconciler(audits, EntityTypePayment, fieldStore{id: {
+		"status": "completed", "amount": "100.00", "amount_minor": 10000, "currency": "USD", "description": "archived",
+	}})
+
+	for _, archived := range []ActionType{ActionTypeProcessed, ActionTypeCreated} {
+		policy := RetentionPolicy{Rules: []RetentionRule{{EntityType: EntityTypePayment, Action: archived}}}
+		if result, err := NewArchiver(service, audits, &archiveStore{}, &legalHolds{}, policy).RunOnce(ctx); err != nil || result.Archived != 1 {
+			t.Fatalf("expected the %s entry archived, got %+v, %v", archived, result, err)
+		}
+
+		got, err := reconciler.ReconcileAll(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("expected no inconsistencies with the %s entry archived, got %v", archived, got)
+		}
+		state, _ := NewReplayer(audits).StateAt(ctx, EntityTypePayment, id, time.Now())
+		if state == nil || state.Data["status"] != "completed" {
+			t.Errorf("expected the replay to end completed, got %+v", state)
+		}
+	}
+
+	// Changes still contradict each other across entries that remain.
+	audits.add(t, id, ActionTypeRefunded, time.Now(), map[string]interface{}{"status": "pending"}, map[string]interface{}{"status": "refunded"})
+	state, _ := NewReplayer(audits).StateAt(ctx, EntityTypePayment, id, time.Now())
+	if state == nil || len(state.Issues) != 1 || state.Issues[0].Field != "status" {
+		t.Errorf("expected the unarchived contradiction to be reported, got %+v", state)
+	}
+}
+
+// entryLog is an audit Repository that keeps entries in insertion order.
+type entryLog struct {
+	entries []*AuditEntry
+	// holds, when set, makes Delete refuse entries of held entities.
+	holds *legalHolds
+}
+
+func (l *entryLog) add(t *testing.T, entityID string, action ActionType, at time.Time, oldData, newData map[string]interface{}) {
+	t.Helper()
+
+	entry := NewAuditEntry(EntityTypePayment, entityID, action, "user-123")
+	if oldData != nil {
+		entry.SetOldData(oldData)
+	}
+	entry.SetNewData(newData)
+
+	snapshot := entry.ToSnapshot()
+	snapshot.Timestamp = at
+	entry, err := FromSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.entries = append(l.entries, entry)
+}
+
+func (l *entryLog) Save(ctx context.Context, entry *AuditEntry) error {
+	var head *AuditEntry
+	if len(l.entries) > 0 {
+		head = l.entries[len(l.entries)-1]
+	}
+	if err := entry.Chain(head); err != nil {
+		return err
+	}
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *entryLog) FindByID(ctx context.Context, id AuditID) (*AuditEntry, error) {
+	for _, entry := range l.entries {
+		if entry.ID() == id {
+			return entry, nil
+		}
+	}
+	return nil, ErrAuditEntryNotFound
+}
+
+func (l *entryLog) FindByFilter(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	var result []*AuditEntry
+	for _, entry := range l.entries {
+		if filter.EntityType != nil && entry.EntityType() != *filter.EntityType {
+			continue
+		}
+		if filter.ToDate != nil && entry.Timestamp().After(*filter.ToDate) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return filter.Paginate(result)
+}
+
+func (l *entryLog) FindByEntityID(ctx context.Context, entityType EntityType, entityID string) ([]*AuditEntry, error) {
+	var result []*AuditEntry
+	for _, entry := range l.entries {
+		if entry.EntityType() == entityType && entry.EntityID() == entityID {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// fieldStore is an EntitySource holding each entity's fields by ID.
+type fieldStore map[string]map[string]interface{}
+
+func (s fieldStore) Fields(ctx context.Context, entityID string) (map[string]interface{}, error) {
+	return s[entityID], nil
+}
+
+func (s fieldStore) AllFields(ctx context.Context) (map[string]map[string]interface{}, error) {
+	return s, nil
+}
@@ -2,6 +2,10 @@ package audit
 
 import "context"
 
+// Repository stores the audit log. FindByFilter orders, resumes and limits
+// its results as the filter's Sort, Cursor and Limit say; AuditFilter.Paginate
+// does this for adapters that filter in memory. FindByEntityID returns the
+// entity's whole history in chronological order.
 type Repository interface {
 	Save(ctx context.Context, entry *AuditEntry) error
 	FindByID(ctx context.Context, id AuditID) (*AuditEntry, error)
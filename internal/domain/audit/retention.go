@@ -0,0 +1,335 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	defaultArchiveInterval = time.Hour
+	archiverUserID         = "system:retention"
+)
+
+// RetentionRule keeps entries of EntityType with Action for Retain after
+// they were recorded. An empty EntityType or Action matches any.
+type RetentionRule struct {
+	EntityType EntityType
+	Action     ActionType
+	Retain     time.Duration
+}
+
+// RetentionPolicy decides how long entries stay in the live log. The most
+// specific matching rule applies: one naming both entity type and action,
+// then entity type, then action, then a rule naming neither. Entries no rule
+// matches are kept forever.
+type RetentionPolicy struct {
+	Rules []RetentionRule
+}
+
+func (p RetentionPolicy) Retention(entityType EntityType, action ActionType) (time.Duration, bool) {
+	best, found := -1, false
+	var retain time.Duration
+	for _, rule := range p.Rules {
+		if (rule.EntityType != "" && rule.EntityType != entityType) || (rule.Action != "" && rule.Action != action) {
+			continue
+		}
+		specificity := 0
+		if rule.EntityType != "" {
+			specificity += 2
+		}
+		if rule.Action != "" {
+			specificity++
+		}
+		if specificity > best {
+			best, retain, found = specificity, rule.Retain, true
+		}
+	}
+	return retain, found
+}
+
+// LegalHold keeps every entry about an entity in the live log, whatever the
+// retention policy says, until it is released.
+type LegalHold struct {
+	EntityType EntityType
+	EntityID   string
+	Reason     string
+	PlacedBy   string
+	PlacedAt   time.Time
+}
+
+type LegalHoldRepository interface {
+	Place(ctx context.Context, hold LegalHold) error
+	// Release fails with ErrLegalHoldNotFound if the entity is not held.
+	Release(ctx context.Context, entityType EntityType, entityID string) error
+	FindAll(ctx context.Context) ([]LegalHold, error)
+}
+
+// ArchiveStore keeps expired entries once they leave the live log and
+// returns where it put them.
+type ArchiveStore interface {
+	Store(ctx context.Context, entries []*AuditEntry) (string, error)
+}
+
+// PrunableRepository is a Repository entries can be removed from. Delete
+// must refuse, with ErrUnderLegalHold, to remove entries about an entity
+// under a legal hold, checking the holds as it deletes, so that a hold
+// placed while an Archiver runs still protects the entity.
+type PrunableRepository interface {
+	Repository
+	Delete(ctx context.Context, ids ...AuditID) error
+}
+
+// ArchiveResult reports what one archival run did. Held counts expired
+// entries kept for a legal hold. Location is empty when nothing was archived.
+type ArchiveResult struct {
+	Location string
+	Archived int
+	Held     int
+}
+
+// Archiver moves entries past their retention from the live log to an
+// ArchiveStore. Each run that archives anything records an audit_log
+// "archived" entry listing the sequence and hash of every entry it moved,
+// which lets Service.Verify bridge the gaps they leave in the chain. Those
+// records are never archived themselves.
+type Archiver struct {
+	audits     *Service
+	repository PrunableRepository
+	store      ArchiveStore
+	holds      LegalHoldRepository
+	policy     RetentionPolicy
+	interval   time.Duration
+	now        func() time.Time
+}
+
+type ArchiverOption func(*Archiver)
+
+func WithArchiveInterval(interval time.Duration) ArchiverOption {
+	return func(a *Archiver) {
+		a.interval = interval
+	}
+}
+
+func withArchiveClock(now func() time.Time) ArchiverOption {
+	return func(a *Archiver) {
+		a.now = now
+	}
+}
+
+// NewArchiver archives from repository. Archivals are recorded in repository
+// too, signed and redacted as audits is configured to.
+func NewArchiver(audits *Service, repository PrunableRepository, store ArchiveStore, holds LegalHoldRepository, policy RetentionPolicy, opts ...ArchiverOption) *Archiver {
+	a := &Archiver{
+		audits:     audits.WithRepository(repository),
+		repository: repository,
+		store:      store,
+		holds:      holds,
+		policy:     policy,
+		interval:   defaultArchiveInterval,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Run archives expired entries every interval until ctx is cancelled.
+func (a *Archiver) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce archives every entry whose retention has passed and whose entity is
+// not under a legal hold. Entries are stored in the archive before the
+// archival is recorded, and recorded before they are deleted, so a failure
+// part way leaves them in the live log rather than losing them.
+func (a *Archiver) RunOnce(ctx context.Context) (ArchiveResult, error) {
+	held, err := a.held(ctx)
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+
+	entries, err := a.repository.FindByFilter(ctx, AuditFilter{})
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+
+	var result ArchiveResult
+	var expired []*AuditEntry
+	now := a.now()
+	for _, entry := range entries {
+		retain, ok := a.policy.Retention(entry.entityType, entry.action)
+		if !ok || entry.entityType == EntityTypeAuditLog || entry.timestamp.Add(retain).After(now) {
+			continue
+		}
+		if held[entityKey(entry.entityType, entry.entityID)] {
+			result.Held++
+			continue
+		}
+		expired = append(expired, entry)
+	}
+	if len(expired) == 0 {
+		return result, nil
+	}
+	sortBySequence(expired)
+
+	if result.Location, err = a.store.Store(ctx, expired); err != nil {
+		return ArchiveResult{}, fmt.Errorf("failed to archive audit entries: %w", err)
+	}
+
+	archived := make([]interface{}, 0, len(expired))
+	for _, entry := range expired {
+		archived = append(archived, map[string]interface{}{
+			"id":          entry.id.String(),
+			"sequence":    entry.sequence,
+			"hash":        entry.hash,
+			"entity_type": string(entry.entityType),
+			"entity_id":   entry.entityID,
+		})
+	}
+	record := map[string]interface{}{
+		"location": result.Location,
+		"count":    len(expired),
+		"entries":  archived,
+	}
+	if err := a.audits.RecordAction(ctx, EntityTypeAuditLog, result.Location, ActionTypeArchived, archiverUserID, nil, record); err != nil {
+		return ArchiveResult{}, fmt.Errorf("failed to record archival: %w", err)
+	}
+
+	removed, err := a.remove(ctx, expired)
+	if err != nil {
+		return ArchiveResult{}, fmt.Errorf("failed to remove archived entries: %w", err)
+	}
+
+	result.Archived = removed
+	result.Held += len(expired) - removed
+	return result, nil
+}
+
+// remove deletes entries from the live log and returns how many it deleted.
+// Entries whose entity was put under a legal hold since the run began are
+// refused by the repository; they are left in the live log, as well as in
+// the archive, as after a failure part way, and the rest are deleted.
+func (a *Archiver) remove(ctx context.Context, entries []*AuditEntry) (int, error) {
+	for {
+		ids := make([]AuditID, 0, len(entries))
+		for _, entry := range entries {
+			ids = append(ids, entry.id)
+		}
+		err := a.repository.Delete(ctx, ids...)
+		if !errors.Is(err, ErrUnderLegalHold) {
+			return len(entries), err
+		}
+
+		held, heldErr := a.held(ctx)
+		if heldErr != nil {
+			return 0, heldErr
+		}
+		unheld := make([]*AuditEntry, 0, len(entries))
+		for _, entry := range entries {
+			if !held[entityKey(entry.entityType, entry.entityID)] {
+				unheld = append(unheld, entry)
+			}
+		}
+		if len(unheld) == len(entries) {
+			return 0, err
+		}
+		if len(unheld) == 0 {
+			return 0, nil
+		}
+		entries = unheld
+	}
+}
+
+func (a *Archiver) held(ctx context.Context) (map[string]bool, error) {
+	holds, err := a.holds.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	held := make(map[string]bool, len(holds))
+	for _, hold := range holds {
+		held[entityKey(hold.EntityType, hold.EntityID)] = true
+	}
+	return held, nil
+}
+
+func entityKey(entityType EntityType, entityID string) string {
+	return string(entityType) + "/" + entityID
+}
+
+// archivedHashes collects, from the archival records among entries, the hash
+// of every archived entry by sequence.
+func archivedHashes(entries []*AuditEntry) map[int64]string {
+	archived := make(map[int64]string)
+	for _, entry := range entries {
+		if entry.entityType != EntityTypeAuditLog || entry.action != ActionTypeArchived {
+			continue
+		}
+		items, _ := entry.newData["entries"].([]interface{})
+		for _, item := range items {
+			fields, _ := item.(map[string]interface{})
+			sequence, _ := fields["sequence"].(float64)
+			hash, _ := fields["hash"].(string)
+			if sequence > 0 && hash != "" {
+				archived[int64(sequence)] = hash
+			}
+		}
+	}
+	return archived
+}
+
+// archivedSequences holds the sequences of archived entries by the entity
+// they were about. Entries of archival records that do not name the entity
+// are kept under the empty key, as possibly about any entity.
+type archivedSequences map[string][]int64
+
+// archivedEntries collects the archived sequences from the archival records
+// among entries.
+func archivedEntries(entries []*AuditEntry) archivedSequences {
+	archived := make(archivedSequences)
+	for _, entry := range entries {
+		if entry.entityType != EntityTypeAuditLog || entry.action != ActionTypeArchived {
+			continue
+		}
+		items, _ := entry.newData["entries"].([]interface{})
+		for _, item := range items {
+			fields, _ := item.(map[string]interface{})
+			sequence, _ := fields["sequence"].(float64)
+			if sequence <= 0 {
+				continue
+			}
+			key := ""
+			if entityType, _ := fields["entity_type"].(string); entityType != "" {
+				entityID, _ := fields["entity_id"].(string)
+				key = entityKey(EntityType(entityType), entityID)
+			}
+			archived[key] = append(archived[key], int64(sequence))
+		}
+	}
+	return archived
+}
+
+// of returns, in ascending order, the archived sequences that may have been
+// entries about the entity.
+func (a archivedSequences) of(entityType EntityType, entityID string) []int64 {
+	sequences := slices.Concat(a[entityKey(entityType, entityID)], a[""])
+	slices.Sort(sequences)
+	return sequences
+}
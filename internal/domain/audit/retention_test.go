@@ -0,0 +1,257 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicy_Retention(t *testing.T) {
+	year := 365 * 24 * time.Hour
+	policy := RetentionPolicy{Rules: []RetentionRule{
+		{Retain: 1 * year},
+		{Action: ActionTypeCreated, Retain: 2 * year},
+		{EntityType: EntityTypePayment, Retain: 7 * year},
+		{EntityType: EntityTypePayment, Action: ActionTypeRefunded, Retain: 10 * year},
+	}}
+
+	tests := []struct {
+		name       string
+		policy     RetentionPolicy
+		entityType EntityType
+		action     ActionType
+		want       time.Duration
+		wantFound  bool
+	}{
+		{"entity type and action", policy, EntityTypePayment, ActionTypeRefunded, 10 * year, true},
+		{"entity type", policy, EntityTypePayment, ActionTypeCreated, 7 * year, true},
+		{"action", policy, "customer", ActionTypeCreated, 2 * year, true},
+		{"catch-all", policy, "customer", ActionTypeUpdated, 1 * year, true},
+		{"no rule", RetentionPolicy{}, EntityTypePayment, ActionTypeCreated, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := tt.policy.Retention(tt.entityType, tt.action)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("expected %v, %v, got %v, %v", tt.want, tt.wantFound, got, found)
+			}
+		})
+	}
+}
+
+func TestArchiver_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	log := &entryLog{}
+	service := NewService(log, WithKeyProvider(newTestKeys(t)))
+	service.RecordAction(ctx, EntityTypePayment, "pay-1", ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+	service.RecordAction(ctx, EntityTypePayment, "pay-2", ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+	service.RecordAction(ctx, EntityTypePayment, "pay-1", ActionTypeProcessed, "user-123", nil, map[string]interface{}{"status": "processing"})
+	service.RecordAction(ctx, "customer", "cus-1", ActionTypeCreated, "user-123", nil, map[string]interface{}{"name": "Jane"})
+
+	store := &archiveStore{}
+	holds := &legalHolds{}
+	holds.Place(ctx, LegalHold{EntityType: EntityTypePayment, EntityID: "pay-2", Reason: "dispute"})
+	policy := RetentionPolicy{Rules: []RetentionRule{{EntityType: EntityTypePayment, Retain: 7 * 365 * 24 * time.Hour}}}
+
+	now := time.Now()
+	archiver := NewArchiver(service, log, store, holds, policy, withArchiveClock(func() time.Time { return now }))
+
+	result, err := archiver.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != (ArchiveResult{}) {
+		t.Errorf("expected nothing archived before retention ends, got %+v", result)
+	}
+
+	now = now.AddDate(8, 0, 0)
+	result, err = archiver.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Archived != 2 || result.Held != 1 || result.Location == "" {
+		t.Errorf("expected pay-1 archived and pay-2 held, got %+v", result)
+	}
+	if len(store.batches) != 1 || store.batches[0][0].EntityID() != "pay-1" || store.batches[0][1].EntityID() != "pay-1" {
+		t.Errorf("expected pay-1 entries in the archive, got %v", store.batches)
+	}
+	records, _ := service.GetAuditHistory(ctx, EntityTypeAuditLog, result.Location)
+	if len(records) != 1 || records[0].Action() != ActionTypeArchived || records[0].UserID() != archiverUserID {
+		t.Fatalf("expected the archival to be recorded, got %v", records)
+	}
+	if remaining, _ := service.GetAuditHistory(ctx, EntityTypePayment, "pay-1"); len(remaining) != 0 {
+		t.Errorf("expected pay-1 entries removed from the log, got %d", len(remaining))
+	}
+	if err := service.Verify(ctx); err != nil {
+		t.Errorf("expected the log to verify across the archived gap, got %v", err)
+	}
+
+	holds.Release(ctx, EntityTypePayment, "pay-2")
+	if result, _ = archiver.RunOnce(ctx); result.Archived != 1 {
+		t.Errorf("expected pay-2 archived once released, got %+v", result)
+	}
+	if result, _ = archiver.RunOnce(ctx); result.Archived != 0 {
+		t.Errorf("expected archival records to stay in the log, got %+v", result)
+	}
+	if err := service.Verify(ctx); err != nil {
+		t.Errorf("expected the log to verify across both gaps, got %v", err)
+	}
+
+	// Without the archival records the gaps look like tampering.
+	var unrecorded []*AuditEntry
+	for _, entry := range log.entries {
+		if entry.EntityType() != EntityTypeAuditLog {
+			unrecorded = append(unrecorded, entry)
+		}
+	}
+	log.entries = unrecorded
+	if err := service.Verify(ctx); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("expected ErrChainBroken, got %v", err)
+	}
+}
+
+func TestArchiver_ExportVerifiesAcrossGap(t *testing.T) {
+	ctx := context.Background()
+	keys := newTestKeys(t)
+	log := &entryLog{}
+	service := NewService(log, WithKeyProvider(keys))
+	service.RecordAction(ctx, EntityTypePayment, "pay-1", ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+	service.RecordAction(ctx, EntityTypePayment, "pay-1", ActionTypeProcessed, "user-123", nil, map[string]interface{}{"status": "processing"})
+
+	policy := RetentionPolicy{Rules: []RetentionRule{{Action: ActionTypeProcessed}}}
+	if _, err := NewArchiver(service, log, &archiveStore{}, &legalHolds{}, policy).RunOnce(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var exported bytes.Buffer
+	if err := service.Export(ctx, &exported); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkpoint, verified, err := VerifyExport(&exported, keys.public())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checkpoint.Sequence != 3 || verified != 2 {
+		t.Errorf("expected 2 entries verified up to sequence 3, got %d up to %d", verified, checkpoint.Sequence)
+	}
+}
+
+func TestArchiver_KeepsEntriesWhenArchiveFails(t *testing.T) {
+	ctx := context.Background()
+	log := &entryLog{}
+	service := NewService(log)
+	service.RecordAction(ctx, EntityTypePayment, "pay-1", ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+
+	failing := errors.New("disk full")
+	archiver := NewArchiver(service, log, &archiveStore{err: failing}, &legalHolds{}, RetentionPolicy{Rules: []RetentionRule{{}}})
+
+	if _, err := archiver.RunOnce(ctx); !errors.Is(err, failing) {
+		t.Errorf("expected archive error, got %v", err)
+	}
+	if len(log.entries) != 1 {
+		t.Errorf("expected the log untouched, got %d entries", len(log.entries))
+	}
+}
+
+func TestArchiver_HoldPlacedDuringRun(t *testing.T) {
+	ctx := context.Background()
+	holds := &legalHolds{}
+	log := &entryLog{holds: holds}
+	service := NewService(log)
+	service.RecordAction(ctx, EntityTypePayment, "pay-1", ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+	service.RecordAction(ctx, EntityTypePayment, "pay-2", ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+	service.RecordAction(ctx, EntityTypePayment, "pay-2", ActionTypeProcessed, "user-123", nil, map[string]interface{}{"status": "processing"})
+
+	// The hold lands after the entries were selected and archived.
+	store := &archiveStore{stored: func() {
+		holds.Place(ctx, LegalHold{EntityType: EntityTypePayment, EntityID: "pay-2", Reason: "dispute"})
+	}}
+	archiver := NewArchiver(service, log, store, holds, RetentionPolicy{Rules: []RetentionRule{{EntityType: EntityTypePayment}}})
+
+	result, err := archiver.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Archived != 1 || result.Held != 2 {
+		t.Errorf("expected pay-1 archived and pay-2 held, got %+v", result)
+	}
+	if remaining, _ := service.GetAuditHistory(ctx, EntityTypePayment, "pay-2"); len(remaining) != 2 {
+		t.Errorf("expected pay-2 entries kept in the log, got %d", len(remaining))
+	}
+	if remaining, _ := service.GetAuditHistory(ctx, EntityTypePayment, "pay-1"); len(remaining) != 0 {
+		t.Errorf("expected pay-1 entries removed from the log, got %d", len(remaining))
+	}
+	if err := service.Verify(ctx); err != nil {
+		t.Errorf("expected the log to verify, got %v", err)
+	}
+}
+
+func (l *entryLog) Delete(ctx context.Context, ids ...AuditID) error {
+	remove := make(map[AuditID]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	if l.holds != nil {
+		for _, entry := range l.entries {
+			for _, hold := range l.holds.holds {
+				if remove[entry.ID()] && hold.EntityType == entry.EntityType() && hold.EntityID == entry.EntityID() {
+					return fmt.Errorf("%w: %s", ErrUnderLegalHold, entry.ID())
+				}
+			}
+		}
+	}
+
+	var kept []*AuditEntry
+	for _, entry := range l.entries {
+		if !remove[entry.ID()] {
+			kept = append(kept, entry)
+		}
+	}
+	l.entries = kept
+	return nil
+}
+
+type archiveStore struct {
+	batches [][]*AuditEntry
+	err     error
+	// stored runs once the entries are in the archive.
+	stored func()
+}
+
+func (s *archiveStore) Store(ctx context.Context, entries []*AuditEntry) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	if s.stored != nil {
+		defer s.stored()
+	}
+	s.batches = append(s.batches, entries)
+	return fmt.Sprintf("batch-%d", len(s.batches)), nil
+}
+
+type legalHolds struct {
+	holds []LegalHold
+}
+
+func (h *legalHolds) Place(ctx context.Context, hold LegalHold) error {
+	h.holds = append(h.holds, hold)
+	return nil
+}
+
+func (h *legalHolds) Release(ctx context.Context, entityType EntityType, entityID string) error {
+	for i, hold := range h.holds {
+		if hold.EntityType == entityType && hold.EntityID == entityID {
+			h.holds = append(h.holds[:i], h.holds[i+1:]...)
+			return nil
+		}
+	}
+	return ErrLegalHoldNotFound
+}
+
+func (h *legalHolds) FindAll(ctx context.Context) ([]LegalHold, error) {
+	return h.holds, nil
+}
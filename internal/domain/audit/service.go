@@ -2,17 +2,53 @@ package audit
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
+	"io"
+	"iter"
 )
 
+const defaultPageSize = 100
+
 type Service struct {
 	repository Repository
+	keys       KeyProvider
+	redactor   *Redactor
+}
+
+type ServiceOption func(*Service)
+
+// WithKeyProvider signs every recorded entry with the provider's current key
+// and makes Verify check signatures as well as the hash chain.
+func WithKeyProvider(keys KeyProvider) ServiceOption {
+	return func(s *Service) {
+		s.keys = keys
+	}
 }
 
-func NewService(repository Repository) *Service {
-	return &Service{
+// WithRedactor redacts every recorded entry's data before it is signed and
+// saved.
+func WithRedactor(redactor *Redactor) ServiceOption {
+	return func(s *Service) {
+		s.redactor = redactor
+	}
+}
+
+func NewService(repository Repository, opts ...ServiceOption) *Service {
+	s := &Service{
 		repository: repository,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithRepository returns a service configured like s that records to and
+// reads from repository, such as one scoped to a transaction.
+func (s *Service) WithRepository(repository Repository) *Service {
+	bound := *s
+	bound.repository = repository
+	return &bound
 }
 
 func (s *Service) RecordAction(ctx context.Context, entityType EntityType, entityID string, action ActionType, userID string, oldData, newData interface{}) error {
@@ -30,6 +66,22 @@ func (s *Service) RecordAction(ctx context.Context, entityType EntityType, entit
 		}
 	}
 
+	if s.redactor != nil {
+		if err := s.redactor.Redact(entry); err != nil {
+			return err
+		}
+	}
+
+	if s.keys != nil {
+		keyID, key, err := s.keys.SigningKey()
+		if err != nil {
+			return err
+		}
+		if err := entry.Sign(keyID, key); err != nil {
+			return err
+		}
+	}
+
 	return s.repository.Save(ctx, entry)
 }
 
@@ -37,6 +89,7 @@ func (s *Service) GetAuditEntry(ctx context.Context, id AuditID) (*AuditEntry, e
 	return s.repository.FindByID(ctx, id)
 }
 
+// GetAuditHistory returns the entity's entries in chronological order.
 func (s *Service) GetAuditHistory(ctx context.Context, entityType EntityType, entityID string) ([]*AuditEntry, error) {
 	return s.repository.FindByEntityID(ctx, entityType, entityID)
 }
@@ -45,6 +98,134 @@ func (s *Service) GetAuditsByFilter(ctx context.Context, filter AuditFilter) ([]
 	return s.repository.FindByFilter(ctx, filter)
 }
 
+// GetAuditsByQuery returns the entries matching query; see ParseQuery.
+func (s *Service) GetAuditsByQuery(ctx context.Context, query string) ([]*AuditEntry, error) {
+	filter, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.repository.FindByFilter(ctx, filter)
+}
+
+// GetAuditPage returns up to filter.Limit entries, or defaultPageSize if it
+// is zero, and the cursor for the page after them.
+func (s *Service) GetAuditPage(ctx context.Context, filter AuditFilter) (AuditPage, error) {
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
+	}
+
+	// One entry more than asked for tells whether there is a next page.
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := s.repository.FindByFilter(ctx, filter)
+	if err != nil {
+		return AuditPage{}, err
+	}
+
+	if len(entries) <= limit {
+		return AuditPage{Entries: entries}, nil
+	}
+	entries = entries[:limit]
+	return AuditPage{Entries: entries, NextCursor: CursorAfter(entries[limit-1])}, nil
+}
+
+// Stream yields every entry matching filter, fetching them a page at a time,
+// until the entries run out, an error is yielded or the caller stops.
+func (s *Service) Stream(ctx context.Context, filter AuditFilter) iter.Seq2[*AuditEntry, error] {
+	return func(yield func(*AuditEntry, error) bool) {
+		for {
+			page, err := s.GetAuditPage(ctx, filter)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, entry := range page.Entries {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			filter.Cursor = page.NextCursor
+		}
+	}
+}
+
+// Verify walks the audit log's hash chain from the first entry and returns a
+// *BrokenLinkError for the first entry that was altered, removed, duplicated
+// or inserted out of order. With a key provider it also returns a
+// *SignatureError for the first entry whose signature does not verify. It
+// returns nil if the log is intact.
+func (s *Service) Verify(ctx context.Context) error {
+	entries, err := s.entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	var keys KeyResolver
+	if s.keys != nil {
+		keys = s.keys
+	}
+	return verifyLog(entries, keys)
+}
+
+// Checkpoint signs the current head of the log.
+func (s *Service) Checkpoint(ctx context.Context) (Checkpoint, error) {
+	entries, err := s.entries(ctx)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	return s.checkpoint(entries)
+}
+
+// Export writes the whole log, with a checkpoint of its head, as JSON that
+// VerifyExport can check without access to the service.
+func (s *Service) Export(ctx context.Context, w io.Writer) error {
+	entries, err := s.entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	checkpoint, err := s.checkpoint(entries)
+	if err != nil {
+		return err
+	}
+
+	exported := ExportedLog{Entries: make([]EntrySnapshot, 0, len(entries)), Checkpoint: checkpoint}
+	for _, entry := range entries {
+		exported.Entries = append(exported.Entries, entry.ToSnapshot())
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(exported)
+}
+
+func (s *Service) checkpoint(entries []*AuditEntry) (Checkpoint, error) {
+	if s.keys == nil {
+		return Checkpoint{}, ErrNoKeyProvider
+	}
+
+	var head *AuditEntry
+	if len(entries) > 0 {
+		head = entries[len(entries)-1]
+	}
+	return signCheckpoint(head, s.keys)
+}
+
+// entries returns the whole log ordered by sequence.
+func (s *Service) entries(ctx context.Context) ([]*AuditEntry, error) {
+	entries, err := s.repository.FindByFilter(ctx, AuditFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	sortBySequence(entries)
+	return entries, nil
+}
+
 func (s *Service) RecordPaymentCreated(ctx context.Context, paymentID string, userID string, paymentData interface{}) error {
 	return s.RecordAction(ctx, EntityTypePayment, paymentID, ActionTypeCreated, userID, nil, paymentData)
 }
@@ -61,8 +242,14 @@ func (s *Service) RecordPaymentStatusChange(ctx context.Context, paymentID strin
 		action = ActionTypeFailed
 	case "cancelled":
 		action = ActionTypeCancelled
+	case "partially_refunded", "refunded":
+		action = ActionTypeRefunded
+	case "authorized":
+		action = ActionTypeAuthorized
+	case "voided":
+		action = ActionTypeVoided
 	default:
-		return errors.New("unknown payment status")
+		return ErrUnknownPaymentStatus
 	}
 
 	oldData := map[string]interface{}{"status": oldStatus}
@@ -70,3 +257,15 @@ func (s *Service) RecordPaymentStatusChange(ctx context.Context, paymentID strin
 
 	return s.RecordAction(ctx, EntityTypePayment, paymentID, action, userID, oldData, newData)
 }
+
+func (s *Service) RecordPaymentAuthorized(ctx context.Context, paymentID string, userID string, oldData, newData interface{}) error {
+	return s.RecordAction(ctx, EntityTypePayment, paymentID, ActionTypeAuthorized, userID, oldData, newData)
+}
+
+func (s *Service) RecordPaymentCaptured(ctx context.Context, paymentID string, userID string, oldData, newData interface{}) error {
+	return s.RecordAction(ctx, EntityTypePayment, paymentID, ActionTypeCaptured, userID, oldData, newData)
+}
+
+func (s *Service) RecordPaymentRefunded(ctx context.Context, paymentID string, userID string, oldData, newData interface{}) error {
+	return s.RecordAction(ctx, EntityTypePayment, paymentID, ActionTypeRefunded, userID, oldData, newData)
+}
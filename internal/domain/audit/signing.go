@@ -0,0 +1,199 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// KeyResolver finds the public key that verifies signatures made under
+// keyID. It returns an error matching ErrUnknownKey if there is none.
+type KeyResolver interface {
+	PublicKey(keyID string) (ed25519.PublicKey, error)
+}
+
+// KeyProvider supplies the key new signatures are made with. After a
+// rotation it keeps resolving retired keys, so older entries still verify.
+type KeyProvider interface {
+	KeyResolver
+	SigningKey() (keyID string, key ed25519.PrivateKey, err error)
+}
+
+// PublicKeys is a KeyResolver over a fixed set of keys, such as those handed
+// to an auditor along with an exported log.
+type PublicKeys map[string]ed25519.PublicKey
+
+func (k PublicKeys) PublicKey(keyID string) (ed25519.PublicKey, error) {
+	key, ok := k[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	return key, nil
+}
+
+func (a *AuditEntry) KeyID() string {
+	return a.keyID
+}
+
+func (a *AuditEntry) Signature() []byte {
+	return slices.Clone(a.signature)
+}
+
+// Sign signs the entry under keyID. The signature covers everything but the
+// chain fields, which the repository fills in afterwards; the chain hash in
+// turn covers the signature. Entries already in a chain cannot be signed.
+func (a *AuditEntry) Sign(keyID string, key ed25519.PrivateKey) error {
+	if a.sequence != 0 {
+		return fmt.Errorf("audit entry %s is already chained", a.id)
+	}
+
+	a.keyID, a.signature = keyID, nil
+	message, err := a.signedContent()
+	if err != nil {
+		a.keyID = ""
+		return err
+	}
+	a.signature = ed25519.Sign(key, message)
+	return nil
+}
+
+// VerifySignature checks the entry's signature against the key it names.
+func (a *AuditEntry) VerifySignature(keys KeyResolver) error {
+	if len(a.signature) == 0 {
+		return fmt.Errorf("%w: entry %s is not signed", ErrInvalidSignature, a.id)
+	}
+
+	key, err := keys.PublicKey(a.keyID)
+	if err != nil {
+		return err
+	}
+
+	message, err := a.signedContent()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, message, a.signature) {
+		return fmt.Errorf("%w: entry %s does not match its signature", ErrInvalidSignature, a.id)
+	}
+	return nil
+}
+
+func (a *AuditEntry) signedContent() ([]byte, error) {
+	content := a.canonical()
+	content.Sequence, content.PreviousHash, content.Signature = 0, "", nil
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize audit entry %s: %w", a.id, err)
+	}
+	return data, nil
+}
+
+// SignatureError reports the first entry whose signature does not verify.
+type SignatureError struct {
+	Sequence int64
+	EntryID  AuditID
+	Err      error
+}
+
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("audit signature invalid at sequence %d: %v", e.Sequence, e.Err)
+}
+
+func (e *SignatureError) Unwrap() error {
+	return e.Err
+}
+
+// Checkpoint is a signed statement of the head of the audit log. As every
+// entry's hash covers its predecessor, signing the head vouches for the whole
+// log up to it, and shows when entries were removed from the end.
+type Checkpoint struct {
+	Sequence  int64     `json:"sequence"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	KeyID     string    `json:"key_id"`
+	Signature []byte    `json:"signature"`
+}
+
+func signCheckpoint(head *AuditEntry, keys KeyProvider) (Checkpoint, error) {
+	keyID, key, err := keys.SigningKey()
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	checkpoint := Checkpoint{CreatedAt: time.Now().UTC(), KeyID: keyID}
+	if head != nil {
+		checkpoint.Sequence, checkpoint.Hash = head.sequence, head.hash
+	}
+	checkpoint.Signature = ed25519.Sign(key, checkpoint.message())
+	return checkpoint, nil
+}
+
+func (c Checkpoint) message() []byte {
+	return fmt.Appendf(nil, "audit checkpoint\n%d\n%s\n%s", c.Sequence, c.Hash, c.CreatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+func (c Checkpoint) Verify(keys KeyResolver) error {
+	key, err := keys.PublicKey(c.KeyID)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, c.message(), c.Signature) {
+		return fmt.Errorf("%w: checkpoint at sequence %d", ErrInvalidSignature, c.Sequence)
+	}
+	return nil
+}
+
+// ExportedLog is the audit log as written by Service.Export.
+type ExportedLog struct {
+	Entries    []EntrySnapshot `json:"entries"`
+	Checkpoint Checkpoint      `json:"checkpoint"`
+}
+
+// VerifyExport checks a log written by Service.Export using only the public
+// keys in keys, so it can run away from the service. It verifies the hash
+// chain, every entry's signature and that the signed checkpoint matches the
+// last entry, and returns the checkpoint the log was verified up to and how
+// many entries it verified. Entries archived before the export are not among
+// them, so the count can be below the checkpoint's sequence.
+func VerifyExport(r io.Reader, keys KeyResolver) (Checkpoint, int, error) {
+	var exported ExportedLog
+	if err := json.NewDecoder(r).Decode(&exported); err != nil {
+		return Checkpoint{}, 0, fmt.Errorf("failed to read exported log: %w", err)
+	}
+
+	if err := exported.Checkpoint.Verify(keys); err != nil {
+		return Checkpoint{}, 0, err
+	}
+
+	entries := make([]*AuditEntry, 0, len(exported.Entries))
+	for _, snapshot := range exported.Entries {
+		entry, err := FromSnapshot(snapshot)
+		if err != nil {
+			return Checkpoint{}, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	sortBySequence(entries)
+
+	if err := verifyLog(entries, keys); err != nil {
+		return Checkpoint{}, 0, err
+	}
+
+	var head AuditEntry
+	if len(entries) > 0 {
+		head = *entries[len(entries)-1]
+	}
+	if head.sequence != exported.Checkpoint.Sequence || head.hash != exported.Checkpoint.Hash {
+		return Checkpoint{}, 0, &BrokenLinkError{
+			Sequence: head.sequence,
+			EntryID:  head.id,
+			Reason:   fmt.Sprintf("log ends at sequence %d but the checkpoint is at %d", head.sequence, exported.Checkpoint.Sequence),
+		}
+	}
+
+	return exported.Checkpoint, len(entries), nil
+}
@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestService_SignsEntries(t *testing.T) {
+	keys := newTestKeys(t)
+	log := &entryLog{}
+	service := NewService(log, WithKeyProvider(keys))
+	ctx := context.Background()
+
+	service.RecordAction(ctx, EntityTypePayment, "pay-1", ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+	retired := keys.rotate(t)
+	service.RecordAction(ctx, EntityTypePayment, "pay-1", ActionTypeProcessed, "user-123", nil, map[string]interface{}{"status": "processing"})
+
+	if log.entries[0].KeyID() != retired || log.entries[1].KeyID() == retired {
+		t.Errorf("expected entries signed before and after rotation, got %q and %q", log.entries[0].KeyID(), log.entries[1].KeyID())
+	}
+	if err := service.Verify(ctx); err != nil {
+		t.Fatalf("expected log to verify across the rotation, got %v", err)
+	}
+
+	// Rewriting an entry and every hash after it keeps the chain intact, but
+	// the forger cannot sign with our keys.
+	_, forgery, _ := ed25519.GenerateKey(nil)
+	forged, _ := FromSnapshot(log.entries[0].ToSnapshot())
+	forged.sequence, forged.previousHash = 0, ""
+	forged.SetNewData(map[string]interface{}{"status": "completed"})
+	forged.Sign("forged-key", forgery)
+	forged.Chain(nil)
+	log.entries[0] = forged
+	log.entries[1].previousHash = forged.hash
+	log.entries[1].hash, _ = log.entries[1].ComputeHash()
+
+	if err := NewService(log).Verify(ctx); err != nil {
+		t.Fatalf("expected the rewritten chain to hold, got %v", err)
+	}
+	var invalid *SignatureError
+	err := service.Verify(ctx)
+	if !errors.As(err, &invalid) || invalid.Sequence != 1 || !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected unknown key at sequence 1, got %v", err)
+	}
+}
+
+func TestVerifyExport(t *testing.T) {
+	keys := newTestKeys(t)
+	ctx := context.Background()
+
+	export := func(t *testing.T) ExportedLog {
+		t.Helper()
+
+		log := &entryLog{}
+		service := NewService(log, WithKeyProvider(keys))
+		for _, action := range []ActionType{ActionTypeCreated, ActionTypeProcessed, ActionTypeCompleted} {
+			service.RecordAction(ctx, EntityTypePayment, "pay-1", action, "user-123", nil, map[string]interface{}{"action": string(action)})
+		}
+
+		var buf bytes.Buffer
+		if err := service.Export(ctx, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var exported ExportedLog
+		json.Unmarshal(buf.Bytes(), &exported)
+		return exported
+	}
+
+	tests := []struct {
+		name    string
+		tamper  func(exported *ExportedLog)
+		wantErr error
+	}{
+		{
+			name:   "untouched",
+			tamper: func(exported *ExportedLog) {},
+		},
+		{
+			name: "truncated",
+			tamper: func(exported *ExportedLog) {
+				exported.Entries = exported.Entries[:2]
+			},
+			wantErr: ErrChainBroken,
+		},
+		{
+			name: "altered entry",
+			tamper: func(exported *ExportedLog) {
+				exported.Entries[1].UserID = "someone-else"
+			},
+			wantErr: ErrChainBroken,
+		},
+		{
+			name: "moved checkpoint",
+			tamper: func(exported *ExportedLog) {
+				exported.Checkpoint.Sequence = 2
+				exported.Checkpoint.Hash = exported.Entries[1].Hash
+			},
+			wantErr: ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exported := export(t)
+			tt.tamper(&exported)
+			data, _ := json.Marshal(exported)
+
+			// Only the public keys are available offline.
+			checkpoint, verified, err := VerifyExport(bytes.NewReader(data), keys.public())
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if checkpoint.Sequence != 3 || verified != 3 {
+				t.Errorf("expected 3 entries verified up to sequence 3, got %d up to %d", verified, checkpoint.Sequence)
+			}
+		})
+	}
+
+	if err := NewService(&entryLog{}).Export(ctx, &bytes.Buffer{}); !errors.Is(err, ErrNoKeyProvider) {
+		t.Errorf("expected ErrNoKeyProvider, got %v", err)
+	}
+}
+
+// testKeys is an in-memory KeyProvider.
+type testKeys struct {
+	current string
+	private map[string]ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	keys := &testKeys{private: make(map[string]ed25519.PrivateKey)}
+	keys.rotate(t)
+	return keys
+}
+
+// rotate switches to a new key and returns the one it replaced.
+func (k *testKeys) rotate(t *testing.T) string {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	previous := k.current
+	k.current = fmt.Sprintf("key-%d", len(k.private)+1)
+	k.private[k.current] = private
+	return previous
+}
+
+func (k *testKeys) SigningKey() (string, ed25519.PrivateKey, error) {
+	return k.current, k.private[k.current], nil
+}
+
+func (k *testKeys) PublicKey(keyID string) (ed25519.PublicKey, error) {
+	return k.public().PublicKey(keyID)
+}
+
+func (k *testKeys) public() PublicKeys {
+	keys := make(PublicKeys)
+	for id, private := range k.private {
+		keys[id] = private.Public().(ed25519.PublicKey)
+	}
+	return keys
+}
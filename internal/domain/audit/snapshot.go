@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// EntrySnapshot is a plain copy of an AuditEntry's state for persistence
+// adapters and the wire. Data and metadata maps are deep-copied in both
+// directions. The JSON field names are part of the storage format.
+type EntrySnapshot struct {
+	ID         string                 `json:"id"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	Action     string                 `json:"action"`
+	OldData    map[string]interface{} `json:"old_data"`
+	NewData    map[string]interface{} `json:"new_data"`
+	UserID     string                 `json:"user_id"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Metadata   map[string]string      `json:"metadata"`
+	Changes    []Change               `json:"changes,omitempty"`
+
+	Sequence     int64  `json:"sequence,omitempty"`
+	PreviousHash string `json:"previous_hash,omitempty"`
+	Hash         string `json:"hash,omitempty"`
+	KeyID        string `json:"key_id,omitempty"`
+	Signature    []byte `json:"signature,omitempty"`
+}
+
+func (a *AuditEntry) ToSnapshot() EntrySnapshot {
+	return EntrySnapshot{
+		ID:         a.id.String(),
+		EntityType: string(a.entityType),
+		EntityID:   a.entityID,
+		Action:     string(a.action),
+		OldData:    copyData(a.oldData),
+		NewData:    copyData(a.newData),
+		UserID:     a.userID,
+		Timestamp:  a.timestamp,
+		Metadata:   copyMetadata(a.metadata),
+		Changes:    copyChanges(a.changes),
+
+		Sequence:     a.sequence,
+		PreviousHash: a.previousHash,
+		Hash:         a.hash,
+		KeyID:        a.keyID,
+		Signature:    slices.Clone(a.signature),
+	}
+}
+
+// FromSnapshot rebuilds an AuditEntry from state previously taken with
+// ToSnapshot. Entries missing their identity, subject, action or timestamp
+// are rejected with ErrInvalidSnapshot. Chain fields are restored as stored,
+// not recomputed, so Service.Verify can tell whether they still hold. Changes
+// are derived from the data, so they are recomputed rather than trusted.
+func FromSnapshot(s EntrySnapshot) (*AuditEntry, error) {
+	switch {
+	case s.ID == "":
+		return nil, fmt.Errorf("%w: id is required", ErrInvalidSnapshot)
+	case s.EntityType == "" || s.EntityID == "":
+		return nil, fmt.Errorf("%w: entity type and id are required", ErrInvalidSnapshot)
+	case s.Action == "":
+		return nil, fmt.Errorf("%w: action is required", ErrInvalidSnapshot)
+	case s.Timestamp.IsZero():
+		return nil, fmt.Errorf("%w: timestamp is required", ErrInvalidSnapshot)
+	case s.Sequence < 0:
+		return nil, fmt.Errorf("%w: sequence cannot be negative", ErrInvalidSnapshot)
+	}
+
+	entry := &AuditEntry{
+		id:         AuditIDFromString(s.ID),
+		entityType: EntityType(s.EntityType),
+		entityID:   s.EntityID,
+		action:     ActionType(s.Action),
+		oldData:    copyData(s.OldData),
+		newData:    copyData(s.NewData),
+		userID:     s.UserID,
+		timestamp:  s.Timestamp,
+		metadata:   copyMetadata(s.Metadata),
+
+		sequence:     s.Sequence,
+		previousHash: s.PreviousHash,
+		hash:         s.Hash,
+		keyID:        s.KeyID,
+		signature:    slices.Clone(s.Signature),
+	}
+	entry.changes = Diff(entry.oldData, entry.newData)
+	return entry, nil
+}
+
+func copyData(data map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		copied[k] = copyValue(v)
+	}
+	return copied
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copyData(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+func copyChanges(changes []Change) []Change {
+	if changes == nil {
+		return nil
+	}
+	copied := make([]Change, len(changes))
+	for i, change := range changes {
+		change.Old, change.New = copyValue(change.Old), copyValue(change.New)
+		copied[i] = change
+	}
+	return copied
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	copied := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEntrySnapshot_RoundTrip(t *testing.T) {
+	entry := NewAuditEntry(EntityTypePayment, "payment-123", ActionTypeProcessed, "user-456")
+	entry.SetOldData(map[string]interface{}{"status": "pending", "tags": []string{"a"}})
+	entry.SetNewData(map[string]interface{}{"status": "processing", "nested": map[string]int{"n": 1}})
+	entry.AddMetadata("source", "api")
+
+	data, err := json.Marshal(entry.ToSnapshot())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded EntrySnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restored, err := FromSnapshot(decoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if restored.ID() != entry.ID() || restored.Action() != entry.Action() || !restored.Timestamp().Equal(entry.Timestamp()) {
+		t.Errorf("expected identity to survive the round trip, got %+v", restored.ToSnapshot())
+	}
+	if !reflect.DeepEqual(restored.OldData(), entry.OldData()) || !reflect.DeepEqual(restored.NewData(), entry.NewData()) {
+		t.Errorf("expected data to survive the round trip, got %v and %v", restored.OldData(), restored.NewData())
+	}
+	if !reflect.DeepEqual(restored.Metadata(), entry.Metadata()) {
+		t.Errorf("expected metadata %v, got %v", entry.Metadata(), restored.Metadata())
+	}
+}
+
+func TestEntrySnapshot_DeepCopies(t *testing.T) {
+	entry := NewAuditEntry(EntityTypePayment, "payment-123", ActionTypeUpdated, "user-456")
+	entry.SetNewData(map[string]interface{}{"nested": map[string]interface{}{"status": "pending"}})
+
+	snapshot := entry.ToSnapshot()
+	snapshot.NewData["nested"].(map[string]interface{})["status"] = "tampered"
+
+	if entry.NewData()["nested"].(map[string]interface{})["status"] != "pending" {
+		t.Error("expected snapshot not to share nested maps with the entry")
+	}
+}
+
+func TestFromSnapshot_Invariants(t *testing.T) {
+	valid := EntrySnapshot{
+		ID:         "audit-1",
+		EntityType: "payment",
+		EntityID:   "payment-123",
+		Action:     "created",
+		Timestamp:  time.Now(),
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(s *EntrySnapshot)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(s *EntrySnapshot) {}},
+		{name: "missing id", modify: func(s *EntrySnapshot) { s.ID = "" }, wantErr: true},
+		{name: "missing entity", modify: func(s *EntrySnapshot) { s.EntityID = "" }, wantErr: true},
+		{name: "missing action", modify: func(s *EntrySnapshot) { s.Action = "" }, wantErr: true},
+		{name: "missing timestamp", modify: func(s *EntrySnapshot) { s.Timestamp = time.Time{} }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := valid
+			tt.modify(&snapshot)
+
+			entry, err := FromSnapshot(snapshot)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSnapshot) {
+					t.Errorf("expected ErrInvalidSnapshot, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entry.OldData() == nil || entry.Metadata() == nil {
+				t.Error("expected nil maps to be restored as empty maps")
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxMinorUnits = 18
+
+var (
+	ErrEmptyCurrency     = errors.New("currency cannot be empty")
+	ErrUnknownCurrency   = errors.New("unknown currency")
+	ErrDuplicateCurrency = errors.New("currency is already registered")
+)
+
+type Currency struct {
+	Code       string
+	Numeric    string
+	MinorUnits int
+	Name       string
+}
+
+type Registry struct {
+	mu     sync.RWMutex
+	byCode map[string]Currency
+}
+
+// NewRegistry returns a registry preloaded with the ISO 4217 table.
+func NewRegistry() *Registry {
+	r := &Registry{
+		byCode: make(map[string]Currency, len(iso4217)),
+	}
+	for _, c := range iso4217 {
+		r.byCode[c.Code] = c
+	}
+	return r
+}
+
+func (r *Registry) Lookup(code string) (Currency, error) {
+	normalized := Normalize(code)
+	if normalized == "" {
+		return Currency{}, ErrEmptyCurrency
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, exists := r.byCode[normalized]
+	if !exists {
+		return Currency{}, fmt.Errorf("%w %q", ErrUnknownCurrency, code)
+	}
+
+	return c, nil
+}
+
+// Register adds a custom or test currency. Codes already present in the
+// registry cannot be redefined.
+func (r *Registry) Register(c Currency) error {
+	c.Code = Normalize(c.Code)
+	if err := validate(c); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byCode[c.Code]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateCurrency, c.Code)
+	}
+
+	r.byCode[c.Code] = c
+	return nil
+}
+
+func (r *Registry) All() []Currency {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	currencies := make([]Currency, 0, len(r.byCode))
+	for _, c := range r.byCode {
+		currencies = append(currencies, c)
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i].Code < currencies[j].Code
+	})
+
+	return currencies
+}
+
+var defaultRegistry = NewRegistry()
+
+func Lookup(code string) (Currency, error) {
+	return defaultRegistry.Lookup(code)
+}
+
+func Register(c Currency) error {
+	return defaultRegistry.Register(c)
+}
+
+func All() []Currency {
+	return defaultRegistry.All()
+}
+
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validate(c Currency) error {
+	if len(c.Code) != 3 || !isAll(c.Code, 'A', 'Z') {
+		return fmt.Errorf("currency code %q must be three letters", c.Code)
+	}
+	if c.Numeric != "" && (len(c.Numeric) != 3 || !isAll(c.Numeric, '0', '9')) {
+		return fmt.Errorf("numeric code %q must be three digits", c.Numeric)
+	}
+	if c.MinorUnits < 0 || c.MinorUnits > maxMinorUnits {
+		return fmt.Errorf("minor units must be between 0 and %d", maxMinorUnits)
+	}
+	return nil
+}
+
+func isAll(s string, lo, hi byte) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < lo || s[i] > hi {
+			return false
+		}
+	}
+	return true
+}
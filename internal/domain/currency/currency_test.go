@@ -0,0 +1,160 @@
+package currency
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		wantCode   string
+		wantMinor  int
+		wantNumber string
+		wantErr    bool
+	}{
+		{name: "us dollar", code: "USD", wantCode: "USD", wantMinor: 2, wantNumber: "840"},
+		{name: "lower case", code: "eur", wantCode: "EUR", wantMinor: 2, wantNumber: "978"},
+		{name: "zero minor units", code: "JPY", wantCode: "JPY", wantMinor: 0, wantNumber: "392"},
+		{name: "three minor units", code: "KWD", wantCode: "KWD", wantMinor: 3, wantNumber: "414"},
+		{name: "four minor units", code: "CLF", wantCode: "CLF", wantMinor: 4, wantNumber: "990"},
+		{name: "unknown code", code: "XYZ", wantErr: true},
+		{name: "word", code: "DOLLARS", wantErr: true},
+		{name: "empty", code: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Lookup(tt.code)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if c.Code != tt.wantCode {
+				t.Errorf("expected code %q, got %q", tt.wantCode, c.Code)
+			}
+
+			if c.MinorUnits != tt.wantMinor {
+				t.Errorf("expected %d minor units, got %d", tt.wantMinor, c.MinorUnits)
+			}
+
+			if c.Numeric != tt.wantNumber {
+				t.Errorf("expected numeric code %q, got %q", tt.wantNumber, c.Numeric)
+			}
+		})
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency Currency
+		wantErr  bool
+	}{
+		{
+			name:     "test currency",
+			currency: Currency{Code: "XTS", Numeric: "963", MinorUnits: 2, Name: "Testing code"},
+		},
+		{
+			name:     "lower case code is normalized",
+			currency: Currency{Code: "xba", MinorUnits: 0, Name: "Bond Markets Unit"},
+		},
+		{
+			name:     "duplicate iso code",
+			currency: Currency{Code: "USD", Numeric: "840", MinorUnits: 2},
+			wantErr:  true,
+		},
+		{
+			name:     "code too long",
+			currency: Currency{Code: "ABCD", MinorUnits: 2},
+			wantErr:  true,
+		},
+		{
+			name:     "non letter code",
+			currency: Currency{Code: "A1B", MinorUnits: 2},
+			wantErr:  true,
+		},
+		{
+			name:     "bad numeric code",
+			currency: Currency{Code: "XTT", Numeric: "12", MinorUnits: 2},
+			wantErr:  true,
+		},
+		{
+			name:     "negative minor units",
+			currency: Currency{Code: "XTU", MinorUnits: -1},
+			wantErr:  true,
+		},
+		{
+			name:     "too many minor units",
+			currency: Currency{Code: "XTV", MinorUnits: 19},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+
+			err := registry.Register(tt.currency)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			c, err := registry.Lookup(tt.currency.Code)
+			if err != nil {
+				t.Errorf("failed to look up registered currency: %v", err)
+				return
+			}
+
+			if c.MinorUnits != tt.currency.MinorUnits {
+				t.Errorf("expected %d minor units, got %d", tt.currency.MinorUnits, c.MinorUnits)
+			}
+
+			if _, err := Lookup(tt.currency.Code); err == nil {
+				t.Error("expected registration to stay local to the registry")
+			}
+		})
+	}
+}
+
+func TestISO4217Table(t *testing.T) {
+	codes := make(map[string]bool)
+	numerics := make(map[string]bool)
+
+	for _, c := range iso4217 {
+		if err := validate(c); err != nil {
+			t.Errorf("invalid entry %s: %v", c.Code, err)
+		}
+		if codes[c.Code] {
+			t.Errorf("duplicate code %s", c.Code)
+		}
+		if numerics[c.Numeric] {
+			t.Errorf("duplicate numeric code %s", c.Numeric)
+		}
+		if c.Name == "" {
+			t.Errorf("missing name for %s", c.Code)
+		}
+		codes[c.Code] = true
+		numerics[c.Numeric] = true
+	}
+
+	if len(All()) < len(iso4217) {
+		t.Errorf("expected at least %d currencies, got %d", len(iso4217), len(All()))
+	}
+}
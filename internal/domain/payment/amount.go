@@ -0,0 +1,271 @@
+package payment
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go-ddd/internal/domain/currency"
+)
+
+type RoundingMode int
+
+const (
+	RoundHalfUp RoundingMode = iota
+	RoundHalfEven
+)
+
+// Amount is an exact monetary value held as a non-negative number of minor
+// units (cents for USD, yen for JPY) of an ISO 4217 currency.
+type Amount struct {
+	minor    int64
+	currency string
+}
+
+func currencyScale(code string) int {
+	c, err := currency.Lookup(code)
+	if err != nil {
+		return 0
+	}
+	return c.MinorUnits
+}
+
+// NewAmount converts value to an exact Amount using its shortest decimal
+// representation, so inputs with more precision than the currency allows
+// (10.005 USD, 0.1+0.2) are rejected rather than silently rounded.
+func NewAmount(value float64, code string) (Amount, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Amount{}, invalidAmount("amount must be a finite number")
+	}
+	return ParseAmount(strconv.FormatFloat(value, 'f', -1, 64), code)
+}
+
+func NewAmountFromMinor(minor int64, code string) (Amount, error) {
+	c, err := currency.Lookup(code)
+	if err != nil {
+		return Amount{}, &AmountError{Reason: err.Error(), Err: err}
+	}
+	if minor < 0 {
+		return Amount{}, invalidAmount("amount cannot be negative")
+	}
+	return Amount{minor: minor, currency: c.Code}, nil
+}
+
+// ParseAmount parses a plain decimal string such as "100.50" in the given
+// currency. The fractional part may not exceed the currency's minor units.
+func ParseAmount(value string, code string) (Amount, error) {
+	c, err := currency.Lookup(code)
+	if err != nil {
+		return Amount{}, &AmountError{Reason: err.Error(), Err: err}
+	}
+
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "-") {
+		return Amount{}, invalidAmount("amount cannot be negative")
+	}
+	value = strings.TrimPrefix(value, "+")
+
+	whole, frac, _ := strings.Cut(value, ".")
+	if whole == "" && frac == "" {
+		return Amount{}, invalidAmount("invalid amount %q", value)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return Amount{}, invalidAmount("invalid amount %q", value)
+	}
+
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > c.MinorUnits {
+		return Amount{}, invalidAmount("amount %s has more than %d decimal places for %s", value, c.MinorUnits, c.Code)
+	}
+	frac += strings.Repeat("0", c.MinorUnits-len(frac))
+
+	minor, ok := new(big.Int).SetString(whole+frac, 10)
+	if !ok || !minor.IsInt64() {
+		return Amount{}, invalidAmount("amount %s is out of range", value)
+	}
+
+	return Amount{minor: minor.Int64(), currency: c.Code}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (a Amount) MinorUnits() int64 {
+	return a.minor
+}
+
+func (a Amount) Currency() string {
+	return a.currency
+}
+
+func (a Amount) Scale() int {
+	return currencyScale(a.currency)
+}
+
+func (a Amount) IsZero() bool {
+	return a.minor == 0
+}
+
+func (a Amount) Equal(other Amount) bool {
+	return a.minor == other.minor && a.currency == other.currency
+}
+
+// Compare returns -1, 0 or +1 depending on whether a is less than, equal to
+// or greater than other. Amounts in different currencies cannot be compared.
+func (a Amount) Compare(other Amount) (int, error) {
+	if err := a.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case a.minor < other.minor:
+		return -1, nil
+	case a.minor > other.minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (a Amount) Add(other Amount) (Amount, error) {
+	if err := a.sameCurrency(other); err != nil {
+		return Amount{}, err
+	}
+	if a.minor > math.MaxInt64-other.minor {
+		return Amount{}, invalidAmount("amount overflow")
+	}
+	return Amount{minor: a.minor + other.minor, currency: a.currency}, nil
+}
+
+func (a Amount) Subtract(other Amount) (Amount, error) {
+	if err := a.sameCurrency(other); err != nil {
+		return Amount{}, err
+	}
+	if other.minor > a.minor {
+		return Amount{}, invalidAmount("amount cannot be negative")
+	}
+	return Amount{minor: a.minor - other.minor, currency: a.currency}, nil
+}
+
+// Multiply scales the amount by an exact rational factor, rounding the result
+// to whole minor units with the given mode.
+func (a Amount) Multiply(factor *big.Rat, mode RoundingMode) (Amount, error) {
+	if factor.Sign() < 0 {
+		return Amount{}, invalidAmount("amount cannot be negative")
+	}
+
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(a.minor), factor)
+	minor := roundRat(product, mode)
+	if !minor.IsInt64() {
+		return Amount{}, invalidAmount("amount overflow")
+	}
+
+	return Amount{minor: minor.Int64(), currency: a.currency}, nil
+}
+
+// Allocate splits the amount between the given ratios without losing any
+// minor units; leftover units go to the first shares one at a time.
+func (a Amount) Allocate(ratios ...int64) ([]Amount, error) {
+	if len(ratios) == 0 {
+		return nil, invalidAmount("at least one ratio is required")
+	}
+
+	total := new(big.Int)
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, invalidAmount("ratios cannot be negative")
+		}
+		total.Add(total, big.NewInt(ratio))
+	}
+	if total.Sign() == 0 {
+		return nil, invalidAmount("ratios must not all be zero")
+	}
+
+	shares := make([]Amount, len(ratios))
+	remainder := a.minor
+	for i, ratio := range ratios {
+		share := new(big.Int).Mul(big.NewInt(a.minor), big.NewInt(ratio))
+		share.Quo(share, total)
+		shares[i] = Amount{minor: share.Int64(), currency: a.currency}
+		remainder -= share.Int64()
+	}
+	for i := 0; remainder > 0; i = (i + 1) % len(shares) {
+		if ratios[i] == 0 {
+			continue
+		}
+		shares[i].minor++
+		remainder--
+	}
+
+	return shares, nil
+}
+
+// Decimal formats the amount with exactly as many fraction digits as the
+// currency has minor units, e.g. "100.50" for USD or "100" for JPY.
+func (a Amount) Decimal() string {
+	scale := a.Scale()
+	digits := strconv.FormatInt(a.minor, 10)
+	if scale == 0 {
+		return digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+func (a Amount) String() string {
+	return a.Decimal() + " " + a.currency
+}
+
+type amountJSON struct {
+	Minor    int64  `json:"amount_minor"`
+	Currency string `json:"currency"`
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(amountJSON{Minor: a.minor, Currency: a.currency})
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var raw amountJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	amount, err := NewAmountFromMinor(raw.Minor, raw.Currency)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+func (a Amount) sameCurrency(other Amount) error {
+	if a.currency != other.currency {
+		return invalidAmount("currency mismatch: %s and %s", a.currency, other.currency)
+	}
+	return nil
+}
+
+func roundRat(r *big.Rat, mode RoundingMode) *big.Int {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+
+	twiceRem := new(big.Int).Mul(rem.Abs(rem), big.NewInt(2))
+	switch twiceRem.Cmp(r.Denom()) {
+	case 1:
+		quo.Add(quo, big.NewInt(int64(r.Sign())))
+	case 0:
+		if mode == RoundHalfUp || quo.Bit(0) == 1 {
+			quo.Add(quo, big.NewInt(int64(r.Sign())))
+		}
+	}
+
+	return quo
+}
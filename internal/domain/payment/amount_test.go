@@ -0,0 +1,379 @@
+package payment
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"go-ddd/internal/domain/currency"
+)
+
+func TestNewAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		currency string
+		want     string
+		wantErr  bool
+		errMsg   string
+	}{
+		{
+			name:     "valid amount and currency",
+			value:    100.50,
+			currency: "USD",
+			want:     "100.50",
+			wantErr:  false,
+		},
+		{
+			name:     "zero amount",
+			value:    0,
+			currency: "EUR",
+			want:     "0.00",
+			wantErr:  false,
+		},
+		{
+			name:     "negative amount",
+			value:    -10.50,
+			currency: "USD",
+			wantErr:  true,
+			errMsg:   "amount cannot be negative",
+		},
+		{
+			name:     "empty currency",
+			value:    100.50,
+			currency: "",
+			wantErr:  true,
+			errMsg:   "currency cannot be empty",
+		},
+		{
+			name:     "large amount",
+			value:    999999,
+			currency: "JPY",
+			want:     "999999",
+			wantErr:  false,
+		},
+		{
+			name:     "too many decimal places",
+			value:    10.005,
+			currency: "USD",
+			wantErr:  true,
+			errMsg:   "amount 10.005 has more than 2 decimal places for USD",
+		},
+		{
+			name:     "fractional yen",
+			value:    999999.99,
+			currency: "JPY",
+			wantErr:  true,
+			errMsg:   "amount 999999.99 has more than 0 decimal places for JPY",
+		},
+		{
+			name:     "binary floating point artefact",
+			value:    0.30000000000000004,
+			currency: "USD",
+			wantErr:  true,
+			errMsg:   "amount 0.30000000000000004 has more than 2 decimal places for USD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := NewAmount(tt.value, tt.currency)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+					return
+				}
+				if err.Error() != tt.errMsg {
+					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if amount.Decimal() != tt.want {
+				t.Errorf("expected value %q, got %q", tt.want, amount.Decimal())
+			}
+
+			if amount.Currency() != tt.currency {
+				t.Errorf("expected currency %q, got %q", tt.currency, amount.Currency())
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		currency  string
+		wantMinor int64
+		wantErr   bool
+	}{
+		{name: "two decimals", value: "100.50", currency: "USD", wantMinor: 10050},
+		{name: "one decimal", value: "100.5", currency: "USD", wantMinor: 10050},
+		{name: "no decimals", value: "100", currency: "USD", wantMinor: 10000},
+		{name: "leading dot", value: ".25", currency: "EUR", wantMinor: 25},
+		{name: "trailing zeros beyond scale", value: "1.2500", currency: "USD", wantMinor: 125},
+		{name: "three decimal currency", value: "1.234", currency: "KWD", wantMinor: 1234},
+		{name: "zero decimal currency", value: "500", currency: "JPY", wantMinor: 500},
+		{name: "surrounding whitespace", value: " 7.00 ", currency: "USD", wantMinor: 700},
+		{name: "too precise", value: "10.005", currency: "USD", wantErr: true},
+		{name: "negative", value: "-1.00", currency: "USD", wantErr: true},
+		{name: "empty", value: "", currency: "USD", wantErr: true},
+		{name: "lone dot", value: ".", currency: "USD", wantErr: true},
+		{name: "letters", value: "12a.00", currency: "USD", wantErr: true},
+		{name: "exponent", value: "1e3", currency: "USD", wantErr: true},
+		{name: "overflow", value: "99999999999999999999", currency: "USD", wantErr: true},
+		{name: "empty currency", value: "1.00", currency: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := ParseAmount(tt.value, tt.currency)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+					return
+				}
+				if !errors.Is(err, ErrInvalidAmount) {
+					t.Errorf("expected ErrInvalidAmount, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if amount.MinorUnits() != tt.wantMinor {
+				t.Errorf("expected %d minor units, got %d", tt.wantMinor, amount.MinorUnits())
+			}
+		})
+	}
+}
+
+func TestParseAmount_Currency(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		want     string
+		errMsg   string
+		errIs    error
+	}{
+		{name: "upper case", currency: "USD", want: "USD"},
+		{name: "lower case is normalized", currency: "usd", want: "USD"},
+		{name: "padded mixed case", currency: " eUr ", want: "EUR"},
+		{name: "unknown code", currency: "XYZ", errMsg: `unknown currency "XYZ"`, errIs: currency.ErrUnknownCurrency},
+		{name: "not a code", currency: "DOLLARS", errMsg: `unknown currency "DOLLARS"`, errIs: currency.ErrUnknownCurrency},
+		{name: "blank", currency: "  ", errMsg: "currency cannot be empty", errIs: currency.ErrEmptyCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := ParseAmount("1", tt.currency)
+
+			if tt.errMsg != "" {
+				if err == nil {
+					t.Errorf("expected error but got none")
+					return
+				}
+				if err.Error() != tt.errMsg {
+					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
+				}
+				if !errors.Is(err, ErrInvalidAmount) || !errors.Is(err, tt.errIs) {
+					t.Errorf("expected error to match ErrInvalidAmount and %v", tt.errIs)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if amount.Currency() != tt.want {
+				t.Errorf("expected currency %q, got %q", tt.want, amount.Currency())
+			}
+		})
+	}
+}
+
+func TestAmount_Decimal(t *testing.T) {
+	tests := []struct {
+		name     string
+		minor    int64
+		currency string
+		want     string
+	}{
+		{name: "whole dollars", minor: 10000, currency: "USD", want: "100.00"},
+		{name: "cents only", minor: 5, currency: "USD", want: "0.05"},
+		{name: "zero", minor: 0, currency: "EUR", want: "0.00"},
+		{name: "yen", minor: 1234, currency: "JPY", want: "1234"},
+		{name: "dinar", minor: 1234, currency: "KWD", want: "1.234"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := NewAmountFromMinor(tt.minor, tt.currency)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if amount.Decimal() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, amount.Decimal())
+			}
+
+			if amount.String() != tt.want+" "+tt.currency {
+				t.Errorf("expected %q, got %q", tt.want+" "+tt.currency, amount.String())
+			}
+		})
+	}
+}
+
+func TestAmount_AddSubtract(t *testing.T) {
+	tenCents := mustParseAmount("0.10", "USD")
+	twentyCents := mustParseAmount("0.20", "USD")
+
+	sum, err := tenCents.Add(twentyCents)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !sum.Equal(mustParseAmount("0.30", "USD")) {
+		t.Errorf("expected 0.30 USD, got %s", sum)
+	}
+
+	diff, err := sum.Subtract(tenCents)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !diff.Equal(twentyCents) {
+		t.Errorf("expected 0.20 USD, got %s", diff)
+	}
+
+	if _, err := tenCents.Subtract(twentyCents); err == nil {
+		t.Error("expected error for negative result")
+	}
+
+	if _, err := tenCents.Add(mustParseAmount("0.10", "EUR")); err == nil {
+		t.Error("expected error for currency mismatch")
+	}
+
+	if _, err := (Amount{minor: 1<<63 - 1, currency: "USD"}).Add(tenCents); err == nil {
+		t.Error("expected error for overflow")
+	}
+}
+
+func TestAmount_Compare(t *testing.T) {
+	small := mustParseAmount("1.00", "USD")
+	large := mustParseAmount("2.00", "USD")
+
+	if got, _ := small.Compare(large); got != -1 {
+		t.Errorf("expected -1, got %d", got)
+	}
+	if got, _ := large.Compare(small); got != 1 {
+		t.Errorf("expected 1, got %d", got)
+	}
+	if got, _ := small.Compare(small); got != 0 {
+		t.Errorf("expected 0, got %d", got)
+	}
+	if _, err := small.Compare(mustParseAmount("1.00", "EUR")); err == nil {
+		t.Error("expected error for currency mismatch")
+	}
+}
+
+func TestAmount_Multiply(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount string
+		factor *big.Rat
+		mode   RoundingMode
+		want   string
+	}{
+		{name: "exact", amount: "10.00", factor: big.NewRat(3, 2), mode: RoundHalfUp, want: "15.00"},
+		{name: "half up rounds away", amount: "0.05", factor: big.NewRat(1, 2), mode: RoundHalfUp, want: "0.03"},
+		{name: "half even rounds to even", amount: "0.05", factor: big.NewRat(1, 2), mode: RoundHalfEven, want: "0.02"},
+		{name: "half even rounds up when odd", amount: "0.07", factor: big.NewRat(1, 2), mode: RoundHalfEven, want: "0.04"},
+		{name: "below half truncates", amount: "1.00", factor: big.NewRat(1, 3), mode: RoundHalfUp, want: "0.33"},
+		{name: "above half rounds up", amount: "2.00", factor: big.NewRat(1, 3), mode: RoundHalfEven, want: "0.67"},
+		{name: "by zero", amount: "12.34", factor: new(big.Rat), mode: RoundHalfUp, want: "0.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mustParseAmount(tt.amount, "USD").Multiply(tt.factor, tt.mode)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Decimal() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got.Decimal())
+			}
+		})
+	}
+
+	if _, err := mustParseAmount("1.00", "USD").Multiply(big.NewRat(-1, 1), RoundHalfUp); err == nil {
+		t.Error("expected error for negative factor")
+	}
+}
+
+func TestAmount_Allocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		ratios  []int64
+		want    []string
+		wantErr bool
+	}{
+		{name: "even split with remainder", amount: "0.05", ratios: []int64{1, 1}, want: []string{"0.03", "0.02"}},
+		{name: "thirds", amount: "100.00", ratios: []int64{1, 1, 1}, want: []string{"33.34", "33.33", "33.33"}},
+		{name: "weighted", amount: "0.05", ratios: []int64{3, 7}, want: []string{"0.02", "0.03"}},
+		{name: "zero ratio gets nothing", amount: "0.05", ratios: []int64{0, 1, 1}, want: []string{"0.00", "0.03", "0.02"}},
+		{name: "no ratios", amount: "1.00", ratios: nil, wantErr: true},
+		{name: "all zero", amount: "1.00", ratios: []int64{0, 0}, wantErr: true},
+		{name: "negative ratio", amount: "1.00", ratios: []int64{1, -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount := mustParseAmount(tt.amount, "USD")
+			shares, err := amount.Allocate(tt.ratios...)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var total int64
+			for i, share := range shares {
+				if share.Decimal() != tt.want[i] {
+					t.Errorf("share %d: expected %s, got %s", i, tt.want[i], share.Decimal())
+				}
+				total += share.MinorUnits()
+			}
+
+			if total != amount.MinorUnits() {
+				t.Errorf("expected shares to sum to %d, got %d", amount.MinorUnits(), total)
+			}
+		})
+	}
+}
+
+func mustParseAmount(value, currency string) Amount {
+	amount, err := ParseAmount(value, currency)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
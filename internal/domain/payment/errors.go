@@ -0,0 +1,59 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrPaymentNotFound            = errors.New("payment not found")
+	ErrInvalidAmount              = errors.New("invalid amount")
+	ErrConcurrentModification     = errors.New("payment was modified concurrently")
+	ErrAuthorizationExpired       = errors.New("authorization has expired")
+	ErrInvalidAuthorizationExpiry = errors.New("authorization expiry must be in the future")
+	ErrRefundReasonRequired       = errors.New("refund reason cannot be empty")
+	ErrInvalidSnapshot            = errors.New("invalid payment snapshot")
+	ErrInvalidEventStream         = errors.New("invalid payment event stream")
+	ErrInvalidFilter              = errors.New("invalid payment filter")
+	ErrInvalidCursor              = errors.New("invalid payment cursor")
+)
+
+// AmountError explains why a value cannot be used as an Amount. It matches
+// ErrInvalidAmount, and the underlying cause if any, with errors.Is.
+type AmountError struct {
+	Reason string
+	Err    error
+}
+
+func invalidAmount(format string, args ...interface{}) error {
+	return &AmountError{Reason: fmt.Sprintf(format, args...)}
+}
+
+func (e *AmountError) Error() string {
+	return e.Reason
+}
+
+func (e *AmountError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrInvalidAmount}
+	}
+	return []error{ErrInvalidAmount, e.Err}
+}
+
+// ErrInvalidTransition is returned when an event is not allowed from the
+// payment's current status. The zero value matches any invalid transition:
+//
+//	errors.Is(err, payment.ErrInvalidTransition{})
+type ErrInvalidTransition struct {
+	From  PaymentStatus
+	Event Event
+}
+
+func (e ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("cannot %s payment in %s status", e.Event, e.From)
+}
+
+func (e ErrInvalidTransition) Is(target error) bool {
+	t, ok := target.(ErrInvalidTransition)
+	return ok && (t.Event == "" || t == e)
+}
@@ -0,0 +1,224 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DomainEvent is a fact recorded by a Payment when one of its commands
+// succeeds. Events are JSON-serializable so they can be stored or published.
+type DomainEvent interface {
+	EventType() string
+	AggregateID() string
+	Header() EventHeader
+}
+
+// EventHeader carries what every payment event has in common. CausationID
+// links the event to whatever triggered the command, such as a request or an
+// upstream message, when the caller supplied one via WithCausationID.
+type EventHeader struct {
+	EventID     string    `json:"event_id"`
+	PaymentID   PaymentID `json:"payment_id"`
+	OccurredAt  time.Time `json:"occurred_at"`
+	CausationID string    `json:"causation_id,omitempty"`
+}
+
+func (h EventHeader) Header() EventHeader {
+	return h
+}
+
+func (h EventHeader) AggregateID() string {
+	return h.PaymentID.String()
+}
+
+// StatusChange records the transition that produced an event.
+type StatusChange struct {
+	From PaymentStatus `json:"from"`
+	To   PaymentStatus `json:"to"`
+}
+
+type PaymentCreated struct {
+	EventHeader
+	Amount      Amount `json:"amount"`
+	Description string `json:"description"`
+}
+
+type PaymentProcessed struct {
+	EventHeader
+	StatusChange
+}
+
+type PaymentCompleted struct {
+	EventHeader
+	StatusChange
+}
+
+type PaymentFailed struct {
+	EventHeader
+	StatusChange
+}
+
+type PaymentCancelled struct {
+	EventHeader
+	StatusChange
+}
+
+type PaymentAuthorized struct {
+	EventHeader
+	StatusChange
+	Amount    Amount    `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type PaymentCaptured struct {
+	EventHeader
+	StatusChange
+	Authorized Amount `json:"authorized"`
+	Amount     Amount `json:"amount"`
+}
+
+type PaymentVoided struct {
+	EventHeader
+	StatusChange
+}
+
+type PaymentRefunded struct {
+	EventHeader
+	StatusChange
+	RefundID      RefundID `json:"refund_id"`
+	Amount        Amount   `json:"amount"`
+	Reason        string   `json:"reason"`
+	TotalRefunded Amount   `json:"total_refunded"`
+}
+
+func (PaymentCreated) EventType() string    { return "payment.created" }
+func (PaymentProcessed) EventType() string  { return "payment.processed" }
+func (PaymentCompleted) EventType() string  { return "payment.completed" }
+func (PaymentFailed) EventType() string     { return "payment.failed" }
+func (PaymentCancelled) EventType() string  { return "payment.cancelled" }
+func (PaymentAuthorized) EventType() string { return "payment.authorized" }
+func (PaymentCaptured) EventType() string   { return "payment.captured" }
+func (PaymentVoided) EventType() string     { return "payment.voided" }
+func (PaymentRefunded) EventType() string   { return "payment.refunded" }
+
+// EventDispatcher receives the events of a payment once it has been
+// persisted.
+type EventDispatcher interface {
+	Dispatch(ctx context.Context, events []DomainEvent) error
+}
+
+type EventDispatcherFunc func(ctx context.Context, events []DomainEvent) error
+
+func (f EventDispatcherFunc) Dispatch(ctx context.Context, events []DomainEvent) error {
+	return f(ctx, events)
+}
+
+type causationKey struct{}
+
+// WithCausationID returns a context whose payment commands, when run through
+// Service, record id as the causation of the events they raise.
+func WithCausationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, causationKey{}, id)
+}
+
+func CausationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(causationKey{}).(string)
+	return id
+}
+
+// DecodeEvent is the inverse of json.Marshal for a payment event whose
+// EventType is eventType.
+func DecodeEvent(eventType string, data []byte) (DomainEvent, error) {
+	var event DomainEvent
+	var err error
+	switch eventType {
+	case PaymentCreated{}.EventType():
+		event, err = decode[PaymentCreated](data)
+	case PaymentProcessed{}.EventType():
+		event, err = decode[PaymentProcessed](data)
+	case PaymentCompleted{}.EventType():
+		event, err = decode[PaymentCompleted](data)
+	case PaymentFailed{}.EventType():
+		event, err = decode[PaymentFailed](data)
+	case PaymentCancelled{}.EventType():
+		event, err = decode[PaymentCancelled](data)
+	case PaymentAuthorized{}.EventType():
+		event, err = decode[PaymentAuthorized](data)
+	case PaymentCaptured{}.EventType():
+		event, err = decode[PaymentCaptured](data)
+	case PaymentVoided{}.EventType():
+		event, err = decode[PaymentVoided](data)
+	case PaymentRefunded{}.EventType():
+		event, err = decode[PaymentRefunded](data)
+	default:
+		return nil, fmt.Errorf("unknown payment event type %q", eventType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", eventType, err)
+	}
+	return event, nil
+}
+
+func decode[T DomainEvent](data []byte) (DomainEvent, error) {
+	var event T
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// PullEvents returns the events recorded since the last call and clears them.
+func (p *Payment) PullEvents() []DomainEvent {
+	events := p.events
+	p.events = nil
+	p.committed = 0
+	return events
+}
+
+// UncommittedEvents returns the events an event-sourced Repository has not
+// yet appended to the payment's stream. Unlike PullEvents it leaves them in
+// place for the dispatcher.
+func (p *Payment) UncommittedEvents() []DomainEvent {
+	return slices.Clone(p.events[p.committed:])
+}
+
+// MarkEventsCommitted is called by event-sourced Repository implementations
+// once UncommittedEvents have been appended.
+func (p *Payment) MarkEventsCommitted() {
+	p.committed = len(p.events)
+}
+
+func (p *Payment) header(at time.Time) EventHeader {
+	return EventHeader{
+		EventID:     uuid.New().String(),
+		PaymentID:   p.id,
+		OccurredAt:  at,
+		CausationID: p.causationID,
+	}
+}
+
+func (p *Payment) record(event DomainEvent) {
+	p.events = append(p.events, event)
+}
+
+// recordTransition records the event for a plain status change made by fire.
+func (p *Payment) recordTransition(event Event, change StatusChange, at time.Time) {
+	header := p.header(at)
+	switch event {
+	case EventProcess:
+		p.record(PaymentProcessed{EventHeader: header, StatusChange: change})
+	case EventComplete:
+		p.record(PaymentCompleted{EventHeader: header, StatusChange: change})
+	case EventFail:
+		p.record(PaymentFailed{EventHeader: header, StatusChange: change})
+	case EventCancel:
+		p.record(PaymentCancelled{EventHeader: header, StatusChange: change})
+	case EventVoid:
+		p.record(PaymentVoided{EventHeader: header, StatusChange: change})
+	}
+}
@@ -0,0 +1,144 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPayment_RecordsEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		command func(p *Payment) error
+		want    []string
+	}{
+		{
+			name:    "process and complete",
+			command: func(p *Payment) error { p.Process(); return p.Complete() },
+			want:    []string{"payment.processed", "payment.completed"},
+		},
+		{
+			name:    "fail",
+			command: (*Payment).Fail,
+			want:    []string{"payment.failed"},
+		},
+		{
+			name:    "cancel",
+			command: (*Payment).Cancel,
+			want:    []string{"payment.cancelled"},
+		},
+		{
+			name: "authorize, capture and refund",
+			command: func(p *Payment) error {
+				p.Authorize(time.Now().Add(time.Hour))
+				p.Capture(mustCreateAmount(100.0, "USD"))
+				_, err := p.Refund(mustCreateAmount(10.0, "USD"), "damaged")
+				return err
+			},
+			want: []string{"payment.authorized", "payment.captured", "payment.refunded"},
+		},
+		{
+			name:    "rejected command records nothing",
+			command: (*Payment).Complete,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := NewPayment(mustCreateAmount(100.0, "USD"), "test payment")
+			created := payment.PullEvents()
+			if len(created) != 1 || created[0].EventType() != "payment.created" {
+				t.Fatalf("expected a single payment.created event, got %v", created)
+			}
+
+			tt.command(payment)
+
+			var got []string
+			for _, event := range payment.PullEvents() {
+				got = append(got, event.EventType())
+				if event.Header().PaymentID != payment.ID() || event.Header().OccurredAt.IsZero() {
+					t.Errorf("incomplete header %+v", event.Header())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if len(payment.PullEvents()) != 0 {
+				t.Error("expected PullEvents to clear recorded events")
+			}
+		})
+	}
+}
+
+func TestPaymentEvents_JSON(t *testing.T) {
+	payment := NewPayment(mustCreateAmount(100.0, "USD"), "test payment")
+	payment.PullEvents()
+	payment.Process()
+	payment.Complete()
+	payment.Refund(mustCreateAmount(40.0, "USD"), "damaged")
+	events := payment.PullEvents()
+
+	data, err := json.Marshal(events[2])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded PaymentRefunded
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	original := events[2].(PaymentRefunded)
+	if decoded.PaymentID != original.PaymentID || decoded.RefundID != original.RefundID || !decoded.Amount.Equal(original.Amount) {
+		t.Errorf("expected %+v, got %+v", original, decoded)
+	}
+	if decoded.From != PaymentStatusCompleted || decoded.To != PaymentStatusPartiallyRefunded {
+		t.Errorf("expected completed to partially_refunded, got %v to %v", decoded.From, decoded.To)
+	}
+}
+
+func TestService_DispatchesEventsAfterPersisting(t *testing.T) {
+	repo := &conflictingRepository{}
+	var dispatched []DomainEvent
+	dispatcher := EventDispatcherFunc(func(ctx context.Context, events []DomainEvent) error {
+		for _, event := range events {
+			if event.Header().PaymentID != repo.payment.ID() {
+				t.Errorf("expected event for persisted payment, got %+v", event.Header())
+			}
+		}
+		if repo.updates > 0 && repo.payment.Status() == PaymentStatusPending {
+			t.Error("expected events to be dispatched after the update")
+		}
+		dispatched = append(dispatched, events...)
+		return nil
+	})
+	service := NewService(repo, WithEventDispatcher(dispatcher))
+	ctx := WithCausationID(context.Background(), "request-42")
+
+	payment, err := service.CreatePayment(ctx, mustCreateAmount(100.0, "USD"), "test payment")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.ProcessPayment(ctx, payment.ID()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.CompletePayment(context.Background(), payment.ID()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.ProcessPayment(ctx, payment.ID()); !errors.Is(err, ErrInvalidTransition{}) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	if len(dispatched) != 3 {
+		t.Fatalf("expected 3 dispatched events, got %d", len(dispatched))
+	}
+	for i, want := range []string{"request-42", "request-42", ""} {
+		if got := dispatched[i].Header().CausationID; got != want {
+			t.Errorf("event %d: expected causation %q, got %q", i, want, got)
+		}
+	}
+}
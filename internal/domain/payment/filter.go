@@ -0,0 +1,104 @@
+package payment
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PaymentFilter selects payments; a payment must satisfy every field that is
+// set. Date ranges are inclusive at both ends.
+type PaymentFilter struct {
+	Statuses []PaymentStatus
+	Currency string
+
+	// MinAmount and MaxAmount bound the payment's amount, inclusive. Only
+	// payments in the bounds' currency can match them.
+	MinAmount *Amount
+	MaxAmount *Amount
+
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+
+	// Description matches payments whose description contains it, ignoring
+	// case.
+	Description string
+
+	// SortBy and Sort order results, with ties broken by ID; the default is
+	// oldest first. Limit caps how many are returned, with zero meaning all.
+	// Cursor resumes after the payment it was taken from; see PaymentPage.
+	SortBy SortField
+	Sort   SortOrder
+	Limit  int
+	Cursor string
+}
+
+// Validate reports a filter that no payment could be selected with, or whose
+// ordering or cursor is unusable, with ErrInvalidFilter or ErrInvalidCursor.
+func (f PaymentFilter) Validate() error {
+	switch f.SortBy {
+	case "", SortByCreatedAt, SortByUpdatedAt, SortByAmount:
+	default:
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidFilter, f.SortBy)
+	}
+	if f.Sort != "" && f.Sort != SortAscending && f.Sort != SortDescending {
+		return fmt.Errorf("%w: unknown sort order %q", ErrInvalidFilter, f.Sort)
+	}
+	if f.Limit < 0 {
+		return fmt.Errorf("%w: limit cannot be negative", ErrInvalidFilter)
+	}
+	if f.Cursor != "" {
+		cursor, err := DecodeCursor(f.Cursor)
+		if err != nil {
+			return err
+		}
+		if cursor.SortBy != f.sortField() {
+			return fmt.Errorf("%w: cursor is for sorting by %s, not %s", ErrInvalidCursor, cursor.SortBy, f.sortField())
+		}
+	}
+
+	for _, bound := range []*Amount{f.MinAmount, f.MaxAmount} {
+		if bound != nil && f.Currency != "" && !strings.EqualFold(bound.Currency(), f.Currency) {
+			return fmt.Errorf("%w: amount bound %s is not in %s", ErrInvalidFilter, bound, f.Currency)
+		}
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil {
+		order, err := f.MinAmount.Compare(*f.MaxAmount)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+		}
+		if order > 0 {
+			return fmt.Errorf("%w: minimum amount %s is above maximum %s", ErrInvalidFilter, f.MinAmount, f.MaxAmount)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether p satisfies every condition of the filter. SortBy,
+// Sort, Limit and Cursor are not conditions; see Paginate.
+func (f PaymentFilter) Matches(p *Payment) bool {
+	switch {
+	case len(f.Statuses) > 0 && !slices.Contains(f.Statuses, p.status),
+		f.Currency != "" && !strings.EqualFold(p.amount.currency, f.Currency),
+		f.MinAmount != nil && !within(p.amount, *f.MinAmount, 1),
+		f.MaxAmount != nil && !within(p.amount, *f.MaxAmount, -1),
+		f.CreatedFrom != nil && p.createdAt.Before(*f.CreatedFrom),
+		f.CreatedTo != nil && p.createdAt.After(*f.CreatedTo),
+		f.UpdatedFrom != nil && p.updatedAt.Before(*f.UpdatedFrom),
+		f.UpdatedTo != nil && p.updatedAt.After(*f.UpdatedTo),
+		f.Description != "" && !strings.Contains(strings.ToLower(p.description), strings.ToLower(f.Description)):
+		return false
+	}
+	return true
+}
+
+// within reports whether amount is on the side of bound given by sign, or
+// equal to it: 1 for at least bound, -1 for at most. Amounts in another
+// currency are never within.
+func within(amount, bound Amount, sign int) bool {
+	order, err := amount.Compare(bound)
+	return err == nil && order*sign >= 0
+}
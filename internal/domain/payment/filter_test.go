@@ -0,0 +1,92 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPaymentFilter_Matches(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := &Payment{
+		id:          PaymentIDFromString("pay-1"),
+		amount:      mustParseAmount("100.50", "USD"),
+		status:      PaymentStatusCompleted,
+		description: "Monthly Subscription",
+		createdAt:   created,
+		updatedAt:   created.Add(time.Hour),
+	}
+	amount := func(value, currency string) *Amount {
+		a := mustParseAmount(value, currency)
+		return &a
+	}
+	at := func(d time.Duration) *time.Time {
+		t := created.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name   string
+		filter PaymentFilter
+		want   bool
+	}{
+		{"empty filter", PaymentFilter{}, true},
+		{"status in set", PaymentFilter{Statuses: []PaymentStatus{PaymentStatusFailed, PaymentStatusCompleted}}, true},
+		{"status not in set", PaymentFilter{Statuses: []PaymentStatus{PaymentStatusPending}}, false},
+		{"currency ignores case", PaymentFilter{Currency: "usd"}, true},
+		{"other currency", PaymentFilter{Currency: "EUR"}, false},
+		{"amount within range", PaymentFilter{MinAmount: amount("100.50", "USD"), MaxAmount: amount("100.50", "USD")}, true},
+		{"amount below minimum", PaymentFilter{MinAmount: amount("100.51", "USD")}, false},
+		{"amount above maximum", PaymentFilter{MaxAmount: amount("100", "USD")}, false},
+		{"amount bound in another currency", PaymentFilter{MinAmount: amount("1", "EUR")}, false},
+		{"created range inclusive", PaymentFilter{CreatedFrom: at(0), CreatedTo: at(0)}, true},
+		{"created before range", PaymentFilter{CreatedFrom: at(time.Minute)}, false},
+		{"created after range", PaymentFilter{CreatedTo: at(-time.Minute)}, false},
+		{"updated within range", PaymentFilter{UpdatedFrom: at(time.Hour), UpdatedTo: at(2 * time.Hour)}, true},
+		{"updated before range", PaymentFilter{UpdatedFrom: at(2 * time.Hour)}, false},
+		{"description substring ignores case", PaymentFilter{Description: "subscription"}, true},
+		{"description missing", PaymentFilter{Description: "refund"}, false},
+		{"all conditions must hold", PaymentFilter{Currency: "USD", Description: "refund"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(p); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPaymentFilter_Validate(t *testing.T) {
+	usd := mustParseAmount("10", "USD")
+	more := mustParseAmount("20", "USD")
+	eur := mustParseAmount("10", "EUR")
+	p := &Payment{id: PaymentIDFromString("pay-1"), amount: usd}
+
+	tests := []struct {
+		name    string
+		filter  PaymentFilter
+		wantErr error
+	}{
+		{"empty filter", PaymentFilter{}, nil},
+		{"amount range", PaymentFilter{Currency: "USD", MinAmount: &usd, MaxAmount: &more}, nil},
+		{"cursor for the sort field", PaymentFilter{SortBy: SortByAmount, Cursor: CursorAfter(p, SortByAmount)}, nil},
+		{"unknown sort field", PaymentFilter{SortBy: "status"}, ErrInvalidFilter},
+		{"unknown sort order", PaymentFilter{Sort: "up"}, ErrInvalidFilter},
+		{"negative limit", PaymentFilter{Limit: -1}, ErrInvalidFilter},
+		{"minimum above maximum", PaymentFilter{MinAmount: &more, MaxAmount: &usd}, ErrInvalidFilter},
+		{"bounds in different currencies", PaymentFilter{MinAmount: &usd, MaxAmount: &eur}, ErrInvalidFilter},
+		{"bound outside the currency", PaymentFilter{Currency: "USD", MaxAmount: &eur}, ErrInvalidFilter},
+		{"malformed cursor", PaymentFilter{Cursor: "!"}, ErrInvalidCursor},
+		{"cursor for another sort field", PaymentFilter{SortBy: SortByUpdatedAt, Cursor: CursorAfter(p, SortByCreatedAt)}, ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package payment
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByUpdatedAt SortField = "updated_at"
+	// SortByAmount groups payments by currency code, then orders them by
+	// amount within each currency.
+	SortByAmount SortField = "amount"
+)
+
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+// PaymentPage is one page of a query. NextCursor, set as the next query's
+// PaymentFilter.Cursor, continues where the page ends; it is empty on the
+// last page.
+type PaymentPage struct {
+	Payments   []*Payment
+	NextCursor string
+}
+
+// Cursor is the position a PaymentFilter.Cursor encodes: the sort key and ID
+// of the last payment already returned. At is set for the timestamp fields
+// and Amount for SortByAmount. Callers treat cursors as opaque; adapters that
+// resume queries themselves decode them with DecodeCursor.
+type Cursor struct {
+	SortBy SortField
+	At     time.Time
+	Amount Amount
+	ID     PaymentID
+}
+
+// CursorAfter returns the cursor that resumes a query sorted by field after p.
+func CursorAfter(p *Payment, field SortField) string {
+	var key string
+	switch field {
+	case SortByUpdatedAt:
+		key = strconv.FormatInt(p.updatedAt.UnixNano(), 10)
+	case SortByAmount:
+		key = p.amount.currency + ":" + strconv.FormatInt(p.amount.minor, 10)
+	default:
+		field = SortByCreatedAt
+		key = strconv.FormatInt(p.createdAt.UnixNano(), 10)
+	}
+	raw := string(field) + ":" + key + ":" + p.id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	field, rest, _ := strings.Cut(string(raw), ":")
+	c := Cursor{SortBy: SortField(field)}
+	var parts []string
+	switch c.SortBy {
+	case SortByCreatedAt, SortByUpdatedAt:
+		parts = strings.SplitN(rest, ":", 2)
+	case SortByAmount:
+		parts = strings.SplitN(rest, ":", 3)
+	default:
+		return Cursor{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidCursor, field)
+	}
+	if len(parts) < 2 || parts[len(parts)-1] == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	c.ID = PaymentIDFromString(parts[len(parts)-1])
+
+	if c.SortBy == SortByAmount {
+		if len(parts) != 3 {
+			return Cursor{}, ErrInvalidCursor
+		}
+		minor, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+		}
+		if c.Amount, err = NewAmountFromMinor(minor, parts[0]); err != nil {
+			return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+		}
+		return c, nil
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	c.At = time.Unix(0, nanos)
+	return c, nil
+}
+
+func (f PaymentFilter) sortField() SortField {
+	if f.SortBy == "" {
+		return SortByCreatedAt
+	}
+	return f.SortBy
+}
+
+// comparePayments orders payments by field, then ID, ascending. Timestamps
+// are compared in wall-clock nanoseconds, as cursors hold them.
+func comparePayments(field SortField) func(a, b *Payment) int {
+	switch field {
+	case SortByUpdatedAt:
+		return func(a, b *Payment) int {
+			return cmp.Or(cmp.Compare(a.updatedAt.UnixNano(), b.updatedAt.UnixNano()), cmp.Compare(a.id.String(), b.id.String()))
+		}
+	case SortByAmount:
+		return func(a, b *Payment) int {
+			return cmp.Or(cmp.Compare(a.amount.currency, b.amount.currency), cmp.Compare(a.amount.minor, b.amount.minor), cmp.Compare(a.id.String(), b.id.String()))
+		}
+	default:
+		return func(a, b *Payment) int {
+			return cmp.Or(cmp.Compare(a.createdAt.UnixNano(), b.createdAt.UnixNano()), cmp.Compare(a.id.String(), b.id.String()))
+		}
+	}
+}
+
+func (f PaymentFilter) comparer() func(a, b *Payment) int {
+	compare := comparePayments(f.sortField())
+	if f.Sort == SortDescending {
+		return func(a, b *Payment) int { return compare(b, a) }
+	}
+	return compare
+}
+
+// Paginate sorts payments, which already match the filter, in the filter's
+// order and returns those after its cursor, at most Limit of them. It fails
+// if the filter does not Validate.
+func (f PaymentFilter) Paginate(payments []*Payment) ([]*Payment, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	compare := f.comparer()
+	sorted := slices.SortedFunc(slices.Values(payments), compare)
+
+	if f.Cursor != "" {
+		cursor, err := DecodeCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		position := &Payment{id: cursor.ID, amount: cursor.Amount, createdAt: cursor.At, updatedAt: cursor.At}
+		start, _ := slices.BinarySearchFunc(sorted, position, compare)
+		if start < len(sorted) && compare(sorted[start], position) == 0 {
+			start++
+		}
+		sorted = sorted[start:]
+	}
+
+	if f.Limit > 0 && len(sorted) > f.Limit {
+		sorted = sorted[:f.Limit]
+	}
+	return sorted, nil
+}
@@ -0,0 +1,140 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPaymentFilter_Paginate(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(id string, minute int, updated int, amount, currency string) *Payment {
+		return &Payment{
+			id:        PaymentIDFromString(id),
+			amount:    mustParseAmount(amount, currency),
+			createdAt: base.Add(time.Duration(minute) * time.Minute),
+			updatedAt: base.Add(time.Duration(updated) * time.Minute),
+		}
+	}
+	payments := []*Payment{
+		at("c", 2, 2, "5", "USD"),
+		at("b", 1, 9, "30", "USD"),
+		at("d", 3, 4, "1", "EUR"),
+		at("a", 1, 3, "5", "USD"),
+	}
+
+	tests := []struct {
+		name    string
+		filter  PaymentFilter
+		want    []string
+		wantErr error
+	}{
+		{"oldest first by default, ties by ID", PaymentFilter{}, []string{"a", "b", "c", "d"}, nil},
+		{"descending", PaymentFilter{Sort: SortDescending}, []string{"d", "c", "b", "a"}, nil},
+		{"by update time", PaymentFilter{SortBy: SortByUpdatedAt}, []string{"c", "a", "d", "b"}, nil},
+		{"by currency, then amount", PaymentFilter{SortBy: SortByAmount}, []string{"d", "a", "c", "b"}, nil},
+		{"limit", PaymentFilter{Limit: 2}, []string{"a", "b"}, nil},
+		{"cursor", PaymentFilter{Cursor: CursorAfter(payments[1], SortByCreatedAt)}, []string{"c", "d"}, nil},
+		{"amount cursor", PaymentFilter{SortBy: SortByAmount, Cursor: CursorAfter(payments[3], SortByAmount)}, []string{"c", "b"}, nil},
+		{"descending cursor", PaymentFilter{SortBy: SortByUpdatedAt, Sort: SortDescending, Cursor: CursorAfter(payments[2], SortByUpdatedAt)}, []string{"a", "c"}, nil},
+		{"cursor of a removed payment", PaymentFilter{Cursor: CursorAfter(at("bb", 2, 0, "1", "USD"), SortByCreatedAt)}, []string{"c", "d"}, nil},
+		{"cursor past the end", PaymentFilter{Cursor: CursorAfter(at("z", 9, 0, "1", "USD"), SortByCreatedAt)}, nil, nil},
+		{"invalid cursor", PaymentFilter{Cursor: "!"}, nil, ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := tt.filter.Paginate(payments)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			var got []string
+			for _, p := range page {
+				got = append(got, p.ID().String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	p := &Payment{
+		id:        PaymentIDFromString("pay:1"),
+		amount:    mustParseAmount("12.34", "EUR"),
+		createdAt: time.Unix(0, 1700000000123456789),
+		updatedAt: time.Unix(0, 1700000000987654321),
+	}
+
+	tests := []struct {
+		field SortField
+		want  Cursor
+	}{
+		{SortByCreatedAt, Cursor{SortBy: SortByCreatedAt, At: p.createdAt, ID: p.id}},
+		{SortByUpdatedAt, Cursor{SortBy: SortByUpdatedAt, At: p.updatedAt, ID: p.id}},
+		{SortByAmount, Cursor{SortBy: SortByAmount, Amount: p.amount, ID: p.id}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.field), func(t *testing.T) {
+			got, err := DecodeCursor(CursorAfter(p, tt.field))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.SortBy != tt.want.SortBy || !got.At.Equal(tt.want.At) || !got.Amount.Equal(tt.want.Amount) || got.ID != tt.want.ID {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestService_ListPayments(t *testing.T) {
+	ctx := context.Background()
+	repo := &listingRepository{}
+	for i := range 5 {
+		p := NewPayment(mustParseAmount("10", "USD"), "order")
+		p.createdAt = p.createdAt.Add(time.Duration(i) * time.Minute)
+		repo.payments = append(repo.payments, p)
+	}
+	service := NewService(repo)
+
+	page, err := service.ListPayments(ctx, PaymentFilter{Sort: SortDescending, Limit: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Payments) != 3 || page.Payments[0] != repo.payments[4] || page.NextCursor == "" {
+		t.Fatalf("expected the 3 newest payments and a cursor, got %d payments", len(page.Payments))
+	}
+
+	page, err = service.ListPayments(ctx, PaymentFilter{Sort: SortDescending, Limit: 3, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Payments) != 2 || page.Payments[1] != repo.payments[0] || page.NextCursor != "" {
+		t.Errorf("expected the 2 oldest payments and no cursor, got %d payments and %q", len(page.Payments), page.NextCursor)
+	}
+
+	if _, err := service.ListPayments(ctx, PaymentFilter{Limit: -1}); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("expected ErrInvalidFilter, got %v", err)
+	}
+}
+
+// listingRepository serves FindByFilter from a fixed list of payments.
+type listingRepository struct {
+	Repository
+	payments []*Payment
+}
+
+func (r *listingRepository) FindByFilter(ctx context.Context, filter PaymentFilter) ([]*Payment, error) {
+	var matching []*Payment
+	for _, p := range r.payments {
+		if filter.Matches(p) {
+			matching = append(matching, p)
+		}
+	}
+	return filter.Paginate(matching)
+}
@@ -1,7 +1,7 @@
 package payment
 
 import (
-	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,27 +23,13 @@ func (id PaymentID) String() string {
 	return id.value
 }
 
-type Amount struct {
-	value    float64
-	currency string
+func (id PaymentID) MarshalText() ([]byte, error) {
+	return []byte(id.value), nil
 }
 
-func NewAmount(value float64, currency string) (Amount, error) {
-	if value < 0 {
-		return Amount{}, errors.New("amount cannot be negative")
-	}
-	if currency == "" {
-		return Amount{}, errors.New("currency cannot be empty")
-	}
-	return Amount{value: value, currency: currency}, nil
-}
-
-func (a Amount) Value() float64 {
-	return a.value
-}
-
-func (a Amount) Currency() string {
-	return a.currency
+func (id *PaymentID) UnmarshalText(text []byte) error {
+	id.value = string(text)
+	return nil
 }
 
 type PaymentStatus int
@@ -54,6 +40,10 @@ const (
 	PaymentStatusCompleted
 	PaymentStatusFailed
 	PaymentStatusCancelled
+	PaymentStatusPartiallyRefunded
+	PaymentStatusRefunded
+	PaymentStatusAuthorized
+	PaymentStatusVoided
 )
 
 func (s PaymentStatus) String() string {
@@ -68,30 +58,76 @@ func (s PaymentStatus) String() string {
 		return "failed"
 	case PaymentStatusCancelled:
 		return "cancelled"
+	case PaymentStatusPartiallyRefunded:
+		return "partially_refunded"
+	case PaymentStatusRefunded:
+		return "refunded"
+	case PaymentStatusAuthorized:
+		return "authorized"
+	case PaymentStatusVoided:
+		return "voided"
 	default:
 		return "unknown"
 	}
 }
 
+func (s PaymentStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *PaymentStatus) UnmarshalText(text []byte) error {
+	status, err := ParsePaymentStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
+
+// ParsePaymentStatus is the inverse of PaymentStatus.String.
+func ParsePaymentStatus(s string) (PaymentStatus, error) {
+	for status := PaymentStatusPending; status <= PaymentStatusVoided; status++ {
+		if status.String() == s {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown payment status %q", s)
+}
+
 type Payment struct {
 	id          PaymentID
 	amount      Amount
 	status      PaymentStatus
 	description string
+	refunds     []Refund
+	captured    Amount
+	authExpiry  time.Time
 	createdAt   time.Time
 	updatedAt   time.Time
+	version     int64
+
+	events      []DomainEvent
+	committed   int
+	causationID string
 }
 
 func NewPayment(amount Amount, description string) *Payment {
+	return newPayment(amount, description, "")
+}
+
+func newPayment(amount Amount, description string, causationID string) *Payment {
 	now := time.Now()
-	return &Payment{
+	p := &Payment{
 		id:          NewPaymentID(),
 		amount:      amount,
 		status:      PaymentStatusPending,
 		description: description,
 		createdAt:   now,
 		updatedAt:   now,
+		causationID: causationID,
 	}
+	p.record(PaymentCreated{EventHeader: p.header(now), Amount: amount, Description: description})
+	return p
 }
 
 func (p *Payment) ID() PaymentID {
@@ -110,6 +146,50 @@ func (p *Payment) Description() string {
 	return p.description
 }
 
+func (p *Payment) AuthorizationExpiresAt() time.Time {
+	return p.authExpiry
+}
+
+// CapturedAmount is the amount actually settled. Payments completed without a
+// separate capture settle their full amount.
+func (p *Payment) CapturedAmount() Amount {
+	if p.captured.currency == "" {
+		return p.amount
+	}
+	return p.captured
+}
+
+func (p *Payment) Refunds() []Refund {
+	refunds := make([]Refund, len(p.refunds))
+	copy(refunds, p.refunds)
+	return refunds
+}
+
+// RefundedAmount totals the refunds issued. It fails with ErrInvalidAmount if
+// the total overflows.
+func (p *Payment) RefundedAmount() (Amount, error) {
+	total := Amount{currency: p.amount.currency}
+	for _, r := range p.refunds {
+		var err error
+		if total, err = total.Add(r.amount); err != nil {
+			return Amount{}, err
+		}
+	}
+	return total, nil
+}
+
+func (p *Payment) RefundableAmount() Amount {
+	refunded, err := p.RefundedAmount()
+	if err != nil {
+		return Amount{currency: p.amount.currency}
+	}
+	remaining, err := p.CapturedAmount().Subtract(refunded)
+	if err != nil {
+		return Amount{currency: p.amount.currency}
+	}
+	return remaining
+}
+
 func (p *Payment) CreatedAt() time.Time {
 	return p.createdAt
 }
@@ -118,38 +198,164 @@ func (p *Payment) UpdatedAt() time.Time {
 	return p.updatedAt
 }
 
-func (p *Payment) Process() error {
-	if p.status != PaymentStatusPending {
-		return errors.New("payment can only be processed from pending status")
+// Version is the revision of the payment last read from or written to a
+// Repository. A payment that was never saved has version 0.
+func (p *Payment) Version() int64 {
+	return p.version
+}
+
+// SetVersion is called by Repository implementations after a successful
+// write; application code should not need it.
+func (p *Payment) SetVersion(version int64) {
+	p.version = version
+}
+
+func (p *Payment) CanTransition(event Event) bool {
+	_, err := lifecycle.Fire(p, event)
+	return err == nil
+}
+
+func (p *Payment) AvailableEvents() []Event {
+	var events []Event
+	for _, event := range lifecycle.AvailableEvents(p.status) {
+		if p.CanTransition(event) {
+			events = append(events, event)
+		}
 	}
-	p.status = PaymentStatusProcessing
-	p.updatedAt = time.Now()
-	return nil
+	return events
+}
+
+func (p *Payment) Process() error {
+	return p.fire(EventProcess)
 }
 
 func (p *Payment) Complete() error {
-	if p.status != PaymentStatusProcessing {
-		return errors.New("payment can only be completed from processing status")
+	return p.fire(EventComplete)
+}
+
+func (p *Payment) Fail() error {
+	return p.fire(EventFail)
+}
+
+func (p *Payment) Cancel() error {
+	return p.fire(EventCancel)
+}
+
+// Authorize places a hold on the payment amount until expiresAt. The hold is
+// later either captured, fully or partially, or voided.
+func (p *Payment) Authorize(expiresAt time.Time) error {
+	next, err := lifecycle.Fire(p, EventAuthorize)
+	if err != nil {
+		return err
 	}
-	p.status = PaymentStatusCompleted
-	p.updatedAt = time.Now()
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return ErrInvalidAuthorizationExpiry
+	}
+	p.authExpiry = expiresAt
+	change := StatusChange{From: p.status, To: next}
+	p.apply(next, now)
+	p.record(PaymentAuthorized{EventHeader: p.header(now), StatusChange: change, Amount: p.amount, ExpiresAt: expiresAt})
 	return nil
 }
 
-func (p *Payment) Fail() error {
-	if p.status == PaymentStatusCompleted {
-		return errors.New("completed payment cannot be failed")
+func (p *Payment) Capture(amount Amount) error {
+	next, err := lifecycle.Fire(p, EventCapture)
+	if err != nil {
+		return err
+	}
+	if amount.IsZero() {
+		return invalidAmount("capture amount must be greater than zero")
+	}
+
+	cmp, err := amount.Compare(p.amount)
+	if err != nil {
+		return err
 	}
-	p.status = PaymentStatusFailed
-	p.updatedAt = time.Now()
+	if cmp > 0 {
+		return invalidAmount("capture of %s exceeds authorized amount %s", amount, p.amount)
+	}
+
+	now := time.Now()
+	p.captured = amount
+	change := StatusChange{From: p.status, To: next}
+	p.apply(next, now)
+	p.record(PaymentCaptured{EventHeader: p.header(now), StatusChange: change, Authorized: p.amount, Amount: amount})
 	return nil
 }
 
-func (p *Payment) Cancel() error {
-	if p.status == PaymentStatusCompleted || p.status == PaymentStatusProcessing {
-		return errors.New("payment cannot be cancelled in current status")
+func (p *Payment) Void() error {
+	return p.fire(EventVoid)
+}
+
+// Refund gives back part or all of the captured amount. Several partial
+// refunds may be issued until their total reaches the captured amount.
+func (p *Payment) Refund(amount Amount, reason string) (Refund, error) {
+	if !lifecycle.CanTransition(p.status, EventRefund) {
+		return Refund{}, ErrInvalidTransition{From: p.status, Event: EventRefund}
+	}
+	if amount.IsZero() {
+		return Refund{}, invalidAmount("refund amount must be greater than zero")
+	}
+	if reason == "" {
+		return Refund{}, ErrRefundReasonRequired
+	}
+
+	cmp, err := amount.Compare(p.RefundableAmount())
+	if err != nil {
+		return Refund{}, err
+	}
+	if cmp > 0 {
+		return Refund{}, invalidAmount("refund of %s exceeds refundable amount %s", amount, p.RefundableAmount())
+	}
+
+	refunded, err := p.RefundedAmount()
+	if err != nil {
+		return Refund{}, err
+	}
+	totalRefunded, err := refunded.Add(amount)
+	if err != nil {
+		return Refund{}, err
+	}
+
+	event := EventPartialRefund
+	if cmp == 0 {
+		event = EventRefund
+	}
+	next, err := lifecycle.Fire(p, event)
+	if err != nil {
+		return Refund{}, err
+	}
+
+	refund := newRefund(amount, reason)
+	p.refunds = append(p.refunds, refund)
+	change := StatusChange{From: p.status, To: next}
+	p.apply(next, refund.createdAt)
+	p.record(PaymentRefunded{
+		EventHeader:   p.header(refund.createdAt),
+		StatusChange:  change,
+		RefundID:      refund.id,
+		Amount:        amount,
+		Reason:        reason,
+		TotalRefunded: totalRefunded,
+	})
+
+	return refund, nil
+}
+
+func (p *Payment) fire(event Event) error {
+	next, err := lifecycle.Fire(p, event)
+	if err != nil {
+		return err
 	}
-	p.status = PaymentStatusCancelled
-	p.updatedAt = time.Now()
+	now := time.Now()
+	change := StatusChange{From: p.status, To: next}
+	p.apply(next, now)
+	p.recordTransition(event, change, now)
 	return nil
 }
+
+func (p *Payment) apply(status PaymentStatus, at time.Time) {
+	p.status = status
+	p.updatedAt = at
+}
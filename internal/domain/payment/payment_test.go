@@ -1,83 +1,12 @@
 package payment
 
 import (
+	"errors"
+	"math"
 	"testing"
 	"time"
 )
 
-func TestNewAmount(t *testing.T) {
-	tests := []struct {
-		name     string
-		value    float64
-		currency string
-		wantErr  bool
-		errMsg   string
-	}{
-		{
-			name:     "valid amount and currency",
-			value:    100.50,
-			currency: "USD",
-			wantErr:  false,
-		},
-		{
-			name:     "zero amount",
-			value:    0,
-			currency: "EUR",
-			wantErr:  false,
-		},
-		{
-			name:     "negative amount",
-			value:    -10.50,
-			currency: "USD",
-			wantErr:  true,
-			errMsg:   "amount cannot be negative",
-		},
-		{
-			name:     "empty currency",
-			value:    100.50,
-			currency: "",
-			wantErr:  true,
-			errMsg:   "currency cannot be empty",
-		},
-		{
-			name:     "large amount",
-			value:    999999.99,
-			currency: "JPY",
-			wantErr:  false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			amount, err := NewAmount(tt.value, tt.currency)
-
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("expected error but got none")
-					return
-				}
-				if err.Error() != tt.errMsg {
-					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
-				}
-				return
-			}
-
-			if err != nil {
-				t.Errorf("unexpected error: %v", err)
-				return
-			}
-
-			if amount.Value() != tt.value {
-				t.Errorf("expected value %f, got %f", tt.value, amount.Value())
-			}
-
-			if amount.Currency() != tt.currency {
-				t.Errorf("expected currency %q, got %q", tt.currency, amount.Currency())
-			}
-		})
-	}
-}
-
 func TestPaymentStatus_String(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -109,6 +38,26 @@ func TestPaymentStatus_String(t *testing.T) {
 			status: PaymentStatusCancelled,
 			want:   "cancelled",
 		},
+		{
+			name:   "partially refunded status",
+			status: PaymentStatusPartiallyRefunded,
+			want:   "partially_refunded",
+		},
+		{
+			name:   "refunded status",
+			status: PaymentStatusRefunded,
+			want:   "refunded",
+		},
+		{
+			name:   "authorized status",
+			status: PaymentStatusAuthorized,
+			want:   "authorized",
+		},
+		{
+			name:   "voided status",
+			status: PaymentStatusVoided,
+			want:   "voided",
+		},
 		{
 			name:   "unknown status",
 			status: PaymentStatus(999),
@@ -144,25 +93,25 @@ func TestPayment_Process(t *testing.T) {
 			name:          "process from processing",
 			initialStatus: PaymentStatusProcessing,
 			wantErr:       true,
-			errMsg:        "payment can only be processed from pending status",
+			errMsg:        "cannot process payment in processing status",
 		},
 		{
 			name:          "process from completed",
 			initialStatus: PaymentStatusCompleted,
 			wantErr:       true,
-			errMsg:        "payment can only be processed from pending status",
+			errMsg:        "cannot process payment in completed status",
 		},
 		{
 			name:          "process from failed",
 			initialStatus: PaymentStatusFailed,
 			wantErr:       true,
-			errMsg:        "payment can only be processed from pending status",
+			errMsg:        "cannot process payment in failed status",
 		},
 		{
 			name:          "process from cancelled",
 			initialStatus: PaymentStatusCancelled,
 			wantErr:       true,
-			errMsg:        "payment can only be processed from pending status",
+			errMsg:        "cannot process payment in cancelled status",
 		},
 	}
 
@@ -222,25 +171,25 @@ func TestPayment_Complete(t *testing.T) {
 			name:          "complete from pending",
 			initialStatus: PaymentStatusPending,
 			wantErr:       true,
-			errMsg:        "payment can only be completed from processing status",
+			errMsg:        "cannot complete payment in pending status",
 		},
 		{
 			name:          "complete from completed",
 			initialStatus: PaymentStatusCompleted,
 			wantErr:       true,
-			errMsg:        "payment can only be completed from processing status",
+			errMsg:        "cannot complete payment in completed status",
 		},
 		{
 			name:          "complete from failed",
 			initialStatus: PaymentStatusFailed,
 			wantErr:       true,
-			errMsg:        "payment can only be completed from processing status",
+			errMsg:        "cannot complete payment in failed status",
 		},
 		{
 			name:          "complete from cancelled",
 			initialStatus: PaymentStatusCancelled,
 			wantErr:       true,
-			errMsg:        "payment can only be completed from processing status",
+			errMsg:        "cannot complete payment in cancelled status",
 		},
 	}
 
@@ -303,22 +252,28 @@ func TestPayment_Fail(t *testing.T) {
 			expectedStatus: PaymentStatusFailed,
 		},
 		{
-			name:           "fail from failed",
-			initialStatus:  PaymentStatusFailed,
-			wantErr:        false,
-			expectedStatus: PaymentStatusFailed,
+			name:          "fail from failed",
+			initialStatus: PaymentStatusFailed,
+			wantErr:       true,
+			errMsg:        "cannot fail payment in failed status",
 		},
 		{
-			name:           "fail from cancelled",
-			initialStatus:  PaymentStatusCancelled,
-			wantErr:        false,
-			expectedStatus: PaymentStatusFailed,
+			name:          "fail from cancelled",
+			initialStatus: PaymentStatusCancelled,
+			wantErr:       true,
+			errMsg:        "cannot fail payment in cancelled status",
 		},
 		{
 			name:          "fail from completed",
 			initialStatus: PaymentStatusCompleted,
 			wantErr:       true,
-			errMsg:        "completed payment cannot be failed",
+			errMsg:        "cannot fail payment in completed status",
+		},
+		{
+			name:          "fail from refunded",
+			initialStatus: PaymentStatusRefunded,
+			wantErr:       true,
+			errMsg:        "cannot fail payment in refunded status",
 		},
 	}
 
@@ -375,28 +330,34 @@ func TestPayment_Cancel(t *testing.T) {
 			expectedStatus: PaymentStatusCancelled,
 		},
 		{
-			name:           "cancel from failed",
-			initialStatus:  PaymentStatusFailed,
-			wantErr:        false,
-			expectedStatus: PaymentStatusCancelled,
+			name:          "cancel from failed",
+			initialStatus: PaymentStatusFailed,
+			wantErr:       true,
+			errMsg:        "cannot cancel payment in failed status",
 		},
 		{
-			name:           "cancel from cancelled",
-			initialStatus:  PaymentStatusCancelled,
-			wantErr:        false,
-			expectedStatus: PaymentStatusCancelled,
+			name:          "cancel from cancelled",
+			initialStatus: PaymentStatusCancelled,
+			wantErr:       true,
+			errMsg:        "cannot cancel payment in cancelled status",
 		},
 		{
 			name:          "cancel from processing",
 			initialStatus: PaymentStatusProcessing,
 			wantErr:       true,
-			errMsg:        "payment cannot be cancelled in current status",
+			errMsg:        "cannot cancel payment in processing status",
 		},
 		{
 			name:          "cancel from completed",
 			initialStatus: PaymentStatusCompleted,
 			wantErr:       true,
-			errMsg:        "payment cannot be cancelled in current status",
+			errMsg:        "cannot cancel payment in completed status",
+		},
+		{
+			name:          "cancel from partially refunded",
+			initialStatus: PaymentStatusPartiallyRefunded,
+			wantErr:       true,
+			errMsg:        "cannot cancel payment in partially_refunded status",
 		},
 	}
 
@@ -438,6 +399,427 @@ func TestPayment_Cancel(t *testing.T) {
 	}
 }
 
+func TestPayment_Authorize(t *testing.T) {
+	tests := []struct {
+		name          string
+		initialStatus PaymentStatus
+		expiresIn     time.Duration
+		wantErr       bool
+		errMsg        string
+	}{
+		{
+			name:          "authorize from pending",
+			initialStatus: PaymentStatusPending,
+			expiresIn:     time.Hour,
+			wantErr:       false,
+		},
+		{
+			name:          "authorize with past expiry",
+			initialStatus: PaymentStatusPending,
+			expiresIn:     -time.Minute,
+			wantErr:       true,
+			errMsg:        "authorization expiry must be in the future",
+		},
+		{
+			name:          "authorize from processing",
+			initialStatus: PaymentStatusProcessing,
+			expiresIn:     time.Hour,
+			wantErr:       true,
+			errMsg:        "cannot authorize payment in processing status",
+		},
+		{
+			name:          "authorize twice",
+			initialStatus: PaymentStatusAuthorized,
+			expiresIn:     time.Hour,
+			wantErr:       true,
+			errMsg:        "cannot authorize payment in authorized status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, _ := NewAmount(100.0, "USD")
+			payment := NewPayment(amount, "test payment")
+			payment.status = tt.initialStatus
+			oldUpdatedAt := payment.updatedAt
+			expiresAt := time.Now().Add(tt.expiresIn)
+
+			time.Sleep(1 * time.Millisecond)
+
+			err := payment.Authorize(expiresAt)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+					return
+				}
+				if err.Error() != tt.errMsg {
+					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if payment.Status() != PaymentStatusAuthorized {
+				t.Errorf("expected status %v, got %v", PaymentStatusAuthorized, payment.Status())
+			}
+
+			if !payment.AuthorizationExpiresAt().Equal(expiresAt) {
+				t.Errorf("expected expiry %v, got %v", expiresAt, payment.AuthorizationExpiresAt())
+			}
+
+			if !payment.UpdatedAt().After(oldUpdatedAt) {
+				t.Errorf("expected updated_at to be updated")
+			}
+		})
+	}
+}
+
+func TestPayment_Capture(t *testing.T) {
+	tests := []struct {
+		name            string
+		initialStatus   PaymentStatus
+		expired         bool
+		capture         string
+		currency        string
+		wantErr         bool
+		errMsg          string
+		expectedCapture string
+	}{
+		{
+			name:            "full capture",
+			initialStatus:   PaymentStatusAuthorized,
+			capture:         "100.00",
+			expectedCapture: "100.00",
+		},
+		{
+			name:            "partial capture",
+			initialStatus:   PaymentStatusAuthorized,
+			capture:         "60.25",
+			expectedCapture: "60.25",
+		},
+		{
+			name:          "over capture",
+			initialStatus: PaymentStatusAuthorized,
+			capture:       "100.01",
+			wantErr:       true,
+			errMsg:        "capture of 100.01 USD exceeds authorized amount 100.00 USD",
+		},
+		{
+			name:          "zero capture",
+			initialStatus: PaymentStatusAuthorized,
+			capture:       "0",
+			wantErr:       true,
+			errMsg:        "capture amount must be greater than zero",
+		},
+		{
+			name:          "currency mismatch",
+			initialStatus: PaymentStatusAuthorized,
+			capture:       "10.00",
+			currency:      "EUR",
+			wantErr:       true,
+			errMsg:        "currency mismatch: EUR and USD",
+		},
+		{
+			name:          "expired authorization",
+			initialStatus: PaymentStatusAuthorized,
+			expired:       true,
+			capture:       "10.00",
+			wantErr:       true,
+			errMsg:        "authorization has expired",
+		},
+		{
+			name:          "capture from pending",
+			initialStatus: PaymentStatusPending,
+			capture:       "10.00",
+			wantErr:       true,
+			errMsg:        "cannot capture payment in pending status",
+		},
+		{
+			name:          "capture from voided",
+			initialStatus: PaymentStatusVoided,
+			capture:       "10.00",
+			wantErr:       true,
+			errMsg:        "cannot capture payment in voided status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, _ := NewAmount(100.0, "USD")
+			payment := NewPayment(amount, "test payment")
+			payment.status = tt.initialStatus
+			payment.authExpiry = time.Now().Add(time.Hour)
+			if tt.expired {
+				payment.authExpiry = time.Now().Add(-time.Second)
+			}
+
+			currency := tt.currency
+			if currency == "" {
+				currency = "USD"
+			}
+
+			err := payment.Capture(mustParseAmount(tt.capture, currency))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+					return
+				}
+				if err.Error() != tt.errMsg {
+					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if payment.Status() != PaymentStatusCompleted {
+				t.Errorf("expected status %v, got %v", PaymentStatusCompleted, payment.Status())
+			}
+
+			if payment.CapturedAmount().Decimal() != tt.expectedCapture {
+				t.Errorf("expected captured amount %s, got %s", tt.expectedCapture, payment.CapturedAmount().Decimal())
+			}
+
+			if payment.RefundableAmount().Decimal() != tt.expectedCapture {
+				t.Errorf("expected refundable amount %s, got %s", tt.expectedCapture, payment.RefundableAmount().Decimal())
+			}
+		})
+	}
+}
+
+func TestPayment_Void(t *testing.T) {
+	tests := []struct {
+		name          string
+		initialStatus PaymentStatus
+		wantErr       bool
+		errMsg        string
+	}{
+		{
+			name:          "void from authorized",
+			initialStatus: PaymentStatusAuthorized,
+			wantErr:       false,
+		},
+		{
+			name:          "void from pending",
+			initialStatus: PaymentStatusPending,
+			wantErr:       true,
+			errMsg:        "cannot void payment in pending status",
+		},
+		{
+			name:          "void from completed",
+			initialStatus: PaymentStatusCompleted,
+			wantErr:       true,
+			errMsg:        "cannot void payment in completed status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, _ := NewAmount(100.0, "USD")
+			payment := NewPayment(amount, "test payment")
+			payment.status = tt.initialStatus
+
+			err := payment.Void()
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+					return
+				}
+				if err.Error() != tt.errMsg {
+					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if payment.Status() != PaymentStatusVoided {
+				t.Errorf("expected status %v, got %v", PaymentStatusVoided, payment.Status())
+			}
+		})
+	}
+}
+
+func TestPayment_Refund(t *testing.T) {
+	tests := []struct {
+		name           string
+		initialStatus  PaymentStatus
+		refunds        []string
+		currency       string
+		noReason       bool
+		wantErr        bool
+		errMsg         string
+		expectedStatus PaymentStatus
+		expectedTotal  string
+	}{
+		{
+			name:           "full refund",
+			initialStatus:  PaymentStatusCompleted,
+			refunds:        []string{"100.00"},
+			expectedStatus: PaymentStatusRefunded,
+			expectedTotal:  "100.00",
+		},
+		{
+			name:           "single partial refund",
+			initialStatus:  PaymentStatusCompleted,
+			refunds:        []string{"30.00"},
+			expectedStatus: PaymentStatusPartiallyRefunded,
+			expectedTotal:  "30.00",
+		},
+		{
+			name:           "partial refunds adding up to full amount",
+			initialStatus:  PaymentStatusCompleted,
+			refunds:        []string{"30.00", "45.50", "24.50"},
+			expectedStatus: PaymentStatusRefunded,
+			expectedTotal:  "100.00",
+		},
+		{
+			name:          "partial refunds exceeding amount",
+			initialStatus: PaymentStatusCompleted,
+			refunds:       []string{"60.00", "40.01"},
+			wantErr:       true,
+			errMsg:        "refund of 40.01 USD exceeds refundable amount 40.00 USD",
+		},
+		{
+			name:          "refund more than amount",
+			initialStatus: PaymentStatusCompleted,
+			refunds:       []string{"100.01"},
+			wantErr:       true,
+			errMsg:        "refund of 100.01 USD exceeds refundable amount 100.00 USD",
+		},
+		{
+			name:          "zero refund",
+			initialStatus: PaymentStatusCompleted,
+			refunds:       []string{"0"},
+			wantErr:       true,
+			errMsg:        "refund amount must be greater than zero",
+		},
+		{
+			name:          "missing reason",
+			initialStatus: PaymentStatusCompleted,
+			refunds:       []string{"10.00"},
+			noReason:      true,
+			wantErr:       true,
+			errMsg:        "refund reason cannot be empty",
+		},
+		{
+			name:          "currency mismatch",
+			initialStatus: PaymentStatusCompleted,
+			refunds:       []string{"10.00"},
+			currency:      "EUR",
+			wantErr:       true,
+			errMsg:        "currency mismatch: EUR and USD",
+		},
+		{
+			name:          "refund from processing",
+			initialStatus: PaymentStatusProcessing,
+			refunds:       []string{"10.00"},
+			wantErr:       true,
+			errMsg:        "cannot refund payment in processing status",
+		},
+		{
+			name:          "refund from fully refunded",
+			initialStatus: PaymentStatusRefunded,
+			refunds:       []string{"10.00"},
+			wantErr:       true,
+			errMsg:        "cannot refund payment in refunded status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, _ := NewAmount(100.0, "USD")
+			payment := NewPayment(amount, "test payment")
+			payment.status = tt.initialStatus
+			oldUpdatedAt := payment.updatedAt
+
+			currency := tt.currency
+			if currency == "" {
+				currency = "USD"
+			}
+			reason := "customer request"
+			if tt.noReason {
+				reason = ""
+			}
+
+			time.Sleep(1 * time.Millisecond)
+
+			var err error
+			for _, value := range tt.refunds {
+				var refund Refund
+				refund, err = payment.Refund(mustParseAmount(value, currency), reason)
+				if err != nil {
+					break
+				}
+				if refund.ID().String() == "" {
+					t.Error("expected refund ID to be set")
+				}
+				if refund.Reason() != reason {
+					t.Errorf("expected reason %q, got %q", reason, refund.Reason())
+				}
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+					return
+				}
+				if err.Error() != tt.errMsg {
+					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if payment.Status() != tt.expectedStatus {
+				t.Errorf("expected status %v, got %v", tt.expectedStatus, payment.Status())
+			}
+
+			if refunded, _ := payment.RefundedAmount(); refunded.Decimal() != tt.expectedTotal {
+				t.Errorf("expected refunded amount %s, got %s", tt.expectedTotal, refunded.Decimal())
+			}
+
+			if len(payment.Refunds()) != len(tt.refunds) {
+				t.Errorf("expected %d refunds, got %d", len(tt.refunds), len(payment.Refunds()))
+			}
+
+			if !payment.UpdatedAt().After(oldUpdatedAt) {
+				t.Errorf("expected updated_at to be updated")
+			}
+		})
+	}
+}
+
+func TestPayment_RefundedAmountOverflow(t *testing.T) {
+	amount, _ := NewAmount(100, "USD")
+	p := NewPayment(amount, "overflow")
+	p.refunds = []Refund{
+		{amount: Amount{minor: math.MaxInt64, currency: "USD"}},
+		{amount: Amount{minor: 1, currency: "USD"}},
+	}
+
+	if _, err := p.RefundedAmount(); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount, got %v", err)
+	}
+}
+
 func TestNewPayment(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -475,12 +857,8 @@ func TestNewPayment(t *testing.T) {
 				t.Error("expected payment ID to be set")
 			}
 
-			if payment.Amount().Value() != tt.amount.Value() {
-				t.Errorf("expected amount value %f, got %f", tt.amount.Value(), payment.Amount().Value())
-			}
-
-			if payment.Amount().Currency() != tt.amount.Currency() {
-				t.Errorf("expected currency %q, got %q", tt.amount.Currency(), payment.Amount().Currency())
+			if !payment.Amount().Equal(tt.amount) {
+				t.Errorf("expected amount %s, got %s", tt.amount, payment.Amount())
 			}
 
 			if payment.Description() != tt.description {
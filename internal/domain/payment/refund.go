@@ -0,0 +1,64 @@
+package payment
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefundID struct {
+	value string
+}
+
+func NewRefundID() RefundID {
+	return RefundID{value: uuid.New().String()}
+}
+
+func RefundIDFromString(id string) RefundID {
+	return RefundID{value: id}
+}
+
+func (id RefundID) String() string {
+	return id.value
+}
+
+func (id RefundID) MarshalText() ([]byte, error) {
+	return []byte(id.value), nil
+}
+
+func (id *RefundID) UnmarshalText(text []byte) error {
+	id.value = string(text)
+	return nil
+}
+
+type Refund struct {
+	id        RefundID
+	amount    Amount
+	reason    string
+	createdAt time.Time
+}
+
+func newRefund(amount Amount, reason string) Refund {
+	return Refund{
+		id:        NewRefundID(),
+		amount:    amount,
+		reason:    reason,
+		createdAt: time.Now(),
+	}
+}
+
+func (r Refund) ID() RefundID {
+	return r.id
+}
+
+func (r Refund) Amount() Amount {
+	return r.amount
+}
+
+func (r Refund) Reason() string {
+	return r.reason
+}
+
+func (r Refund) CreatedAt() time.Time {
+	return r.createdAt
+}
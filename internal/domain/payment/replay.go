@@ -0,0 +1,94 @@
+package payment
+
+import "fmt"
+
+// Replay rebuilds a payment from its event stream. from is a snapshot taken
+// part way through the stream, or nil when events start with PaymentCreated.
+// The payment's version is the snapshot's version plus len(events), so an
+// event-sourced Repository can use the stream length as the version.
+//
+// Streams that do not describe a valid history, such as a transition whose
+// From is not the replayed status, are rejected with ErrInvalidEventStream.
+func Replay(from *Snapshot, events []DomainEvent) (*Payment, error) {
+	p := &Payment{}
+	if from != nil {
+		restored, err := FromSnapshot(*from)
+		if err != nil {
+			return nil, err
+		}
+		p = restored
+	}
+
+	for _, event := range events {
+		if err := p.when(event); err != nil {
+			return nil, fmt.Errorf("%w: event %d (%s): %w", ErrInvalidEventStream, p.version+1, event.EventType(), err)
+		}
+		p.version++
+	}
+
+	if p.id.value == "" {
+		return nil, fmt.Errorf("%w: stream is empty", ErrInvalidEventStream)
+	}
+
+	return p, nil
+}
+
+// when applies a recorded event to the payment without recording it again.
+func (p *Payment) when(event DomainEvent) error {
+	header := event.Header()
+	if created, ok := event.(PaymentCreated); ok {
+		if p.id.value != "" {
+			return fmt.Errorf("payment %s already exists", p.id)
+		}
+		p.id = header.PaymentID
+		p.amount = created.Amount
+		p.description = created.Description
+		p.status = PaymentStatusPending
+		p.createdAt = header.OccurredAt
+		p.updatedAt = header.OccurredAt
+		return nil
+	}
+
+	if p.id.value == "" {
+		return fmt.Errorf("stream must start with %s", PaymentCreated{}.EventType())
+	}
+	if header.PaymentID != p.id {
+		return fmt.Errorf("event belongs to payment %s", header.PaymentID)
+	}
+
+	var change StatusChange
+	switch e := event.(type) {
+	case PaymentProcessed:
+		change = e.StatusChange
+	case PaymentCompleted:
+		change = e.StatusChange
+	case PaymentFailed:
+		change = e.StatusChange
+	case PaymentCancelled:
+		change = e.StatusChange
+	case PaymentVoided:
+		change = e.StatusChange
+	case PaymentAuthorized:
+		change = e.StatusChange
+		p.authExpiry = e.ExpiresAt
+	case PaymentCaptured:
+		change = e.StatusChange
+		p.captured = e.Amount
+	case PaymentRefunded:
+		change = e.StatusChange
+		p.refunds = append(p.refunds, Refund{
+			id:        e.RefundID,
+			amount:    e.Amount,
+			reason:    e.Reason,
+			createdAt: header.OccurredAt,
+		})
+	default:
+		return fmt.Errorf("unsupported event %T", event)
+	}
+
+	if change.From != p.status {
+		return fmt.Errorf("transition from %s but payment is %s", change.From, p.status)
+	}
+	p.apply(change.To, header.OccurredAt)
+	return nil
+}
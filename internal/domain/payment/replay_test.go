@@ -0,0 +1,116 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// history returns a payment taken through every kind of event and the
+// events it recorded, each encoded and decoded as a store would.
+func history(t *testing.T) (*Payment, []DomainEvent) {
+	t.Helper()
+
+	payment := NewPayment(mustCreateAmount(100.0, "USD"), "test payment")
+	payment.Authorize(time.Now().Add(time.Hour))
+	payment.Capture(mustCreateAmount(80.0, "USD"))
+	payment.Refund(mustCreateAmount(30.0, "USD"), "damaged")
+	payment.Refund(mustCreateAmount(50.0, "USD"), "returned")
+
+	var events []DomainEvent
+	for _, event := range payment.PullEvents() {
+		data, err := json.Marshal(event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		decoded, err := DecodeEvent(event.EventType(), data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		events = append(events, decoded)
+	}
+	return payment, events
+}
+
+func sameState(t *testing.T, want, got *Payment) {
+	t.Helper()
+
+	wantJSON, _ := json.Marshal(want.ToSnapshot())
+	gotJSON, _ := json.Marshal(got.ToSnapshot())
+	if string(wantJSON) != string(gotJSON) {
+		t.Errorf("expected %s, got %s", wantJSON, gotJSON)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	original, events := history(t)
+	original.SetVersion(int64(len(events)))
+
+	replayed, err := Replay(nil, events)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sameState(t, original, replayed)
+	if len(replayed.PullEvents()) != 0 {
+		t.Error("expected replay to record no new events")
+	}
+
+	partial, err := Replay(nil, events[:2])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snapshot := partial.ToSnapshot()
+	resumed, err := Replay(&snapshot, events[2:])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sameState(t, original, resumed)
+}
+
+func TestReplay_InvalidStreams(t *testing.T) {
+	_, events := history(t)
+	other := NewPayment(mustCreateAmount(10.0, "USD"), "other").PullEvents()
+
+	tests := []struct {
+		name   string
+		events []DomainEvent
+	}{
+		{name: "empty", events: nil},
+		{name: "missing creation", events: events[1:]},
+		{name: "created twice", events: []DomainEvent{events[0], events[0]}},
+		{name: "out of order", events: []DomainEvent{events[0], events[2]}},
+		{name: "foreign event", events: []DomainEvent{other[0], events[1]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Replay(nil, tt.events); !errors.Is(err, ErrInvalidEventStream) {
+				t.Errorf("expected ErrInvalidEventStream, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDecodeEvent_UnknownType(t *testing.T) {
+	if _, err := DecodeEvent("payment.teleported", []byte("{}")); err == nil {
+		t.Error("expected error for unknown event type")
+	}
+}
+
+func TestPayment_UncommittedEvents(t *testing.T) {
+	payment := NewPayment(mustCreateAmount(100.0, "USD"), "test payment")
+	payment.MarkEventsCommitted()
+	payment.Process()
+
+	uncommitted := payment.UncommittedEvents()
+	if len(uncommitted) != 1 || uncommitted[0].EventType() != "payment.processed" {
+		t.Fatalf("expected only payment.processed, got %v", uncommitted)
+	}
+	if pulled := payment.PullEvents(); len(pulled) != 2 {
+		t.Errorf("expected PullEvents to still return both events, got %d", len(pulled))
+	}
+	if len(payment.UncommittedEvents()) != 0 {
+		t.Error("expected no uncommitted events after PullEvents")
+	}
+}
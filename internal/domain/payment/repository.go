@@ -2,10 +2,20 @@ package payment
 
 import "context"
 
+// Repository persists payments. Save stores a new payment at version 1; it
+// fails with ErrConcurrentModification if a payment with the same ID is
+// already stored, and never overwrites one. Update is a compare-and-swap on
+// Version: it fails with ErrConcurrentModification when the stored payment
+// has moved on since it was loaded, and bumps the version on success.
+//
+// FindByFilter orders, resumes and limits its results as the filter's SortBy,
+// Sort, Cursor and Limit say; PaymentFilter.Paginate does this for adapters
+// that filter in memory. FindAll returns payments in no particular order.
 type Repository interface {
 	Save(ctx context.Context, payment *Payment) error
 	FindByID(ctx context.Context, id PaymentID) (*Payment, error)
 	FindAll(ctx context.Context) ([]*Payment, error)
+	FindByFilter(ctx context.Context, filter PaymentFilter) ([]*Payment, error)
 	Update(ctx context.Context, payment *Payment) error
 	Delete(ctx context.Context, id PaymentID) error
 }
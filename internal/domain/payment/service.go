@@ -3,25 +3,58 @@ package payment
 import (
 	"context"
 	"errors"
+	"time"
 )
 
+const defaultPageSize = 100
+
 type Service struct {
-	repository Repository
+	repository      Repository
+	dispatcher      EventDispatcher
+	conflictRetry   int
+	conflictBackoff time.Duration
+}
+
+type ServiceOption func(*Service)
+
+// WithConflictRetry makes commands reload the payment and try again, up to
+// retries more times, when Update reports ErrConcurrentModification.
+func WithConflictRetry(retries int, backoff time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.conflictRetry = retries
+		s.conflictBackoff = backoff
+	}
 }
 
-func NewService(repository Repository) *Service {
-	return &Service{
+// WithEventDispatcher hands the events raised by each command to dispatcher
+// once the payment has been persisted.
+func WithEventDispatcher(dispatcher EventDispatcher) ServiceOption {
+	return func(s *Service) {
+		s.dispatcher = dispatcher
+	}
+}
+
+func NewService(repository Repository, opts ...ServiceOption) *Service {
+	s := &Service{
 		repository: repository,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Service) CreatePayment(ctx context.Context, amount Amount, description string) (*Payment, error) {
-	payment := NewPayment(amount, description)
+	payment := newPayment(amount, description, CausationIDFromContext(ctx))
 
 	if err := s.repository.Save(ctx, payment); err != nil {
 		return nil, err
 	}
 
+	if err := s.dispatch(ctx, payment); err != nil {
+		return nil, err
+	}
+
 	return payment, nil
 }
 
@@ -33,70 +66,126 @@ func (s *Service) GetAllPayments(ctx context.Context) ([]*Payment, error) {
 	return s.repository.FindAll(ctx)
 }
 
-func (s *Service) ProcessPayment(ctx context.Context, id PaymentID) error {
-	payment, err := s.repository.FindByID(ctx, id)
-	if err != nil {
-		return err
+// ListPayments returns up to filter.Limit payments matching filter, or
+// defaultPageSize if it is zero, with a cursor to the next page if any.
+func (s *Service) ListPayments(ctx context.Context, filter PaymentFilter) (PaymentPage, error) {
+	if err := filter.Validate(); err != nil {
+		return PaymentPage{}, err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
 	}
 
-	if payment == nil {
-		return errors.New("payment not found")
+	// One payment more than asked for tells whether there is a next page.
+	limit := filter.Limit
+	filter.Limit++
+	payments, err := s.repository.FindByFilter(ctx, filter)
+	if err != nil {
+		return PaymentPage{}, err
 	}
 
-	if err := payment.Process(); err != nil {
-		return err
+	if len(payments) <= limit {
+		return PaymentPage{Payments: payments}, nil
 	}
+	payments = payments[:limit]
+	return PaymentPage{Payments: payments, NextCursor: CursorAfter(payments[limit-1], filter.sortField())}, nil
+}
 
-	return s.repository.Update(ctx, payment)
+func (s *Service) ProcessPayment(ctx context.Context, id PaymentID) error {
+	return s.Execute(ctx, id, (*Payment).Process)
 }
 
 func (s *Service) CompletePayment(ctx context.Context, id PaymentID) error {
-	payment, err := s.repository.FindByID(ctx, id)
-	if err != nil {
-		return err
-	}
+	return s.Execute(ctx, id, (*Payment).Complete)
+}
 
-	if payment == nil {
-		return errors.New("payment not found")
-	}
+func (s *Service) FailPayment(ctx context.Context, id PaymentID) error {
+	return s.Execute(ctx, id, (*Payment).Fail)
+}
 
-	if err := payment.Complete(); err != nil {
-		return err
-	}
+func (s *Service) CancelPayment(ctx context.Context, id PaymentID) error {
+	return s.Execute(ctx, id, (*Payment).Cancel)
+}
 
-	return s.repository.Update(ctx, payment)
+func (s *Service) AuthorizePayment(ctx context.Context, id PaymentID, expiresAt time.Time) error {
+	return s.Execute(ctx, id, func(payment *Payment) error {
+		return payment.Authorize(expiresAt)
+	})
 }
 
-func (s *Service) FailPayment(ctx context.Context, id PaymentID) error {
-	payment, err := s.repository.FindByID(ctx, id)
-	if err != nil {
-		return err
-	}
+func (s *Service) CapturePayment(ctx context.Context, id PaymentID, amount Amount) error {
+	return s.Execute(ctx, id, func(payment *Payment) error {
+		return payment.Capture(amount)
+	})
+}
 
-	if payment == nil {
-		return errors.New("payment not found")
-	}
+func (s *Service) VoidPayment(ctx context.Context, id PaymentID) error {
+	return s.Execute(ctx, id, (*Payment).Void)
+}
 
-	if err := payment.Fail(); err != nil {
+func (s *Service) RefundPayment(ctx context.Context, id PaymentID, amount Amount, reason string) (Refund, error) {
+	var refund Refund
+	err := s.Execute(ctx, id, func(payment *Payment) error {
+		var err error
+		refund, err = payment.Refund(amount, reason)
 		return err
+	})
+	if err != nil {
+		return Refund{}, err
 	}
 
-	return s.repository.Update(ctx, payment)
+	return refund, nil
 }
 
-func (s *Service) CancelPayment(ctx context.Context, id PaymentID) error {
-	payment, err := s.repository.FindByID(ctx, id)
-	if err != nil {
-		return err
+// Execute loads the payment, applies command, persists the result and then
+// dispatches the events command raised. When the service was built
+// WithConflictRetry, a version conflict on Update reloads the payment and runs
+// command again against the fresh state.
+func (s *Service) Execute(ctx context.Context, id PaymentID, command func(*Payment) error) error {
+	for attempt := 0; ; attempt++ {
+		payment, err := s.repository.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if payment == nil {
+			return ErrPaymentNotFound
+		}
+
+		payment.causationID = CausationIDFromContext(ctx)
+		if err := command(payment); err != nil {
+			return err
+		}
+
+		err = s.repository.Update(ctx, payment)
+		if err == nil {
+			return s.dispatch(ctx, payment)
+		}
+		if !errors.Is(err, ErrConcurrentModification) || attempt >= s.conflictRetry {
+			return err
+		}
+
+		if s.conflictBackoff > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(s.conflictBackoff):
+			}
+		}
 	}
+}
 
-	if payment == nil {
-		return errors.New("payment not found")
+// dispatch leaves events on the payment when no dispatcher is configured, so
+// callers holding the payment can still pull them.
+func (s *Service) dispatch(ctx context.Context, payment *Payment) error {
+	if s.dispatcher == nil {
+		return nil
 	}
 
-	if err := payment.Cancel(); err != nil {
-		return err
+	events := payment.PullEvents()
+	if len(events) == 0 {
+		return nil
 	}
 
-	return s.repository.Update(ctx, payment)
+	return s.dispatcher.Dispatch(ctx, events)
 }
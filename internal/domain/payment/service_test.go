@@ -0,0 +1,106 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// conflictingRepository holds a single payment and reports a version conflict
+// on the first conflicts calls to Update.
+type conflictingRepository struct {
+	payment   *Payment
+	conflicts int
+	updates   int
+}
+
+func (r *conflictingRepository) Save(ctx context.Context, p *Payment) error {
+	r.payment = p
+	return nil
+}
+
+func (r *conflictingRepository) FindByID(ctx context.Context, id PaymentID) (*Payment, error) {
+	if r.payment == nil || r.payment.ID() != id {
+		return nil, ErrPaymentNotFound
+	}
+	loaded := *r.payment
+	return &loaded, nil
+}
+
+func (r *conflictingRepository) FindAll(ctx context.Context) ([]*Payment, error) {
+	return []*Payment{r.payment}, nil
+}
+
+func (r *conflictingRepository) FindByFilter(ctx context.Context, filter PaymentFilter) ([]*Payment, error) {
+	if r.payment == nil || !filter.Matches(r.payment) {
+		return nil, nil
+	}
+	return filter.Paginate([]*Payment{r.payment})
+}
+
+func (r *conflictingRepository) Update(ctx context.Context, p *Payment) error {
+	r.updates++
+	if r.updates <= r.conflicts {
+		return ErrConcurrentModification
+	}
+	r.payment = p
+	return nil
+}
+
+func (r *conflictingRepository) Delete(ctx context.Context, id PaymentID) error {
+	r.payment = nil
+	return nil
+}
+
+func TestService_ConflictRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		conflicts   int
+		retries     int
+		wantErr     error
+		wantUpdates int
+	}{
+		{name: "no retry by default", conflicts: 1, retries: 0, wantErr: ErrConcurrentModification, wantUpdates: 1},
+		{name: "retry succeeds", conflicts: 2, retries: 2, wantUpdates: 3},
+		{name: "retries exhausted", conflicts: 3, retries: 2, wantErr: ErrConcurrentModification, wantUpdates: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &conflictingRepository{conflicts: tt.conflicts}
+			service := NewService(repo, WithConflictRetry(tt.retries, 0))
+			ctx := context.Background()
+
+			payment, err := service.CreatePayment(ctx, mustCreateAmount(100.0, "USD"), "test payment")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = service.ProcessPayment(ctx, payment.ID())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if repo.updates != tt.wantUpdates {
+				t.Errorf("expected %d updates, got %d", tt.wantUpdates, repo.updates)
+			}
+			if tt.wantErr == nil && repo.payment.Status() != PaymentStatusProcessing {
+				t.Errorf("expected status %v, got %v", PaymentStatusProcessing, repo.payment.Status())
+			}
+		})
+	}
+}
+
+func TestService_ConflictRetryHonoursContext(t *testing.T) {
+	repo := &conflictingRepository{conflicts: 1}
+	service := NewService(repo, WithConflictRetry(1, time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	payment, _ := service.CreatePayment(ctx, mustCreateAmount(100.0, "USD"), "test payment")
+	cancel()
+
+	err := service.ProcessPayment(ctx, payment.ID())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package payment
+
+import (
+	"fmt"
+	"time"
+)
+
+// Snapshot is a plain copy of a Payment's state for persistence adapters and
+// the wire. It shares no memory with the payment it was taken from, and its
+// JSON field names are part of the storage format: rename with care.
+type Snapshot struct {
+	ID                     string           `json:"id"`
+	Amount                 int64            `json:"amount_minor"`
+	Currency               string           `json:"currency"`
+	Status                 string           `json:"status"`
+	Description            string           `json:"description"`
+	Captured               *int64           `json:"captured_minor,omitempty"`
+	Refunds                []RefundSnapshot `json:"refunds,omitempty"`
+	AuthorizationExpiresAt time.Time        `json:"authorization_expires_at,omitzero"`
+	CreatedAt              time.Time        `json:"created_at"`
+	UpdatedAt              time.Time        `json:"updated_at"`
+	Version                int64            `json:"version"`
+}
+
+type RefundSnapshot struct {
+	ID        string    `json:"id"`
+	Amount    int64     `json:"amount_minor"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (p *Payment) ToSnapshot() Snapshot {
+	s := Snapshot{
+		ID:                     p.id.String(),
+		Amount:                 p.amount.minor,
+		Currency:               p.amount.currency,
+		Status:                 p.status.String(),
+		Description:            p.description,
+		AuthorizationExpiresAt: p.authExpiry,
+		CreatedAt:              p.createdAt,
+		UpdatedAt:              p.updatedAt,
+		Version:                p.version,
+	}
+	if p.captured.currency != "" {
+		captured := p.captured.minor
+		s.Captured = &captured
+	}
+	for _, r := range p.refunds {
+		s.Refunds = append(s.Refunds, RefundSnapshot{
+			ID:        r.id.String(),
+			Amount:    r.amount.minor,
+			Reason:    r.reason,
+			CreatedAt: r.createdAt,
+		})
+	}
+	return s
+}
+
+// FromSnapshot rebuilds a Payment from state previously taken with ToSnapshot.
+// Snapshots that no sequence of commands could have produced are rejected
+// with ErrInvalidSnapshot.
+func FromSnapshot(s Snapshot) (*Payment, error) {
+	if s.ID == "" {
+		return nil, invalidSnapshot("id is required")
+	}
+
+	amount, err := NewAmountFromMinor(s.Amount, s.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+
+	status, err := ParsePaymentStatus(s.Status)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+
+	if s.CreatedAt.IsZero() {
+		return nil, invalidSnapshot("created_at is required")
+	}
+	if s.UpdatedAt.Before(s.CreatedAt) {
+		return nil, invalidSnapshot("updated_at is before created_at")
+	}
+	if s.Version < 0 {
+		return nil, invalidSnapshot("version cannot be negative")
+	}
+	if status == PaymentStatusAuthorized && s.AuthorizationExpiresAt.IsZero() {
+		return nil, invalidSnapshot("authorized payment has no expiry")
+	}
+
+	p := &Payment{
+		id:          PaymentIDFromString(s.ID),
+		amount:      amount,
+		status:      status,
+		description: s.Description,
+		authExpiry:  s.AuthorizationExpiresAt,
+		createdAt:   s.CreatedAt,
+		updatedAt:   s.UpdatedAt,
+		version:     s.Version,
+	}
+
+	if s.Captured != nil {
+		if !isSettled(status) {
+			return nil, invalidSnapshot("%s payment cannot have a captured amount", status)
+		}
+		if *s.Captured <= 0 || *s.Captured > amount.minor {
+			return nil, invalidSnapshot("captured amount must be between 0 and %s", amount)
+		}
+		p.captured = Amount{minor: *s.Captured, currency: amount.currency}
+	}
+
+	for _, r := range s.Refunds {
+		if r.ID == "" || r.Reason == "" || r.Amount <= 0 || r.CreatedAt.IsZero() {
+			return nil, invalidSnapshot("refund %q is incomplete", r.ID)
+		}
+		p.refunds = append(p.refunds, Refund{
+			id:        RefundIDFromString(r.ID),
+			amount:    Amount{minor: r.Amount, currency: amount.currency},
+			reason:    r.Reason,
+			createdAt: r.CreatedAt,
+		})
+	}
+
+	if err := checkRefundTotals(p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func isSettled(status PaymentStatus) bool {
+	switch status {
+	case PaymentStatusCompleted, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkRefundTotals ties the refund-related statuses to the recorded refunds:
+// partially refunded needs some refundable amount left, refunded needs none.
+func checkRefundTotals(p *Payment) error {
+	if len(p.refunds) > 0 && p.status != PaymentStatusPartiallyRefunded && p.status != PaymentStatusRefunded {
+		return invalidSnapshot("%s payment cannot have refunds", p.status)
+	}
+
+	total, err := p.RefundedAmount()
+	if err != nil {
+		return invalidSnapshot("refunds cannot be totalled: %v", err)
+	}
+	refunded := total.minor
+	captured := p.CapturedAmount().minor
+	switch {
+	case refunded > captured:
+		return invalidSnapshot("refunds exceed captured amount %s", p.CapturedAmount())
+	case p.status == PaymentStatusPartiallyRefunded && (refunded == 0 || refunded == captured):
+		return invalidSnapshot("partially refunded payment must have a partial refund total")
+	case p.status == PaymentStatusRefunded && refunded != captured:
+		return invalidSnapshot("refunded payment must be refunded in full")
+	}
+	return nil
+}
+
+func invalidSnapshot(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSnapshot, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,153 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	payment := NewPayment(mustCreateAmount(100.0, "USD"), "test payment")
+	if err := payment.Authorize(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := payment.Capture(mustCreateAmount(80.0, "USD")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := payment.Refund(mustCreateAmount(30.0, "USD"), "damaged"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payment.SetVersion(4)
+
+	data, err := json.Marshal(payment.ToSnapshot())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded Snapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restored, err := FromSnapshot(decoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if restored.ID() != payment.ID() || restored.Status() != payment.Status() || restored.Version() != 4 {
+		t.Errorf("expected %s %v v4, got %s %v v%d", payment.ID(), payment.Status(), restored.ID(), restored.Status(), restored.Version())
+	}
+	if !restored.CapturedAmount().Equal(payment.CapturedAmount()) || !restored.RefundableAmount().Equal(payment.RefundableAmount()) {
+		t.Errorf("expected captured %s refundable %s, got %s and %s",
+			payment.CapturedAmount(), payment.RefundableAmount(), restored.CapturedAmount(), restored.RefundableAmount())
+	}
+	if !restored.CreatedAt().Equal(payment.CreatedAt()) || !restored.AuthorizationExpiresAt().Equal(payment.AuthorizationExpiresAt()) {
+		t.Error("expected timestamps to survive the round trip")
+	}
+	if !reflect.DeepEqual(restored.ToSnapshot(), decoded) {
+		t.Errorf("expected restored snapshot to equal decoded snapshot")
+	}
+}
+
+func TestSnapshot_JSONFields(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	snapshot := NewPayment(mustCreateAmount(12.5, "EUR"), "coffee").ToSnapshot()
+	snapshot.ID = "p-1"
+	snapshot.CreatedAt = created
+	snapshot.UpdatedAt = created
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"id":"p-1","amount_minor":1250,"currency":"EUR","status":"pending","description":"coffee",` +
+		`"created_at":"2024-01-02T03:04:05Z","updated_at":"2024-01-02T03:04:05Z","version":0}`
+	if string(data) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, data)
+	}
+}
+
+func TestFromSnapshot_Invariants(t *testing.T) {
+	now := time.Now()
+	valid := func() Snapshot {
+		return Snapshot{
+			ID:        "p-1",
+			Amount:    10000,
+			Currency:  "USD",
+			Status:    "completed",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+	captured := func(minor int64) *int64 { return &minor }
+	refund := func(minor int64) RefundSnapshot {
+		return RefundSnapshot{ID: "r-1", Amount: minor, Reason: "damaged", CreatedAt: now}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(s *Snapshot)
+		wantErr string
+	}{
+		{name: "valid", modify: func(s *Snapshot) {}},
+		{name: "missing id", modify: func(s *Snapshot) { s.ID = "" }, wantErr: "id is required"},
+		{name: "unknown currency", modify: func(s *Snapshot) { s.Currency = "XXY" }, wantErr: "unknown currency"},
+		{name: "negative amount", modify: func(s *Snapshot) { s.Amount = -1 }, wantErr: "cannot be negative"},
+		{name: "unknown status", modify: func(s *Snapshot) { s.Status = "lost" }, wantErr: "unknown payment status"},
+		{name: "missing created_at", modify: func(s *Snapshot) { s.CreatedAt = time.Time{} }, wantErr: "created_at is required"},
+		{name: "updated before created", modify: func(s *Snapshot) { s.UpdatedAt = now.Add(-time.Second) }, wantErr: "updated_at is before created_at"},
+		{name: "negative version", modify: func(s *Snapshot) { s.Version = -1 }, wantErr: "version cannot be negative"},
+		{name: "authorized without expiry", modify: func(s *Snapshot) { s.Status = "authorized" }, wantErr: "no expiry"},
+		{name: "captured while pending", modify: func(s *Snapshot) { s.Status = "pending"; s.Captured = captured(100) }, wantErr: "cannot have a captured amount"},
+		{name: "over-captured", modify: func(s *Snapshot) { s.Captured = captured(10001) }, wantErr: "captured amount must be"},
+		{name: "refund while completed", modify: func(s *Snapshot) { s.Refunds = []RefundSnapshot{refund(100)} }, wantErr: "cannot have refunds"},
+		{name: "refund without reason", modify: func(s *Snapshot) {
+			s.Status = "partially_refunded"
+			s.Refunds = []RefundSnapshot{{ID: "r-1", Amount: 100, CreatedAt: now}}
+		}, wantErr: "is incomplete"},
+		{name: "refunds exceed capture", modify: func(s *Snapshot) {
+			s.Status = "refunded"
+			s.Captured = captured(5000)
+			s.Refunds = []RefundSnapshot{refund(6000)}
+		}, wantErr: "exceed captured amount"},
+		{name: "partially refunded in full", modify: func(s *Snapshot) {
+			s.Status = "partially_refunded"
+			s.Refunds = []RefundSnapshot{refund(10000)}
+		}, wantErr: "partial refund total"},
+		{name: "refunded in part", modify: func(s *Snapshot) {
+			s.Status = "refunded"
+			s.Refunds = []RefundSnapshot{refund(100)}
+		}, wantErr: "refunded in full"},
+		{name: "refunded against capture", modify: func(s *Snapshot) {
+			s.Status = "refunded"
+			s.Captured = captured(5000)
+			s.Refunds = []RefundSnapshot{refund(5000)}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := valid()
+			tt.modify(&snapshot)
+
+			_, err := FromSnapshot(snapshot)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidSnapshot) {
+				t.Fatalf("expected ErrInvalidSnapshot, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package payment
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Event string
+
+const (
+	EventProcess       Event = "process"
+	EventComplete      Event = "complete"
+	EventFail          Event = "fail"
+	EventCancel        Event = "cancel"
+	EventAuthorize     Event = "authorize"
+	EventCapture       Event = "capture"
+	EventVoid          Event = "void"
+	EventRefund        Event = "refund"
+	EventPartialRefund Event = "partial_refund"
+)
+
+// Guard is an extra condition on a transition. A zero Guard always allows it.
+type Guard struct {
+	Name  string
+	Check func(p *Payment) error
+}
+
+type Transition struct {
+	From  PaymentStatus
+	Event Event
+	To    PaymentStatus
+	Guard Guard
+}
+
+// StateMachine evaluates a table of transitions. Several rows may share the
+// same From and Event; the first whose guard passes wins.
+type StateMachine struct {
+	initial     PaymentStatus
+	transitions []Transition
+}
+
+func NewStateMachine(initial PaymentStatus, transitions ...Transition) *StateMachine {
+	return &StateMachine{
+		initial:     initial,
+		transitions: transitions,
+	}
+}
+
+var authorizationNotExpired = Guard{
+	Name: "authorization not expired",
+	Check: func(p *Payment) error {
+		if time.Now().After(p.authExpiry) {
+			return ErrAuthorizationExpired
+		}
+		return nil
+	},
+}
+
+var lifecycle = NewStateMachine(PaymentStatusPending,
+	Transition{From: PaymentStatusPending, Event: EventProcess, To: PaymentStatusProcessing},
+	Transition{From: PaymentStatusPending, Event: EventAuthorize, To: PaymentStatusAuthorized},
+	Transition{From: PaymentStatusPending, Event: EventFail, To: PaymentStatusFailed},
+	Transition{From: PaymentStatusPending, Event: EventCancel, To: PaymentStatusCancelled},
+	Transition{From: PaymentStatusProcessing, Event: EventComplete, To: PaymentStatusCompleted},
+	Transition{From: PaymentStatusProcessing, Event: EventFail, To: PaymentStatusFailed},
+	Transition{From: PaymentStatusAuthorized, Event: EventCapture, To: PaymentStatusCompleted, Guard: authorizationNotExpired},
+	Transition{From: PaymentStatusAuthorized, Event: EventVoid, To: PaymentStatusVoided},
+	Transition{From: PaymentStatusAuthorized, Event: EventFail, To: PaymentStatusFailed},
+	Transition{From: PaymentStatusCompleted, Event: EventRefund, To: PaymentStatusRefunded},
+	Transition{From: PaymentStatusCompleted, Event: EventPartialRefund, To: PaymentStatusPartiallyRefunded},
+	Transition{From: PaymentStatusPartiallyRefunded, Event: EventRefund, To: PaymentStatusRefunded},
+	Transition{From: PaymentStatusPartiallyRefunded, Event: EventPartialRefund, To: PaymentStatusPartiallyRefunded},
+)
+
+// Lifecycle returns the state machine governing Payment status changes.
+func Lifecycle() *StateMachine {
+	return lifecycle
+}
+
+func (m *StateMachine) Transitions() []Transition {
+	transitions := make([]Transition, len(m.transitions))
+	copy(transitions, m.transitions)
+	return transitions
+}
+
+// CanTransition reports whether the table has any row for event out of from,
+// without evaluating guards.
+func (m *StateMachine) CanTransition(from PaymentStatus, event Event) bool {
+	for _, t := range m.transitions {
+		if t.From == from && t.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *StateMachine) AvailableEvents(from PaymentStatus) []Event {
+	var events []Event
+	seen := make(map[Event]bool)
+	for _, t := range m.transitions {
+		if t.From == from && !seen[t.Event] {
+			seen[t.Event] = true
+			events = append(events, t.Event)
+		}
+	}
+	return events
+}
+
+// Fire resolves the target status for event on p without mutating it.
+func (m *StateMachine) Fire(p *Payment, event Event) (PaymentStatus, error) {
+	var guardErr error
+	for _, t := range m.transitions {
+		if t.From != p.status || t.Event != event {
+			continue
+		}
+		if t.Guard.Check == nil {
+			return t.To, nil
+		}
+		err := t.Guard.Check(p)
+		if err == nil {
+			return t.To, nil
+		}
+		if guardErr == nil {
+			guardErr = err
+		}
+	}
+
+	if guardErr != nil {
+		return p.status, guardErr
+	}
+	return p.status, ErrInvalidTransition{From: p.status, Event: event}
+}
+
+func (m *StateMachine) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n")
+	fmt.Fprintf(&b, "    [*] --> %s\n", m.initial)
+	for _, t := range m.transitions {
+		fmt.Fprintf(&b, "    %s --> %s: %s\n", t.From, t.To, t.label())
+	}
+	for _, status := range m.terminalStatuses() {
+		fmt.Fprintf(&b, "    %s --> [*]\n", status)
+	}
+	return b.String()
+}
+
+func (m *StateMachine) Graphviz() string {
+	var b strings.Builder
+	b.WriteString("digraph payment {\n")
+	b.WriteString("    rankdir=LR;\n")
+	fmt.Fprintf(&b, "    %q [shape=circle];\n", m.initial.String())
+	for _, status := range m.terminalStatuses() {
+		fmt.Fprintf(&b, "    %q [shape=doublecircle];\n", status.String())
+	}
+	for _, t := range m.transitions {
+		fmt.Fprintf(&b, "    %q -> %q [label=%q];\n", t.From.String(), t.To.String(), t.label())
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (m *StateMachine) terminalStatuses() []PaymentStatus {
+	var terminal []PaymentStatus
+	seen := make(map[PaymentStatus]bool)
+	for _, t := range m.transitions {
+		if !seen[t.To] && len(m.AvailableEvents(t.To)) == 0 {
+			seen[t.To] = true
+			terminal = append(terminal, t.To)
+		}
+	}
+	return terminal
+}
+
+func (t Transition) label() string {
+	if t.Guard.Name == "" {
+		return string(t.Event)
+	}
+	return fmt.Sprintf("%s [%s]", t.Event, t.Guard.Name)
+}
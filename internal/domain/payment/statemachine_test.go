@@ -0,0 +1,160 @@
+package payment
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStateMachine_CanTransition(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  PaymentStatus
+		event Event
+		want  bool
+	}{
+		{name: "process pending", from: PaymentStatusPending, event: EventProcess, want: true},
+		{name: "complete processing", from: PaymentStatusProcessing, event: EventComplete, want: true},
+		{name: "capture authorized", from: PaymentStatusAuthorized, event: EventCapture, want: true},
+		{name: "partial refund partially refunded", from: PaymentStatusPartiallyRefunded, event: EventPartialRefund, want: true},
+		{name: "fail failed", from: PaymentStatusFailed, event: EventFail, want: false},
+		{name: "fail cancelled", from: PaymentStatusCancelled, event: EventFail, want: false},
+		{name: "cancel processing", from: PaymentStatusProcessing, event: EventCancel, want: false},
+		{name: "refund refunded", from: PaymentStatusRefunded, event: EventRefund, want: false},
+		{name: "unknown event", from: PaymentStatusPending, event: Event("explode"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lifecycle().CanTransition(tt.from, tt.event)
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStateMachine_AvailableEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		from PaymentStatus
+		want []Event
+	}{
+		{name: "pending", from: PaymentStatusPending, want: []Event{EventProcess, EventAuthorize, EventFail, EventCancel}},
+		{name: "processing", from: PaymentStatusProcessing, want: []Event{EventComplete, EventFail}},
+		{name: "authorized", from: PaymentStatusAuthorized, want: []Event{EventCapture, EventVoid, EventFail}},
+		{name: "completed", from: PaymentStatusCompleted, want: []Event{EventRefund, EventPartialRefund}},
+		{name: "refunded is terminal", from: PaymentStatusRefunded, want: nil},
+		{name: "failed is terminal", from: PaymentStatusFailed, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lifecycle().AvailableEvents(tt.from)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStateMachine_Fire(t *testing.T) {
+	amount, _ := NewAmount(100.0, "USD")
+
+	t.Run("invalid transition is typed", func(t *testing.T) {
+		payment := NewPayment(amount, "test payment")
+
+		_, err := Lifecycle().Fire(payment, EventComplete)
+
+		var invalid ErrInvalidTransition
+		if !errors.As(err, &invalid) {
+			t.Fatalf("expected ErrInvalidTransition, got %v", err)
+		}
+		if invalid.From != PaymentStatusPending || invalid.Event != EventComplete {
+			t.Errorf("unexpected error contents: %+v", invalid)
+		}
+		if !errors.Is(err, ErrInvalidTransition{}) {
+			t.Error("expected error to match any ErrInvalidTransition")
+		}
+		if errors.Is(err, ErrInvalidTransition{From: PaymentStatusPending, Event: EventVoid}) {
+			t.Error("expected error not to match a different transition")
+		}
+	})
+
+	t.Run("guard blocks transition", func(t *testing.T) {
+		payment := NewPayment(amount, "test payment")
+		payment.status = PaymentStatusAuthorized
+		payment.authExpiry = time.Now().Add(-time.Minute)
+
+		_, err := Lifecycle().Fire(payment, EventCapture)
+		if !errors.Is(err, ErrAuthorizationExpired) {
+			t.Errorf("expected guard error, got %v", err)
+		}
+		if payment.CanTransition(EventCapture) {
+			t.Error("expected capture to be unavailable")
+		}
+		if !reflect.DeepEqual(payment.AvailableEvents(), []Event{EventVoid, EventFail}) {
+			t.Errorf("unexpected available events %v", payment.AvailableEvents())
+		}
+	})
+
+	t.Run("first passing guard wins", func(t *testing.T) {
+		blocked := Guard{Name: "never", Check: func(*Payment) error { return errors.New("blocked") }}
+		machine := NewStateMachine(PaymentStatusPending,
+			Transition{From: PaymentStatusPending, Event: EventProcess, To: PaymentStatusFailed, Guard: blocked},
+			Transition{From: PaymentStatusPending, Event: EventProcess, To: PaymentStatusProcessing},
+		)
+
+		to, err := machine.Fire(NewPayment(amount, "test payment"), EventProcess)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if to != PaymentStatusProcessing {
+			t.Errorf("expected %v, got %v", PaymentStatusProcessing, to)
+		}
+	})
+
+	t.Run("fire does not mutate", func(t *testing.T) {
+		payment := NewPayment(amount, "test payment")
+
+		to, err := Lifecycle().Fire(payment, EventProcess)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if to != PaymentStatusProcessing || payment.Status() != PaymentStatusPending {
+			t.Errorf("expected pending payment and processing target, got %v and %v", payment.Status(), to)
+		}
+	})
+}
+
+func TestStateMachine_Diagrams(t *testing.T) {
+	mermaid := Lifecycle().Mermaid()
+	for _, line := range []string{
+		"stateDiagram-v2",
+		"[*] --> pending",
+		"pending --> processing: process",
+		"authorized --> completed: capture [authorization not expired]",
+		"voided --> [*]",
+	} {
+		if !strings.Contains(mermaid, line) {
+			t.Errorf("expected mermaid output to contain %q", line)
+		}
+	}
+
+	dot := Lifecycle().Graphviz()
+	for _, line := range []string{
+		"digraph payment {",
+		`"pending" [shape=circle];`,
+		`"refunded" [shape=doublecircle];`,
+		`"completed" -> "partially_refunded" [label="partial_refund"];`,
+	} {
+		if !strings.Contains(dot, line) {
+			t.Errorf("expected graphviz output to contain %q", line)
+		}
+	}
+	if strings.Contains(dot, `"partially_refunded" [shape=doublecircle]`) {
+		t.Error("expected partially_refunded not to be terminal")
+	}
+}
@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-ddd/internal/domain/audit"
+)
+
+// FileStore is an audit.ArchiveStore that writes each batch of archived
+// entries to its own gzip-compressed file of JSON lines, one
+// audit.EntrySnapshot per line. Locations are file names within the
+// store's directory.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Store writes entries to a new file, named after the sequences it spans and
+// the time it was written, and returns that name. The file appears complete
+// or not at all.
+func (s *FileStore) Store(ctx context.Context, entries []*audit.AuditEntry) (string, error) {
+	if len(entries) == 0 {
+		return "", errors.New("no entries to archive")
+	}
+
+	name := fmt.Sprintf("audit-%d-%d-%s.jsonl.gz",
+		entries[0].Sequence(), entries[len(entries)-1].Sequence(), time.Now().UTC().Format("20060102T150405.000000000Z"))
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp, entries); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func write(w io.Writer, entries []*audit.AuditEntry) error {
+	compressed := gzip.NewWriter(w)
+	encoder := json.NewEncoder(compressed)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry.ToSnapshot()); err != nil {
+			return err
+		}
+	}
+	return compressed.Close()
+}
+
+// Load reads back the entries archived at location.
+func (s *FileStore) Load(location string) ([]*audit.AuditEntry, error) {
+	if location != filepath.Base(location) {
+		return nil, fmt.Errorf("invalid archive location %q", location)
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, location))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	compressed, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", location, err)
+	}
+	defer compressed.Close()
+
+	var entries []*audit.AuditEntry
+	decoder := json.NewDecoder(compressed)
+	for {
+		var snapshot audit.EntrySnapshot
+		if err := decoder.Decode(&snapshot); err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read archive %s: %w", location, err)
+		}
+
+		entry, err := audit.FromSnapshot(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
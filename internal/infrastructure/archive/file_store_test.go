@@ -0,0 +1,62 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/infrastructure/repository"
+)
+
+func TestFileStore_ArchivesAndLoads(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "archive")
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	repo := repository.NewAuditMemoryRepository()
+	service := audit.NewService(repo)
+	for _, id := range []string{"payment-1", "payment-2"} {
+		service.RecordAction(ctx, audit.EntityTypePayment, id, audit.ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+	}
+
+	// A zero retention archives everything recorded so far.
+	archiver := audit.NewArchiver(service, repo, store, repository.NewLegalHoldMemoryRepository(),
+		audit.RetentionPolicy{Rules: []audit.RetentionRule{{EntityType: audit.EntityTypePayment}}})
+	result, err := archiver.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Archived != 2 {
+		t.Fatalf("expected 2 entries archived, got %+v", result)
+	}
+
+	entries, err := store.Load(result.Location)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].EntityID() != "payment-1" || entries[1].Sequence() != 2 {
+		t.Fatalf("expected both entries in sequence order, got %v", entries)
+	}
+	for _, entry := range entries {
+		if hash, _ := entry.ComputeHash(); hash != entry.Hash() {
+			t.Errorf("expected archived entry %d to keep its hash", entry.Sequence())
+		}
+	}
+
+	if err := service.Verify(ctx); err != nil {
+		t.Errorf("expected the live log to verify, got %v", err)
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 || files[0].Name() != result.Location {
+		t.Errorf("expected only the archive file, got %v", files)
+	}
+	if _, err := store.Load("../" + result.Location); err == nil {
+		t.Error("expected a location outside the store to be rejected")
+	}
+}
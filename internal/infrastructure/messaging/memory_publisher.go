@@ -0,0 +1,56 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+
+	"go-ddd/internal/application/outbox"
+)
+
+// MemoryPublisher records published messages in memory. It is meant for tests
+// and demos; FailNext lets a test make the next publishes fail.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []outbox.Message
+	failures []error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, message outbox.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.failures) > 0 {
+		err := p.failures[0]
+		p.failures = p.failures[1:]
+		return err
+	}
+
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+// FailNext queues errors to be returned, in order, by the next calls to
+// Publish.
+func (p *MemoryPublisher) FailNext(errs ...error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failures = append(p.failures, errs...)
+}
+
+func (p *MemoryPublisher) Messages() []outbox.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	messages := make([]outbox.Message, len(p.messages))
+	copy(messages, p.messages)
+	return messages
+}
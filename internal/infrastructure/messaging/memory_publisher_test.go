@@ -0,0 +1,39 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-ddd/internal/application/outbox"
+)
+
+func TestMemoryPublisher_Publish(t *testing.T) {
+	publisher := NewMemoryPublisher()
+	ctx := context.Background()
+	unavailable := errors.New("broker unavailable")
+
+	message, err := outbox.NewMessage("payment", "payment-123", "payment.created", map[string]string{"status": "pending"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	publisher.FailNext(unavailable)
+	if err := publisher.Publish(ctx, message); !errors.Is(err, unavailable) {
+		t.Errorf("expected queued failure, got %v", err)
+	}
+	if err := publisher.Publish(ctx, message); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	messages := publisher.Messages()
+	if len(messages) != 1 || messages[0].ID != message.ID {
+		t.Errorf("expected the message to be recorded once, got %v", messages)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := publisher.Publish(cancelled, message); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -2,95 +2,216 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"sync"
 
 	"go-ddd/internal/domain/audit"
 )
 
+// AuditMemoryRepository keeps private copies of saved entries, so editing an
+// entry or its maps after Save does not alter the stored log. Saved entries
+// are appended to a single hash chain; see audit.AuditEntry.Chain. Entries
+// are indexed by entity, entity type, user, action and time, and
+// FindByFilter scans only the candidates of the most selective index.
 type AuditMemoryRepository struct {
 	mu      sync.RWMutex
-	entries map[string]*audit.AuditEntry
+	entries map[string]*auditRecord
+	indexes auditIndexes
+	head    *audit.AuditEntry
+	holds   audit.LegalHoldRepository
 }
 
-func NewAuditMemoryRepository() *AuditMemoryRepository {
-	return &AuditMemoryRepository{
-		entries: make(map[string]*audit.AuditEntry),
+type AuditMemoryOption func(*AuditMemoryRepository)
+
+// WithLegalHolds makes Delete refuse entries of the entities holds has under
+// a legal hold. Without it nothing is held.
+func WithLegalHolds(holds audit.LegalHoldRepository) AuditMemoryOption {
+	return func(r *AuditMemoryRepository) {
+		r.holds = holds
 	}
 }
 
+func NewAuditMemoryRepository(opts ...AuditMemoryOption) *AuditMemoryRepository {
+	r := &AuditMemoryRepository{
+		entries: make(map[string]*auditRecord),
+		indexes: newAuditIndexes(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Save links entry to the end of the log. Saving an ID that is already
+// stored fails with audit.ErrDuplicateEntry, and saving an entry linked
+// elsewhere that does not continue the log with audit.ErrOutOfSequence.
 func (r *AuditMemoryRepository) Save(ctx context.Context, entry *audit.AuditEntry) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.entries[entry.ID().String()] = entry
+	return r.append(entry)
+}
+
+// append chains and stores entries in order, all or none of them. It must be
+// called with the lock held.
+func (r *AuditMemoryRepository) append(entries ...*audit.AuditEntry) error {
+	head := r.head
+	ids := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		id := entry.ID().String()
+		if _, exists := r.entries[id]; exists || ids[id] {
+			return fmt.Errorf("%w: %s", audit.ErrDuplicateEntry, id)
+		}
+		if err := entry.Chain(head); err != nil {
+			return err
+		}
+		ids[id] = true
+		head = entry
+	}
+
+	records := make([]*auditRecord, 0, len(entries))
+	for _, entry := range entries {
+		record, err := newAuditRecord(entry)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+	}
+	for _, record := range records {
+		r.entries[record.id] = record
+		r.indexes.add(record)
+	}
+	if len(entries) > 0 {
+		stored, err := audit.FromSnapshot(head.ToSnapshot())
+		if err != nil {
+			return err
+		}
+		r.head = stored
+	}
+	return nil
+}
+
+// Delete removes entries from the log, all or none of them. The chain head
+// is kept, so later entries still link to the last one saved. Unknown IDs
+// fail with audit.ErrAuditEntryNotFound, and entries of an entity under a
+// legal hold with audit.ErrUnderLegalHold.
+func (r *AuditMemoryRepository) Delete(ctx context.Context, ids ...audit.AuditID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	held, err := r.held(ctx)
+	if err != nil {
+		return err
+	}
+	records := make([]*auditRecord, 0, len(ids))
+	for _, id := range ids {
+		record, exists := r.entries[id.String()]
+		if !exists {
+			return fmt.Errorf("%w: %s", audit.ErrAuditEntryNotFound, id)
+		}
+		if held[entityKey{record.entry.EntityType(), record.entry.EntityID()}] {
+			return fmt.Errorf("%w: %s", audit.ErrUnderLegalHold, id)
+		}
+		records = append(records, record)
+	}
+	for _, record := range records {
+		delete(r.entries, record.id)
+		r.indexes.remove(record)
+	}
 	return nil
 }
 
+func (r *AuditMemoryRepository) held(ctx context.Context) (map[entityKey]bool, error) {
+	if r.holds == nil {
+		return nil, nil
+	}
+	holds, err := r.holds.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	held := make(map[entityKey]bool, len(holds))
+	for _, hold := range holds {
+		held[entityKey{hold.EntityType, hold.EntityID}] = true
+	}
+	return held, nil
+}
+
 func (r *AuditMemoryRepository) FindByID(ctx context.Context, id audit.AuditID) (*audit.AuditEntry, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	entry, exists := r.entries[id.String()]
+	record, exists := r.entries[id.String()]
 	if !exists {
-		return nil, errors.New("audit entry not found")
+		return nil, audit.ErrAuditEntryNotFound
 	}
 
-	return entry, nil
+	return record.copy()
 }
 
 func (r *AuditMemoryRepository) FindByEntityID(ctx context.Context, entityType audit.EntityType, entityID string) ([]*audit.AuditEntry, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	var result []*audit.AuditEntry
-	for _, entry := range r.entries {
-		if entry.EntityType() == entityType && entry.EntityID() == entityID {
-			result = append(result, entry)
+	result := []*audit.AuditEntry{}
+	var err error
+	span{}.each(r.indexes.byEntity[entityKey{entityType, entityID}], false, func(record *auditRecord) bool {
+		var entry *audit.AuditEntry
+		if entry, err = record.copy(); err != nil {
+			return false
 		}
+		result = append(result, entry)
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return result, nil
 }
 
 func (r *AuditMemoryRepository) FindByFilter(ctx context.Context, filter audit.AuditFilter) ([]*audit.AuditEntry, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	var result []*audit.AuditEntry
-	for _, entry := range r.entries {
-		if r.matchesFilter(entry, filter) {
-			result = append(result, entry)
-		}
-	}
-
-	return result, nil
+	return r.execute(filter, r.indexes.plan(filter))
 }
 
-func (r *AuditMemoryRepository) matchesFilter(entry *audit.AuditEntry, filter audit.AuditFilter) bool {
-	if filter.EntityType != nil && entry.EntityType() != *filter.EntityType {
-		return false
-	}
-
-	if filter.EntityID != nil && entry.EntityID() != *filter.EntityID {
-		return false
-	}
-
-	if filter.Action != nil && entry.Action() != *filter.Action {
-		return false
-	}
+// execute walks the plan's candidates in the filter's order from its cursor,
+// stopping once Limit entries match. It must be called with the lock held.
+func (r *AuditMemoryRepository) execute(filter audit.AuditFilter, plan auditPlan) ([]*audit.AuditEntry, error) {
+	candidates := plan.span
+	descending := filter.Sort == audit.SortDescending
 
-	if filter.UserID != nil && entry.UserID() != *filter.UserID {
-		return false
+	if filter.Cursor != "" {
+		cursor, err := audit.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		candidates = candidates.after(cursor.Timestamp.UnixNano(), cursor.ID.String(), descending)
 	}
 
-	if filter.FromDate != nil && entry.Timestamp().Before(*filter.FromDate) {
-		return false
-	}
+	var result []*audit.AuditEntry
+	var err error
+	plan.each(candidates, descending, func(record *auditRecord) bool {
+		if !filter.Matches(record.entry) {
+			return true
+		}
 
-	if filter.ToDate != nil && entry.Timestamp().After(*filter.ToDate) {
-		return false
+		var entry *audit.AuditEntry
+		if entry, err = record.copy(); err != nil {
+			return false
+		}
+		result = append(result, entry)
+		return len(result) != filter.Limit
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return true
+	return result, nil
 }
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/google/btree"
+
+	"go-ddd/internal/domain/audit"
+)
+
+// auditRecord is a stored entry: a private copy that filters are matched
+// against and results are copied from, keyed by the order audit queries sort
+// by. It never changes once stored.
+type auditRecord struct {
+	entry *audit.AuditEntry
+	at    int64
+	id    string
+}
+
+func newAuditRecord(entry *audit.AuditEntry) (*auditRecord, error) {
+	stored, err := audit.FromSnapshot(entry.ToSnapshot())
+	if err != nil {
+		return nil, err
+	}
+	return &auditRecord{entry: stored, at: stored.Timestamp().UnixNano(), id: stored.ID().String()}, nil
+}
+
+// copy returns the entry for a caller, who may change it without altering
+// the log.
+func (a *auditRecord) copy() (*audit.AuditEntry, error) {
+	return audit.FromSnapshot(a.entry.ToSnapshot())
+}
+
+func (a *auditRecord) compare(at int64, id string) int {
+	return cmp.Or(cmp.Compare(a.at, at), cmp.Compare(a.id, id))
+}
+
+func lessRecord(a, b *auditRecord) bool {
+	return a.compare(b.at, b.id) < 0
+}
+
+const postingsDegree = 32
+
+// postings lists records in the order audit queries sort by: timestamp, then
+// ID. It is a B-tree, so a record is added or removed in logarithmic time
+// wherever it falls and a time range is found by seeking.
+type postings = btree.BTreeG[*auditRecord]
+
+func newPostings() *postings {
+	return btree.NewG(postingsDegree, lessRecord)
+}
+
+// span is the part of a list a query scans: the records from lo up to, but
+// not including, hi. Nil bounds are open. Bounds are positions rather than
+// stored records, so they need no entry.
+type span struct {
+	lo, hi *auditRecord
+}
+
+// between returns the span of timestamps from from to to, inclusive.
+func between(from, to *time.Time) span {
+	var s span
+	if from != nil {
+		s.lo = &auditRecord{at: from.UnixNano()}
+	}
+	if to != nil {
+		s.hi = &auditRecord{at: to.UnixNano() + 1}
+	}
+	return s
+}
+
+// after narrows s to the records past (at, id) in the direction of the scan.
+func (s span) after(at int64, id string, descending bool) span {
+	if descending {
+		position := &auditRecord{at: at, id: id}
+		if s.hi == nil || lessRecord(position, s.hi) {
+			s.hi = position
+		}
+		return s
+	}
+	// id + "\x00" is the least ID greater than id.
+	position := &auditRecord{at: at, id: id + "\x00"}
+	if s.lo == nil || lessRecord(s.lo, position) {
+		s.lo = position
+	}
+	return s
+}
+
+// each calls fn with the records of list within s, in ascending or
+// descending order, until fn returns false. A nil list is empty.
+func (s span) each(list *postings, descending bool, fn func(record *auditRecord) bool) {
+	if list == nil {
+		return
+	}
+
+	if !descending {
+		visit := func(record *auditRecord) bool {
+			return (s.hi == nil || lessRecord(record, s.hi)) && fn(record)
+		}
+		if s.lo != nil {
+			list.AscendGreaterOrEqual(s.lo, visit)
+		} else {
+			list.Ascend(visit)
+		}
+		return
+	}
+
+	visit := func(record *auditRecord) bool {
+		if s.hi != nil && !lessRecord(record, s.hi) {
+			return true
+		}
+		return (s.lo == nil || !lessRecord(record, s.lo)) && fn(record)
+	}
+	if s.hi != nil {
+		list.DescendLessOrEqual(s.hi, visit)
+	} else {
+		list.Descend(visit)
+	}
+}
+
+// count returns how many records of lists fall within s, counting no
+// further than limit.
+func (s span) count(lists []*postings, limit int) int {
+	n := 0
+	for _, list := range lists {
+		if n >= limit {
+			break
+		}
+		s.each(list, false, func(*auditRecord) bool {
+			n++
+			return n < limit
+		})
+	}
+	return n
+}
+
+// entityKey identifies an audited entity, in the audit indexes and the
+// legal holds alike.
+type entityKey struct {
+	entityType audit.EntityType
+	entityID   string
+}
+
+// auditIndexes are the posting lists of every stored record, overall and by
+// the fields filters most often select on.
+type auditIndexes struct {
+	timeline     *postings
+	byEntity     map[entityKey]*postings
+	byEntityType map[audit.EntityType]*postings
+	byUser       map[string]*postings
+	byAction     map[audit.ActionType]*postings
+}
+
+func newAuditIndexes() auditIndexes {
+	return auditIndexes{
+		timeline:     newPostings(),
+		byEntity:     make(map[entityKey]*postings),
+		byEntityType: make(map[audit.EntityType]*postings),
+		byUser:       make(map[string]*postings),
+		byAction:     make(map[audit.ActionType]*postings),
+	}
+}
+
+func (x *auditIndexes) add(record *auditRecord) {
+	entry := record.entry
+	x.timeline.ReplaceOrInsert(record)
+	insertInto(x.byEntity, entityKey{entry.EntityType(), entry.EntityID()}, record)
+	insertInto(x.byEntityType, entry.EntityType(), record)
+	insertInto(x.byUser, entry.UserID(), record)
+	insertInto(x.byAction, entry.Action(), record)
+}
+
+func (x *auditIndexes) remove(record *auditRecord) {
+	entry := record.entry
+	x.timeline.Delete(record)
+	removeFrom(x.byEntity, entityKey{entry.EntityType(), entry.EntityID()}, record)
+	removeFrom(x.byEntityType, entry.EntityType(), record)
+	removeFrom(x.byUser, entry.UserID(), record)
+	removeFrom(x.byAction, entry.Action(), record)
+}
+
+func insertInto[K comparable](index map[K]*postings, key K, record *auditRecord) {
+	list, ok := index[key]
+	if !ok {
+		list = newPostings()
+		index[key] = list
+	}
+	list.ReplaceOrInsert(record)
+}
+
+func removeFrom[K comparable](index map[K]*postings, key K, record *auditRecord) {
+	list, ok := index[key]
+	if !ok {
+		return
+	}
+	list.Delete(record)
+	if list.Len() == 0 {
+		delete(index, key)
+	}
+}
+
+// auditPlan is the index a query scans, the lists of it that hold the
+// candidates, and the span of the filter's time range within each.
+type auditPlan struct {
+	index string
+	lists []*postings
+	span  span
+}
+
+// each calls fn with the candidates within s, in time order or reverse time
+// order, until fn returns false. Candidates from several lists are merged
+// first.
+func (p auditPlan) each(s span, descending bool, fn func(record *auditRecord) bool) {
+	if len(p.lists) == 1 {
+		s.each(p.lists[0], descending, fn)
+		return
+	}
+
+	var merged []*auditRecord
+	for _, list := range p.lists {
+		s.each(list, false, func(record *auditRecord) bool {
+			merged = append(merged, record)
+			return true
+		})
+	}
+	slices.SortFunc(merged, func(a, b *auditRecord) int { return a.compare(b.at, b.id) })
+	if descending {
+		slices.Reverse(merged)
+	}
+	for _, record := range merged {
+		if !fn(record) {
+			return
+		}
+	}
+}
+
+// plan picks the index that leaves the fewest candidates for filter. Every
+// index can be narrowed to the filter's time range, so the timeline alone
+// serves pure time-range queries, and wins ties. A timeline scan is the
+// fallback, so no index is counted further than that scan would go, nor
+// further than the best index so far. Negated sets cannot narrow the search
+// and are left to the filter.
+func (x *auditIndexes) plan(filter audit.AuditFilter) auditPlan {
+	s := between(filter.FromDate, filter.ToDate)
+	best := auditPlan{index: "timeline", lists: []*postings{x.timeline}, span: s}
+	cost := -1
+	consider := func(index string, lists []*postings) {
+		if cost < 0 {
+			cost = x.timeline.Len()
+			if s.lo != nil || s.hi != nil {
+				cost = s.count(best.lists, cost)
+			}
+		}
+		if n := s.count(lists, cost); n < cost {
+			best, cost = auditPlan{index: index, lists: lists, span: s}, n
+		}
+	}
+
+	entityTypes, byEntityType := wanted(filter.EntityType, filter.EntityTypes)
+	entityIDs, byEntityID := wanted(filter.EntityID, filter.EntityIDs)
+	users, byUser := wanted(filter.UserID, filter.UserIDs)
+	actions, byAction := wanted(filter.Action, filter.Actions)
+
+	if byEntityType && byEntityID {
+		var lists []*postings
+		for _, entityType := range entityTypes {
+			for _, entityID := range entityIDs {
+				lists = append(lists, x.byEntity[entityKey{entityType, entityID}])
+			}
+		}
+		consider("entity", lists)
+	}
+	if byEntityType {
+		consider("entity_type", lookupAll(x.byEntityType, entityTypes))
+	}
+	if byUser {
+		consider("user", lookupAll(x.byUser, users))
+	}
+	if byAction {
+		consider("action", lookupAll(x.byAction, actions))
+	}
+
+	return best
+}
+
+// wanted returns the values a filter field is limited to, if it is.
+func wanted[T cmp.Ordered](value *T, match audit.Match[T]) ([]T, bool) {
+	switch {
+	case value != nil:
+		return []T{*value}, true
+	case !match.IsZero() && !match.Not:
+		values := slices.Clone(match.Values)
+		slices.Sort(values)
+		return slices.Compact(values), true
+	}
+	return nil, false
+}
+
+func lookupAll[K comparable](index map[K]*postings, keys []K) []*postings {
+	lists := make([]*postings, 0, len(keys))
+	for _, key := range keys {
+		lists = append(lists, index[key])
+	}
+	return lists
+}
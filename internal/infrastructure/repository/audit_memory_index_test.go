@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"go-ddd/internal/domain/audit"
+)
+
+var indexStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// seedAuditLog saves n entries spread over 100 entities, 10 users and 4
+// actions, with timestamps a minute apart but saved slightly out of order.
+func seedAuditLog(tb testing.TB, n int) *AuditMemoryRepository {
+	tb.Helper()
+
+	repo := NewAuditMemoryRepository()
+	ctx := context.Background()
+	random := rand.New(rand.NewPCG(1, 2))
+	actions := []audit.ActionType{audit.ActionTypeCreated, audit.ActionTypeProcessed, audit.ActionTypeCompleted, audit.ActionTypeRefunded}
+
+	for i := range n {
+		entry := audit.NewAuditEntry(audit.EntityTypePayment, fmt.Sprintf("payment-%d", random.IntN(100)),
+			actions[random.IntN(len(actions))], fmt.Sprintf("user-%d", random.IntN(10)))
+		entry.SetNewData(map[string]interface{}{"amount": fmt.Sprintf("%d.00", random.IntN(2000))})
+
+		snapshot := entry.ToSnapshot()
+		snapshot.Timestamp = indexStart.Add(time.Duration(i+random.IntN(5)) * time.Minute)
+		entry, _ = audit.FromSnapshot(snapshot)
+		if err := repo.Save(ctx, entry); err != nil {
+			tb.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return repo
+}
+
+// scan is the full scan FindByFilter did before it had indexes.
+func scan(repo *AuditMemoryRepository, filter audit.AuditFilter) ([]*audit.AuditEntry, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var result []*audit.AuditEntry
+	for _, record := range repo.entries {
+		entry, err := record.copy()
+		if err != nil {
+			return nil, err
+		}
+		if filter.Matches(entry) {
+			result = append(result, entry)
+		}
+	}
+	return filter.Paginate(result)
+}
+
+func auditFilters() map[string]audit.AuditFilter {
+	entityType := audit.EntityTypePayment
+	entityID := "payment-7"
+	user := "user-3"
+	from, to := indexStart.Add(100*time.Minute), indexStart.Add(400*time.Minute)
+
+	return map[string]audit.AuditFilter{
+		"entity":           {EntityType: &entityType, EntityID: &entityID},
+		"entity set":       {EntityTypes: audit.AnyOf(entityType), EntityIDs: audit.AnyOf("payment-7", "payment-8", "payment-7")},
+		"user":             {UserID: &user},
+		"actions":          {Actions: audit.AnyOf(audit.ActionTypeRefunded, audit.ActionTypeCompleted)},
+		"excluded actions": {Actions: audit.NoneOf(audit.ActionTypeCreated)},
+		"time range":       {FromDate: &from, ToDate: &to},
+		"user in range":    {UserID: &user, FromDate: &from, ToDate: &to, Sort: audit.SortDescending},
+		"predicate":        {Predicates: []audit.Predicate{{Path: "newData.amount", Op: audit.OpGreater, Value: "1990"}}},
+		"limited":          {EntityType: &entityType, Limit: 25},
+		"limited predicate": {
+			Predicates: []audit.Predicate{{Path: "newData.amount", Op: audit.OpGreater, Value: "1000"}},
+			Sort:       audit.SortDescending,
+			Limit:      150,
+		},
+	}
+}
+
+func TestAuditMemoryRepository_IndexedMatchesScan(t *testing.T) {
+	repo := seedAuditLog(t, 2000)
+	ctx := context.Background()
+
+	ids := func(entries []*audit.AuditEntry) []string {
+		var ids []string
+		for _, entry := range entries {
+			ids = append(ids, entry.ID().String())
+		}
+		return ids
+	}
+
+	for name, filter := range auditFilters() {
+		t.Run(name, func(t *testing.T) {
+			want, _ := scan(repo, filter)
+			got, err := repo.FindByFilter(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(ids(got), ids(want)) {
+				t.Errorf("expected %d entries as a scan returns them, got %d", len(want), len(got))
+			}
+
+			filter.Limit = 0
+			want, _ = scan(repo, filter)
+			filter.Limit = 100
+			var streamed []*audit.AuditEntry
+			for entry, err := range audit.NewService(repo).Stream(ctx, filter) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				streamed = append(streamed, entry)
+			}
+			if !slices.Equal(ids(streamed), ids(want)) {
+				t.Errorf("expected %d entries paged as a scan returns them, got %d", len(want), len(streamed))
+			}
+		})
+	}
+}
+
+func TestAuditMemoryRepository_Plan(t *testing.T) {
+	repo := seedAuditLog(t, 2000)
+	filters := auditFilters()
+
+	tests := []struct {
+		filter string
+		index  string
+	}{
+		{"entity", "entity"},
+		{"entity set", "entity"},
+		{"user", "user"},
+		{"actions", "action"},
+		{"excluded actions", "timeline"},
+		{"time range", "timeline"},
+		{"user in range", "user"},
+		{"predicate", "timeline"},
+		{"limited", "timeline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			plan := repo.indexes.plan(filters[tt.filter])
+			if plan.index != tt.index {
+				t.Errorf("expected the %s index, got %s", tt.index, plan.index)
+			}
+			if !slices.IsSortedFunc(candidates(plan), func(a, b *auditRecord) int { return a.compare(b.at, b.id) }) {
+				t.Error("expected candidates in time order")
+			}
+		})
+	}
+
+	// Deleting entries takes them out of every index.
+	entries, _ := repo.FindByEntityID(context.Background(), audit.EntityTypePayment, "payment-7")
+	ids := make([]audit.AuditID, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID())
+	}
+	repo.Delete(context.Background(), ids...)
+	if got := candidates(repo.indexes.plan(filters["entity"])); len(got) != 0 {
+		t.Errorf("expected deleted entries gone from the index, got %d", len(got))
+	}
+	if repo.indexes.timeline.Len() != len(repo.entries) {
+		t.Errorf("expected the timeline to hold %d entries, got %d", len(repo.entries), repo.indexes.timeline.Len())
+	}
+}
+
+func candidates(plan auditPlan) []*auditRecord {
+	var records []*auditRecord
+	plan.each(plan.span, false, func(record *auditRecord) bool {
+		records = append(records, record)
+		return true
+	})
+	return records
+}
+
+func BenchmarkAuditMemoryRepository_FindByFilter(b *testing.B) {
+	repo := seedAuditLog(b, 100_000)
+	ctx := context.Background()
+
+	for name, filter := range auditFilters() {
+		b.Run(name+"/indexed", func(b *testing.B) {
+			for b.Loop() {
+				repo.FindByFilter(ctx, filter)
+			}
+		})
+		b.Run(name+"/scan", func(b *testing.B) {
+			for b.Loop() {
+				scan(repo, filter)
+			}
+		})
+	}
+}
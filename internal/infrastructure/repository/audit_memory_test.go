@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -66,6 +67,186 @@ func TestAuditMemoryRepository_Save(t *testing.T) {
 	}
 }
 
+func TestAuditMemoryRepository_StoresCopies(t *testing.T) {
+	repo := NewAuditMemoryRepository()
+	ctx := context.Background()
+
+	entry := createAuditEntryWithData("payment-123", "user-456")
+	entry.AddMetadata("source", "api")
+	if err := repo.Save(ctx, entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entry.AddMetadata("source", "tampered")
+	entry.NewData()["status"] = "tampered"
+
+	loaded, err := repo.FindByID(ctx, entry.ID())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Metadata()["source"] != "api" {
+		t.Errorf("expected stored metadata to be unchanged, got %q", loaded.Metadata()["source"])
+	}
+	if loaded.NewData()["status"] != "processing" {
+		t.Errorf("expected stored data to be unchanged, got %v", loaded.NewData()["status"])
+	}
+
+	loaded.AddMetadata("source", "tampered")
+	reloaded, _ := repo.FindByID(ctx, entry.ID())
+	if reloaded.Metadata()["source"] != "api" {
+		t.Error("expected loaded entries not to share state with the store")
+	}
+}
+
+func TestAuditMemoryRepository_Chain(t *testing.T) {
+	repo := NewAuditMemoryRepository()
+	service := audit.NewService(repo)
+	ctx := context.Background()
+
+	first := createAuditEntryWithData("payment-123", "user-456")
+	second := createAuditEntryWithData("payment-123", "user-456")
+	for _, entry := range []*audit.AuditEntry{first, second} {
+		if err := repo.Save(ctx, entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if first.Sequence() != 1 || second.Sequence() != 2 || second.PreviousHash() != first.Hash() {
+		t.Errorf("expected entries to be chained, got %d and %d", first.Sequence(), second.Sequence())
+	}
+
+	if err := repo.Save(ctx, first); !errors.Is(err, audit.ErrDuplicateEntry) {
+		t.Errorf("expected ErrDuplicateEntry, got %v", err)
+	}
+
+	// An entry linked in another log cannot be slotted in here.
+	other := NewAuditMemoryRepository()
+	for range 3 {
+		other.Save(ctx, createAuditEntryWithData("payment-123", "user-456"))
+	}
+	foreign := createAuditEntryWithData("payment-123", "user-456")
+	other.Save(ctx, foreign)
+	if err := repo.Save(ctx, foreign); !errors.Is(err, audit.ErrOutOfSequence) {
+		t.Errorf("expected ErrOutOfSequence, got %v", err)
+	}
+
+	if err := service.Verify(ctx); err != nil {
+		t.Fatalf("expected intact chain, got %v", err)
+	}
+
+	record := repo.entries[first.ID().String()]
+	tampered := record.entry.ToSnapshot()
+	tampered.UserID = "someone-else"
+	record.entry, _ = audit.FromSnapshot(tampered)
+
+	var broken *audit.BrokenLinkError
+	if err := service.Verify(ctx); !errors.As(err, &broken) || broken.Sequence != 1 {
+		t.Errorf("expected break at sequence 1, got %v", err)
+	}
+}
+
+func TestAuditMemoryRepository_Delete(t *testing.T) {
+	repo := NewAuditMemoryRepository()
+	ctx := context.Background()
+
+	first := createAuditEntryWithData("payment-123", "user-456")
+	second := createAuditEntryWithData("payment-123", "user-456")
+	repo.Save(ctx, first)
+	repo.Save(ctx, second)
+
+	if err := repo.Delete(ctx, first.ID(), audit.NewAuditID()); !errors.Is(err, audit.ErrAuditEntryNotFound) {
+		t.Errorf("expected ErrAuditEntryNotFound, got %v", err)
+	}
+	if _, err := repo.FindByID(ctx, first.ID()); err != nil {
+		t.Errorf("expected a failed delete to remove nothing, got %v", err)
+	}
+
+	if err := repo.Delete(ctx, second.ID()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	third := createAuditEntryWithData("payment-123", "user-456")
+	if err := repo.Save(ctx, third); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if third.Sequence() != 3 || third.PreviousHash() != second.Hash() {
+		t.Errorf("expected the chain to continue from the deleted head, got sequence %d", third.Sequence())
+	}
+}
+
+func TestAuditMemoryRepository_DeleteHeld(t *testing.T) {
+	holds := NewLegalHoldMemoryRepository()
+	testDeleteHeld(t, NewAuditMemoryRepository(WithLegalHolds(holds)), holds)
+}
+
+func TestAuditMemoryRepository_HoldPlacedDuringArchival(t *testing.T) {
+	holds := NewLegalHoldMemoryRepository()
+	testHoldPlacedDuringArchival(t, NewAuditMemoryRepository(WithLegalHolds(holds)), holds)
+}
+
+// testDeleteHeld checks that repo refuses to delete any of a batch of
+// entries once one of them is about an entity placed under a hold in holds.
+func testDeleteHeld(t *testing.T, repo audit.PrunableRepository, holds audit.LegalHoldRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	held := createAuditEntryWithData("payment-123", "user-456")
+	free := createAuditEntryWithData("payment-456", "user-456")
+	repo.Save(ctx, held)
+	repo.Save(ctx, free)
+	holds.Place(ctx, audit.LegalHold{EntityType: audit.EntityTypePayment, EntityID: "payment-123", Reason: "dispute"})
+
+	if err := repo.Delete(ctx, free.ID(), held.ID()); !errors.Is(err, audit.ErrUnderLegalHold) {
+		t.Fatalf("expected ErrUnderLegalHold, got %v", err)
+	}
+	if _, err := repo.FindByID(ctx, free.ID()); err != nil {
+		t.Errorf("expected a refused delete to remove nothing, got %v", err)
+	}
+
+	holds.Release(ctx, audit.EntityTypePayment, "payment-123")
+	if err := repo.Delete(ctx, free.ID(), held.ID()); err != nil {
+		t.Errorf("expected released entries to be deleted, got %v", err)
+	}
+}
+
+// testHoldPlacedDuringArchival places a hold after an archiver has selected
+// and archived entries, and checks the held entity's entries stay in repo.
+func testHoldPlacedDuringArchival(t *testing.T, repo audit.PrunableRepository, holds audit.LegalHoldRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	service := audit.NewService(repo)
+	service.RecordAction(ctx, audit.EntityTypePayment, "payment-1", audit.ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+	service.RecordAction(ctx, audit.EntityTypePayment, "payment-2", audit.ActionTypeCreated, "user-123", nil, map[string]interface{}{"status": "pending"})
+
+	store := archiveFunc(func(ctx context.Context, entries []*audit.AuditEntry) (string, error) {
+		holds.Place(ctx, audit.LegalHold{EntityType: audit.EntityTypePayment, EntityID: "payment-2", Reason: "dispute"})
+		return "archive-1", nil
+	})
+	policy := audit.RetentionPolicy{Rules: []audit.RetentionRule{{EntityType: audit.EntityTypePayment}}}
+
+	result, err := audit.NewArchiver(service, repo, store, holds, policy).RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Archived != 1 || result.Held != 1 {
+		t.Errorf("expected payment-1 archived and payment-2 held, got %+v", result)
+	}
+	if history, _ := repo.FindByEntityID(ctx, audit.EntityTypePayment, "payment-2"); len(history) != 1 {
+		t.Errorf("expected the held entry to stay in the log, got %d", len(history))
+	}
+	if history, _ := repo.FindByEntityID(ctx, audit.EntityTypePayment, "payment-1"); len(history) != 0 {
+		t.Errorf("expected the unheld entry to be removed, got %d", len(history))
+	}
+	if err := service.Verify(ctx); err != nil {
+		t.Errorf("expected the log to verify, got %v", err)
+	}
+}
+
+type archiveFunc func(ctx context.Context, entries []*audit.AuditEntry) (string, error)
+
+func (f archiveFunc) Store(ctx context.Context, entries []*audit.AuditEntry) (string, error) {
+	return f(ctx, entries)
+}
+
 func TestAuditMemoryRepository_FindByID(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -114,6 +295,9 @@ func TestAuditMemoryRepository_FindByID(t *testing.T) {
 				if err.Error() != tt.errMsg {
 					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
 				}
+				if !errors.Is(err, audit.ErrAuditEntryNotFound) {
+					t.Errorf("expected ErrAuditEntryNotFound, got %v", err)
+				}
 				return
 			}
 
@@ -337,7 +521,7 @@ func TestAuditMemoryRepository_FindByFilter(t *testing.T) {
 
 			// Verify all returned entries match the filter
 			for _, entry := range result {
-				if !repo.matchesFilter(entry, tt.filter) {
+				if !tt.filter.Matches(entry) {
 					t.Errorf("entry %q does not match filter", entry.ID().String())
 				}
 			}
@@ -444,9 +628,7 @@ func TestAuditMemoryRepository_MatchesFilter(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo := NewAuditMemoryRepository()
-
-			result := repo.matchesFilter(entry, tt.filter)
+			result := tt.filter.Matches(entry)
 
 			if result != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, result)
@@ -556,3 +738,26 @@ func createAuditEntryWithData(entityID, userID string) *audit.AuditEntry {
 
 	return entry
 }
+
+func TestAuditMemoryRepository_FindByQuery(t *testing.T) {
+	repo := NewAuditMemoryRepository()
+	service := audit.NewService(repo)
+	ctx := context.Background()
+
+	for _, amount := range []string{"50.00", "1500.00", "2500.00"} {
+		service.RecordAction(ctx, audit.EntityTypePayment, "payment-"+amount, audit.ActionTypeCreated, "user-456", nil, map[string]interface{}{"amount": amount})
+	}
+	service.RecordAction(ctx, audit.EntityTypePayment, "payment-1500.00", audit.ActionTypeProcessed, "admin", nil, map[string]interface{}{"status": "processing"})
+
+	entries, err := service.GetAuditsByQuery(ctx, `action IN (created, processed) AND user_id != admin AND newData.amount > 1000`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].EntityID() != "payment-1500.00" || entries[1].EntityID() != "payment-2500.00" {
+		t.Errorf("expected the two large payments, got %v", entries)
+	}
+
+	if _, err := repo.FindByFilter(ctx, audit.AuditFilter{Predicates: []audit.Predicate{{Path: "amount", Op: audit.OpEqual}}}); !errors.Is(err, audit.ErrInvalidFilter) {
+		t.Errorf("expected ErrInvalidFilter, got %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-ddd/internal/domain/audit"
+)
+
+const auditColumns = `id, entity_type, entity_id, action, user_id, recorded_at, old_data, new_data, metadata,
+	sequence, previous_hash, hash, key_id, signature`
+
+// AuditSQLRepository stores the audit log with entry data and metadata as
+// JSON documents, and indexes the columns an audit.AuditFilter selects on.
+// Saved entries are appended to a single hash chain, whose head is kept in
+// its own row that each append locks.
+// The schema is created by Migrate.
+type AuditSQLRepository struct {
+	db Executor
+}
+
+func NewAuditSQLRepository(db Executor) *AuditSQLRepository {
+	return &AuditSQLRepository{db: db}
+}
+
+// Save links entry to the end of the log. Saving an ID that is already
+// stored fails with audit.ErrDuplicateEntry, and saving an entry linked
+// elsewhere that does not continue the log with audit.ErrOutOfSequence.
+// Concurrent saves wait for the chain head in turn, so each is linked to the
+// one committed before it. entry is linked only once the append commits.
+// Within a transaction the head stays locked until that transaction ends.
+func (r *AuditSQLRepository) Save(ctx context.Context, entry *audit.AuditEntry) error {
+	linked, err := audit.FromSnapshot(entry.ToSnapshot())
+	if err != nil {
+		return err
+	}
+
+	var head *audit.AuditEntry
+	err = atomically(ctx, r.db, func(tx Executor) error {
+		locked, err := r.lockHead(ctx, tx)
+		if err != nil {
+			return err
+		}
+		head = locked
+
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM audit_entries WHERE id = $1)`, linked.ID().String()).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w: %s", audit.ErrDuplicateEntry, linked.ID())
+		}
+		if err := linked.Chain(head); err != nil {
+			return err
+		}
+
+		snapshot := linked.ToSnapshot()
+		oldData, newData, metadata, err := marshalEntryData(snapshot)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO audit_entries (`+auditColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+			snapshot.ID, snapshot.EntityType, snapshot.EntityID, snapshot.Action, snapshot.UserID, snapshot.Timestamp.UnixNano(),
+			oldData, newData, metadata, snapshot.Sequence, snapshot.PreviousHash, snapshot.Hash, snapshot.KeyID, snapshot.Signature)
+		if err != nil {
+			return auditInsertError(err, snapshot)
+		}
+
+		return r.advance(ctx, tx, snapshot)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Within a caller's transaction the append may yet be rolled back, so
+	// entry is left unlinked there, as MemoryUnitOfWork leaves it. Otherwise
+	// linking is deterministic, and entry comes out the same as the copy
+	// that was stored.
+	if _, ownTransaction := r.db.(beginner); !ownTransaction {
+		return nil
+	}
+	return entry.Chain(head)
+}
+
+// lockHead takes the row lock on the chain head, creating it for an empty
+// log, and loads the last entry appended, or nil if there is none. Taking
+// the lock with the transaction's first statement makes concurrent appends
+// queue for the head instead of reading the same one. On SQLite, which locks
+// the whole database, open it with _txlock=immediate so that waiting
+// transactions honour the busy timeout.
+func (r *AuditSQLRepository) lockHead(ctx context.Context, tx Executor) (*audit.AuditEntry, error) {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_chain_head (id, sequence, entry) VALUES (1, 0, 'null')
+		ON CONFLICT (id) DO UPDATE SET sequence = audit_chain_head.sequence`)
+	if err != nil {
+		return nil, err
+	}
+
+	var sequence int64
+	var data []byte
+	if err := tx.QueryRowContext(ctx, `SELECT sequence, entry FROM audit_chain_head WHERE id = 1`).Scan(&sequence, &data); err != nil {
+		return nil, err
+	}
+	if sequence == 0 {
+		return nil, nil
+	}
+
+	var snapshot audit.EntrySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return audit.FromSnapshot(snapshot)
+}
+
+// advance moves the locked head to the entry just appended.
+func (r *AuditSQLRepository) advance(ctx context.Context, tx Executor, snapshot audit.EntrySnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE audit_chain_head SET sequence = $1, entry = $2 WHERE id = 1`, snapshot.Sequence, string(data))
+	return err
+}
+
+// auditInsertError maps a unique constraint violation on inserting snapshot
+// to the audit error it stands for: its ID or its sequence already taken.
+func auditInsertError(err error, snapshot audit.EntrySnapshot) error {
+	constraint, violated := uniqueViolation(err)
+	switch {
+	case !violated:
+		return err
+	case strings.Contains(constraint, "sequence"):
+		return fmt.Errorf("%w: sequence %d of entry %s is taken", audit.ErrOutOfSequence, snapshot.Sequence, snapshot.ID)
+	default:
+		return fmt.Errorf("%w: %s", audit.ErrDuplicateEntry, snapshot.ID)
+	}
+}
+
+// Delete removes entries from the log, all or none of them. The chain head
+// is kept, so later entries still link to the last one saved. Unknown IDs
+// fail with audit.ErrAuditEntryNotFound, and entries of an entity with a row
+// in legal_holds with audit.ErrUnderLegalHold; the holds are checked by the
+// statements that delete.
+func (r *AuditSQLRepository) Delete(ctx context.Context, ids ...audit.AuditID) error {
+	return atomically(ctx, r.db, func(tx Executor) error {
+		for _, id := range ids {
+			result, err := tx.ExecContext(ctx, `
+				DELETE FROM audit_entries
+				WHERE id = $1 AND NOT EXISTS (
+					SELECT 1 FROM legal_holds
+					WHERE legal_holds.entity_type = audit_entries.entity_type AND legal_holds.entity_id = audit_entries.entity_id
+				)`, id.String())
+			if err != nil {
+				return err
+			}
+			if deleted, err := result.RowsAffected(); err != nil {
+				return err
+			} else if deleted == 1 {
+				continue
+			}
+
+			var exists bool
+			err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM audit_entries WHERE id = $1)`, id.String()).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return fmt.Errorf("%w: %s", audit.ErrUnderLegalHold, id)
+			}
+			return fmt.Errorf("%w: %s", audit.ErrAuditEntryNotFound, id)
+		}
+		return nil
+	})
+}
+
+func (r *AuditSQLRepository) FindByID(ctx context.Context, id audit.AuditID) (*audit.AuditEntry, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+auditColumns+` FROM audit_entries WHERE id = $1`, id.String())
+	entry, err := scanAuditEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, audit.ErrAuditEntryNotFound
+	}
+	return entry, err
+}
+
+func (r *AuditSQLRepository) FindByEntityID(ctx context.Context, entityType audit.EntityType, entityID string) ([]*audit.AuditEntry, error) {
+	return r.query(ctx, `
+		SELECT `+auditColumns+` FROM audit_entries
+		WHERE entity_type = $1 AND entity_id = $2
+		ORDER BY recorded_at, id`,
+		string(entityType), entityID)
+}
+
+// auditScanBatch is how many rows FindByFilter loads at a time while it
+// checks predicates that SQL cannot.
+const auditScanBatch = 200
+
+// FindByFilter selects on the indexed columns in SQL. Predicates into entry
+// data and metadata are checked on the rows it loads, which it pages through
+// auditScanBatch at a time until Limit of them match.
+func (r *AuditSQLRepository) FindByFilter(ctx context.Context, filter audit.AuditFilter) ([]*audit.AuditEntry, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if len(filter.Predicates) == 0 {
+		return r.find(ctx, filter)
+	}
+
+	batch := filter
+	batch.Predicates, batch.Limit = nil, auditScanBatch
+	var entries []*audit.AuditEntry
+	for {
+		rows, err := r.find(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range rows {
+			if !filter.Matches(entry) {
+				continue
+			}
+			entries = append(entries, entry)
+			if len(entries) == filter.Limit {
+				return entries, nil
+			}
+		}
+		if len(rows) < batch.Limit {
+			return entries, nil
+		}
+		batch.Cursor = audit.CursorAfter(rows[len(rows)-1])
+	}
+}
+
+// find runs the part of filter that SQL can select on, ignoring Predicates.
+func (r *AuditSQLRepository) find(ctx context.Context, filter audit.AuditFilter) ([]*audit.AuditEntry, error) {
+	var where conditions
+	if filter.EntityType != nil {
+		where.add("entity_type = " + where.arg(string(*filter.EntityType)))
+	}
+	if filter.EntityID != nil {
+		where.add("entity_id = " + where.arg(*filter.EntityID))
+	}
+	if filter.Action != nil {
+		where.add("action = " + where.arg(string(*filter.Action)))
+	}
+	if filter.UserID != nil {
+		where.add("user_id = " + where.arg(*filter.UserID))
+	}
+	if filter.FromDate != nil {
+		where.add("recorded_at >= " + where.arg(filter.FromDate.UnixNano()))
+	}
+	if filter.ToDate != nil {
+		where.add("recorded_at <= " + where.arg(filter.ToDate.UnixNano()))
+	}
+	if !filter.EntityTypes.IsZero() {
+		in(&where, "entity_type", filter.EntityTypes.Values, filter.EntityTypes.Not)
+	}
+	if !filter.EntityIDs.IsZero() {
+		in(&where, "entity_id", filter.EntityIDs.Values, filter.EntityIDs.Not)
+	}
+	if !filter.Actions.IsZero() {
+		in(&where, "action", filter.Actions.Values, filter.Actions.Not)
+	}
+	if !filter.UserIDs.IsZero() {
+		in(&where, "user_id", filter.UserIDs.Values, filter.UserIDs.Not)
+	}
+
+	keys, direction := []string{"recorded_at", "id"}, "ASC"
+	if filter.Sort == audit.SortDescending {
+		direction = "DESC"
+	}
+	if filter.Cursor != "" {
+		cursor, err := audit.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where.add(after(keys, []string{where.arg(cursor.Timestamp.UnixNano()), where.arg(cursor.ID.String())}, direction))
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_entries` + where.where() + ` ORDER BY ` + orderBy(keys, direction)
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + where.arg(filter.Limit)
+	}
+
+	return r.query(ctx, query, where.args...)
+}
+
+func (r *AuditSQLRepository) query(ctx context.Context, query string, args ...interface{}) ([]*audit.AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*audit.AuditEntry
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func marshalEntryData(s audit.EntrySnapshot) (oldData, newData, metadata string, err error) {
+	for _, field := range []struct {
+		dst   *string
+		value interface{}
+	}{{&oldData, s.OldData}, {&newData, s.NewData}, {&metadata, s.Metadata}} {
+		data, err := json.Marshal(field.value)
+		if err != nil {
+			return "", "", "", err
+		}
+		*field.dst = string(data)
+	}
+	return oldData, newData, metadata, nil
+}
+
+// scanAuditEntry rebuilds an entry from a row of auditColumns.
+func scanAuditEntry(row scanner) (*audit.AuditEntry, error) {
+	var s audit.EntrySnapshot
+	var recordedAt int64
+	var oldData, newData, metadata []byte
+	err := row.Scan(&s.ID, &s.EntityType, &s.EntityID, &s.Action, &s.UserID, &recordedAt, &oldData, &newData, &metadata,
+		&s.Sequence, &s.PreviousHash, &s.Hash, &s.KeyID, &s.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Timestamp = time.Unix(0, recordedAt)
+	for _, field := range []struct {
+		data []byte
+		dst  interface{}
+	}{{oldData, &s.OldData}, {newData, &s.NewData}, {metadata, &s.Metadata}} {
+		if err := json.Unmarshal(field.data, field.dst); err != nil {
+			return nil, err
+		}
+	}
+	return audit.FromSnapshot(s)
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"go-ddd/internal/domain/audit"
+)
+
+func TestAuditSQLRepository_Save(t *testing.T) {
+	repo := NewAuditSQLRepository(openTestDB(t))
+	ctx := context.Background()
+
+	first := audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeCreated, "user-123")
+	first.SetNewData(map[string]interface{}{"amount": "100.00", "tags": []interface{}{"a", 1.5}})
+	first.AddMetadata("source", "api")
+	second := audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeProcessed, "user-123")
+	for _, entry := range []*audit.AuditEntry{first, second} {
+		if err := repo.Save(ctx, entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if second.Sequence() != 2 || second.PreviousHash() != first.Hash() {
+		t.Errorf("expected the second entry to follow the first, got sequence %d", second.Sequence())
+	}
+
+	found, err := repo.FindByID(ctx, first.ID())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found.Hash() != first.Hash() || found.Metadata()["source"] != "api" || len(found.Changes()) != len(first.Changes()) {
+		t.Errorf("expected the stored entry to round-trip, got %+v", found.ToSnapshot())
+	}
+	if err := audit.NewService(repo).Verify(ctx); err != nil {
+		t.Errorf("expected the stored chain to verify, got %v", err)
+	}
+
+	if err := repo.Save(ctx, first); !errors.Is(err, audit.ErrDuplicateEntry) {
+		t.Errorf("expected ErrDuplicateEntry, got %v", err)
+	}
+	if _, err := repo.FindByID(ctx, audit.NewAuditID()); !errors.Is(err, audit.ErrAuditEntryNotFound) {
+		t.Errorf("expected ErrAuditEntryNotFound, got %v", err)
+	}
+}
+
+func TestAuditSQLRepository_SaveConcurrently(t *testing.T) {
+	repo := NewAuditSQLRepository(openTestDB(t))
+	ctx := context.Background()
+
+	entries := make([]*audit.AuditEntry, 20)
+	errs := make([]error, len(entries))
+	var wg sync.WaitGroup
+	for i := range entries {
+		entries[i] = audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeUpdated, "user-123")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = repo.Save(ctx, entries[i])
+		}()
+	}
+	wg.Wait()
+
+	sequences := make(map[int64]bool)
+	for i, entry := range entries {
+		if errs[i] != nil {
+			t.Fatalf("expected concurrent saves to queue, got %v", errs[i])
+		}
+		stored, err := repo.FindByID(ctx, entry.ID())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stored.Hash() != entry.Hash() {
+			t.Errorf("expected entry %d to be linked as stored", i)
+		}
+		sequences[entry.Sequence()] = true
+	}
+	if len(sequences) != len(entries) {
+		t.Errorf("expected %d distinct sequences, got %d", len(entries), len(sequences))
+	}
+	if err := audit.NewService(repo).Verify(ctx); err != nil {
+		t.Errorf("expected the chain to verify, got %v", err)
+	}
+}
+
+func TestAuditSQLRepository_SaveFailureLeavesEntryUnlinked(t *testing.T) {
+	repo := NewAuditSQLRepository(openTestDB(t))
+	ctx := context.Background()
+
+	first := audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeCreated, "user-123")
+	if err := repo.Save(ctx, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	second := audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeProcessed, "user-123")
+	if err := repo.Save(canceled, second); err == nil {
+		t.Fatal("expected a canceled save to fail")
+	}
+	if second.Sequence() != 0 || second.Hash() != "" {
+		t.Errorf("expected a failed save to leave the entry unlinked, got sequence %d", second.Sequence())
+	}
+}
+
+func TestAuditInsertError(t *testing.T) {
+	snapshot := audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeCreated, "user-123").ToSnapshot()
+	tests := map[string]struct {
+		err  error
+		want error
+	}{
+		"sqlite id":         {errors.New("constraint failed: UNIQUE constraint failed: audit_entries.id (1555)"), audit.ErrDuplicateEntry},
+		"sqlite sequence":   {errors.New("constraint failed: UNIQUE constraint failed: audit_entries.sequence (2067)"), audit.ErrOutOfSequence},
+		"postgres id":       {errors.New(`ERROR: duplicate key value violates unique constraint "audit_entries_pkey" (SQLSTATE 23505)`), audit.ErrDuplicateEntry},
+		"postgres sequence": {errors.New(`ERROR: duplicate key value violates unique constraint "audit_entries_sequence_key" (SQLSTATE 23505)`), audit.ErrOutOfSequence},
+	}
+	for name, tt := range tests {
+		if err := auditInsertError(tt.err, snapshot); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", name, tt.want, err)
+		}
+	}
+
+	other := errors.New("connection reset")
+	if err := auditInsertError(other, snapshot); err != other {
+		t.Errorf("expected other errors to pass through, got %v", err)
+	}
+}
+
+func TestAuditSQLRepository_Delete(t *testing.T) {
+	repo := NewAuditSQLRepository(openTestDB(t))
+	ctx := context.Background()
+
+	var entries []*audit.AuditEntry
+	for range 3 {
+		entry := audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeUpdated, "user-123")
+		repo.Save(ctx, entry)
+		entries = append(entries, entry)
+	}
+
+	if err := repo.Delete(ctx, entries[1].ID(), audit.NewAuditID()); !errors.Is(err, audit.ErrAuditEntryNotFound) {
+		t.Fatalf("expected ErrAuditEntryNotFound, got %v", err)
+	}
+	if _, err := repo.FindByID(ctx, entries[1].ID()); err != nil {
+		t.Errorf("expected a failed delete to remove nothing, got %v", err)
+	}
+
+	if err := repo.Delete(ctx, entries[1].ID(), entries[2].ID()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	history, _ := repo.FindByEntityID(ctx, audit.EntityTypePayment, "payment-1")
+	if len(history) != 1 {
+		t.Errorf("expected 1 entry left, got %d", len(history))
+	}
+
+	next := audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeUpdated, "user-123")
+	if err := repo.Save(ctx, next); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next.Sequence() != 4 || next.PreviousHash() != entries[2].Hash() {
+		t.Errorf("expected the chain to continue from the deleted head, got sequence %d", next.Sequence())
+	}
+}
+
+func TestAuditSQLRepository_DeleteHeld(t *testing.T) {
+	db := openTestDB(t)
+	testDeleteHeld(t, NewAuditSQLRepository(db), NewLegalHoldSQLRepository(db))
+}
+
+func TestAuditSQLRepository_HoldPlacedDuringArchival(t *testing.T) {
+	db := openTestDB(t)
+	testHoldPlacedDuringArchival(t, NewAuditSQLRepository(db), NewLegalHoldSQLRepository(db))
+}
+
+func TestAuditSQLRepository_FindByFilter(t *testing.T) {
+	memory := seedAuditLog(t, 500)
+	repo := NewAuditSQLRepository(openTestDB(t))
+	ctx := context.Background()
+
+	log, _ := memory.FindByFilter(ctx, audit.AuditFilter{})
+	slices.SortFunc(log, func(a, b *audit.AuditEntry) int { return int(a.Sequence() - b.Sequence()) })
+	for _, entry := range log {
+		if err := repo.Save(ctx, entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	ids := func(entries []*audit.AuditEntry) []string {
+		result := make([]string, len(entries))
+		for i, entry := range entries {
+			result[i] = entry.ID().String()
+		}
+		return result
+	}
+
+	for name, filter := range auditFilters() {
+		t.Run(name, func(t *testing.T) {
+			want, err := memory.FindByFilter(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := repo.FindByFilter(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(ids(got), ids(want)) {
+				t.Errorf("expected %d entries, got %d", len(want), len(got))
+			}
+
+			filter.Limit = 0
+			all, _ := memory.FindByFilter(ctx, filter)
+			filter.Limit = 9
+			var streamed []*audit.AuditEntry
+			for entry, err := range audit.NewService(repo).Stream(ctx, filter) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				streamed = append(streamed, entry)
+			}
+			if !slices.Equal(ids(streamed), ids(all)) {
+				t.Errorf("expected paging to list %d entries, got %d", len(all), len(streamed))
+			}
+		})
+	}
+
+	history, _ := repo.FindByEntityID(ctx, audit.EntityTypePayment, "payment-7")
+	want, _ := memory.FindByEntityID(ctx, audit.EntityTypePayment, "payment-7")
+	if !slices.Equal(ids(history), ids(want)) {
+		t.Errorf("expected the entity's %d entries in order, got %d", len(want), len(history))
+	}
+	if err := audit.NewService(repo).Verify(ctx); err != nil {
+		t.Errorf("expected the copied chain to verify, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"go-ddd/internal/domain/audit"
+)
+
+// LegalHoldMemoryRepository keeps at most one hold per entity; placing
+// another replaces it.
+type LegalHoldMemoryRepository struct {
+	mu    sync.RWMutex
+	holds map[entityKey]audit.LegalHold
+}
+
+func NewLegalHoldMemoryRepository() *LegalHoldMemoryRepository {
+	return &LegalHoldMemoryRepository{
+		holds: make(map[entityKey]audit.LegalHold),
+	}
+}
+
+func (r *LegalHoldMemoryRepository) Place(ctx context.Context, hold audit.LegalHold) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.holds[entityKey{hold.EntityType, hold.EntityID}] = hold
+	return nil
+}
+
+func (r *LegalHoldMemoryRepository) Release(ctx context.Context, entityType audit.EntityType, entityID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := entityKey{entityType, entityID}
+	if _, exists := r.holds[key]; !exists {
+		return audit.ErrLegalHoldNotFound
+	}
+	delete(r.holds, key)
+	return nil
+}
+
+func (r *LegalHoldMemoryRepository) FindAll(ctx context.Context) ([]audit.LegalHold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	holds := make([]audit.LegalHold, 0, len(r.holds))
+	for _, hold := range r.holds {
+		holds = append(holds, hold)
+	}
+	return holds, nil
+}
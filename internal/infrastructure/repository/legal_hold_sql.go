@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"go-ddd/internal/domain/audit"
+)
+
+// LegalHoldSQLRepository keeps at most one hold per entity in the
+// legal_holds table; placing another replaces it. AuditSQLRepository.Delete
+// reads the same table, so holds kept here protect entries stored there. The
+// schema is created by Migrate.
+type LegalHoldSQLRepository struct {
+	db Executor
+}
+
+func NewLegalHoldSQLRepository(db Executor) *LegalHoldSQLRepository {
+	return &LegalHoldSQLRepository{db: db}
+}
+
+func (r *LegalHoldSQLRepository) Place(ctx context.Context, hold audit.LegalHold) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO legal_holds (entity_type, entity_id, reason, placed_by, placed_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (entity_type, entity_id) DO UPDATE SET
+			reason = excluded.reason, placed_by = excluded.placed_by, placed_at = excluded.placed_at`,
+		string(hold.EntityType), hold.EntityID, hold.Reason, hold.PlacedBy, unixNano(hold.PlacedAt))
+	return err
+}
+
+func (r *LegalHoldSQLRepository) Release(ctx context.Context, entityType audit.EntityType, entityID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM legal_holds WHERE entity_type = $1 AND entity_id = $2`, string(entityType), entityID)
+	if err != nil {
+		return err
+	}
+	if released, err := result.RowsAffected(); err != nil {
+		return err
+	} else if released == 0 {
+		return audit.ErrLegalHoldNotFound
+	}
+	return nil
+}
+
+func (r *LegalHoldSQLRepository) FindAll(ctx context.Context) ([]audit.LegalHold, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT entity_type, entity_id, reason, placed_by, placed_at FROM legal_holds`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holds []audit.LegalHold
+	for rows.Next() {
+		var hold audit.LegalHold
+		var placedAt int64
+		if err := rows.Scan(&hold.EntityType, &hold.EntityID, &hold.Reason, &hold.PlacedBy, &placedAt); err != nil {
+			return nil, err
+		}
+		hold.PlacedAt = fromUnixNano(placedAt)
+		holds = append(holds, hold)
+	}
+
+	return holds, rows.Err()
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-ddd/internal/domain/audit"
+)
+
+func TestLegalHoldSQLRepository(t *testing.T) {
+	repo := NewLegalHoldSQLRepository(openTestDB(t))
+	ctx := context.Background()
+
+	repo.Place(ctx, audit.LegalHold{EntityType: audit.EntityTypePayment, EntityID: "payment-123", Reason: "dispute"})
+	repo.Place(ctx, audit.LegalHold{EntityType: audit.EntityTypePayment, EntityID: "payment-123", Reason: "litigation"})
+	repo.Place(ctx, audit.LegalHold{EntityType: audit.EntityTypePayment, EntityID: "payment-456", Reason: "dispute"})
+
+	holds, err := repo.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(holds) != 2 {
+		t.Errorf("expected one hold per entity, got %v", holds)
+	}
+
+	if err := repo.Release(ctx, audit.EntityTypePayment, "payment-123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Release(ctx, audit.EntityTypePayment, "payment-123"); !errors.Is(err, audit.ErrLegalHoldNotFound) {
+		t.Errorf("expected ErrLegalHoldNotFound, got %v", err)
+	}
+	if holds, _ := repo.FindAll(ctx); len(holds) != 1 || holds[0].EntityID != "payment-456" {
+		t.Errorf("expected only payment-456 held, got %v", holds)
+	}
+}
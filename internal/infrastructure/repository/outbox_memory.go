@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/btree"
+
+	"go-ddd/internal/application/outbox"
+)
+
+// OutboxMemoryRepository keeps messages in insertion order. The positions of
+// pending messages are kept apart, so Pending skips the messages already
+// published or dead-lettered however many there are.
+type OutboxMemoryRepository struct {
+	mu       sync.RWMutex
+	messages []outbox.Message
+	index    map[string]int
+	pending  *btree.BTreeG[int]
+}
+
+func NewOutboxMemoryRepository() *OutboxMemoryRepository {
+	return &OutboxMemoryRepository{
+		index:   make(map[string]int),
+		pending: btree.NewOrderedG[int](32),
+	}
+}
+
+func (r *OutboxMemoryRepository) Add(ctx context.Context, messages ...outbox.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.add(messages...)
+	return nil
+}
+
+// add must be called with the lock held.
+func (r *OutboxMemoryRepository) add(messages ...outbox.Message) {
+	for _, message := range messages {
+		message.Payload = append([]byte(nil), message.Payload...)
+		i, exists := r.index[message.ID]
+		if exists {
+			r.messages[i] = message
+		} else {
+			i = len(r.messages)
+			r.index[message.ID] = i
+			r.messages = append(r.messages, message)
+		}
+		r.track(i)
+	}
+}
+
+// track files the message at position i as pending or not by its status. It
+// must be called with the lock held.
+func (r *OutboxMemoryRepository) track(i int) {
+	if r.messages[i].Status == outbox.StatusPending {
+		r.pending.ReplaceOrInsert(i)
+	} else {
+		r.pending.Delete(i)
+	}
+}
+
+func (r *OutboxMemoryRepository) Pending(ctx context.Context, now time.Time, limit int) ([]outbox.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var pending []outbox.Message
+	r.pending.Ascend(func(i int) bool {
+		if message := r.messages[i]; !message.NextAttemptAt.After(now) {
+			pending = append(pending, copyMessage(message))
+		}
+		return limit <= 0 || len(pending) < limit
+	})
+
+	return pending, nil
+}
+
+func (r *OutboxMemoryRepository) MarkPublished(ctx context.Context, id string) error {
+	return r.update(id, func(message *outbox.Message) {
+		message.Status = outbox.StatusPublished
+		message.LastError = ""
+	})
+}
+
+func (r *OutboxMemoryRepository) MarkFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error {
+	return r.update(id, func(message *outbox.Message) {
+		message.Attempts++
+		message.LastError = reason
+		message.NextAttemptAt = nextAttemptAt
+	})
+}
+
+func (r *OutboxMemoryRepository) MarkDeadLettered(ctx context.Context, id string, reason string) error {
+	return r.update(id, func(message *outbox.Message) {
+		message.Attempts++
+		message.LastError = reason
+		message.Status = outbox.StatusDeadLettered
+	})
+}
+
+func (r *OutboxMemoryRepository) DeadLettered(ctx context.Context) ([]outbox.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var dead []outbox.Message
+	for _, message := range r.messages {
+		if message.Status == outbox.StatusDeadLettered {
+			dead = append(dead, copyMessage(message))
+		}
+	}
+
+	return dead, nil
+}
+
+// All returns every message regardless of status, oldest first.
+func (r *OutboxMemoryRepository) All(ctx context.Context) ([]outbox.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	messages := make([]outbox.Message, len(r.messages))
+	for i, message := range r.messages {
+		messages[i] = copyMessage(message)
+	}
+
+	return messages, nil
+}
+
+func (r *OutboxMemoryRepository) update(id string, change func(*outbox.Message)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, exists := r.index[id]
+	if !exists {
+		return outbox.ErrMessageNotFound
+	}
+
+	change(&r.messages[i])
+	r.track(i)
+	return nil
+}
+
+func copyMessage(message outbox.Message) outbox.Message {
+	message.Payload = append([]byte(nil), message.Payload...)
+	return message
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-ddd/internal/application/outbox"
+)
+
+func TestOutboxMemoryRepository_Lifecycle(t *testing.T) {
+	repo := NewOutboxMemoryRepository()
+	ctx := context.Background()
+
+	first := mustCreateMessage("payment.created")
+	second := mustCreateMessage("payment.processed")
+	third := mustCreateMessage("payment.completed")
+	now := time.Now()
+	if err := repo.Add(ctx, first, second, third); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pending, _ := repo.Pending(ctx, now, 2)
+	if len(pending) != 2 || pending[0].ID != first.ID || pending[1].ID != second.ID {
+		t.Fatalf("expected the two oldest messages, got %v", pending)
+	}
+
+	repo.MarkPublished(ctx, first.ID)
+	repo.MarkFailed(ctx, second.ID, "broker unavailable", now.Add(time.Minute))
+	repo.MarkDeadLettered(ctx, third.ID, "rejected")
+
+	if pending, _ := repo.Pending(ctx, now, 0); len(pending) != 0 {
+		t.Errorf("expected nothing due now, got %v", pending)
+	}
+	pending, _ = repo.Pending(ctx, now.Add(time.Minute), 0)
+	if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].LastError != "broker unavailable" {
+		t.Errorf("expected the failed message to be due after backoff, got %+v", pending)
+	}
+
+	if repo.pending.Len() != 1 {
+		t.Errorf("expected only the failed message left pending, got %d", repo.pending.Len())
+	}
+
+	dead, _ := repo.DeadLettered(ctx)
+	if len(dead) != 1 || dead[0].ID != third.ID || dead[0].Status != outbox.StatusDeadLettered {
+		t.Errorf("expected the rejected message to be dead-lettered, got %+v", dead)
+	}
+
+	if err := repo.MarkPublished(ctx, "missing"); !errors.Is(err, outbox.ErrMessageNotFound) {
+		t.Errorf("expected ErrMessageNotFound, got %v", err)
+	}
+}
+
+func mustCreateMessage(eventType string) outbox.Message {
+	message, err := outbox.NewMessage("payment", "payment-123", eventType, map[string]string{"status": "pending"})
+	if err != nil {
+		panic(err)
+	}
+	return message
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-ddd/internal/application/outbox"
+)
+
+const outboxColumns = `id, aggregate_type, aggregate_id, event_type, payload, occurred_at, status, attempts, next_attempt_at, last_error`
+
+// OutboxSQLRepository keeps messages in the outbox_messages table, oldest
+// first by the time their event occurred. Bound to the transaction of a
+// SQLUnitOfWork, Add commits messages with the changes they describe. The
+// schema is created by Migrate.
+type OutboxSQLRepository struct {
+	db Executor
+}
+
+func NewOutboxSQLRepository(db Executor) *OutboxSQLRepository {
+	return &OutboxSQLRepository{db: db}
+}
+
+// Add stores messages, all or none of them, replacing any with the same ID.
+func (r *OutboxSQLRepository) Add(ctx context.Context, messages ...outbox.Message) error {
+	return atomically(ctx, r.db, func(tx Executor) error {
+		for _, message := range messages {
+			payload := string(message.Payload)
+			if payload == "" {
+				payload = "null"
+			}
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO outbox_messages (`+outboxColumns+`)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				ON CONFLICT (id) DO UPDATE SET
+					aggregate_type = excluded.aggregate_type, aggregate_id = excluded.aggregate_id,
+					event_type = excluded.event_type, payload = excluded.payload, occurred_at = excluded.occurred_at,
+					status = excluded.status, attempts = excluded.attempts,
+					next_attempt_at = excluded.next_attempt_at, last_error = excluded.last_error`,
+				message.ID, message.AggregateType, message.AggregateID, message.EventType, payload,
+				unixNano(message.OccurredAt), string(message.Status), message.Attempts,
+				unixNano(message.NextAttemptAt), message.LastError)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *OutboxSQLRepository) Pending(ctx context.Context, now time.Time, limit int) ([]outbox.Message, error) {
+	var where conditions
+	where.add("status = " + where.arg(string(outbox.StatusPending)))
+	where.add("next_attempt_at <= " + where.arg(unixNano(now)))
+	query := `SELECT ` + outboxColumns + ` FROM outbox_messages` + where.where() + ` ORDER BY occurred_at, id`
+	if limit > 0 {
+		query += ` LIMIT ` + where.arg(limit)
+	}
+
+	return r.query(ctx, query, where.args...)
+}
+
+func (r *OutboxSQLRepository) MarkPublished(ctx context.Context, id string) error {
+	return r.update(ctx, `UPDATE outbox_messages SET status = $1, last_error = '' WHERE id = $2`,
+		string(outbox.StatusPublished), id)
+}
+
+func (r *OutboxSQLRepository) MarkFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error {
+	return r.update(ctx, `UPDATE outbox_messages SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2 WHERE id = $3`,
+		reason, unixNano(nextAttemptAt), id)
+}
+
+func (r *OutboxSQLRepository) MarkDeadLettered(ctx context.Context, id string, reason string) error {
+	return r.update(ctx, `UPDATE outbox_messages SET attempts = attempts + 1, last_error = $1, status = $2 WHERE id = $3`,
+		reason, string(outbox.StatusDeadLettered), id)
+}
+
+func (r *OutboxSQLRepository) DeadLettered(ctx context.Context) ([]outbox.Message, error) {
+	return r.query(ctx, `SELECT `+outboxColumns+` FROM outbox_messages WHERE status = $1 ORDER BY occurred_at, id`,
+		string(outbox.StatusDeadLettered))
+}
+
+func (r *OutboxSQLRepository) update(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		return outbox.ErrMessageNotFound
+	}
+	return nil
+}
+
+func (r *OutboxSQLRepository) query(ctx context.Context, query string, args ...interface{}) ([]outbox.Message, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []outbox.Message
+	for rows.Next() {
+		var message outbox.Message
+		var payload []byte
+		var occurredAt, nextAttemptAt int64
+		err := rows.Scan(&message.ID, &message.AggregateType, &message.AggregateID, &message.EventType, &payload,
+			&occurredAt, &message.Status, &message.Attempts, &nextAttemptAt, &message.LastError)
+		if err != nil {
+			return nil, err
+		}
+		message.Payload = payload
+		message.OccurredAt = fromUnixNano(occurredAt)
+		message.NextAttemptAt = fromUnixNano(nextAttemptAt)
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// unixNano stores the zero time as 0, which UnixNano cannot represent.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-ddd/internal/domain/payment"
+)
+
+const defaultSnapshotInterval = 50
+
+// StoredEvent is one entry of a payment's event stream. Version is its
+// position in the stream, starting at 1.
+type StoredEvent struct {
+	Version    int64
+	Type       string
+	Data       json.RawMessage
+	RecordedAt time.Time
+}
+
+// PaymentEventStore is a payment.Repository that keeps each payment as the
+// stream of domain events it recorded and rebuilds payments by replaying
+// them. A payment's version is the length of its stream, and writes append
+// only if the stream has not grown since the payment was loaded.
+//
+// Every snapshotInterval events it also keeps a snapshot, so loading a
+// payment replays at most that many events.
+type PaymentEventStore struct {
+	mu               sync.RWMutex
+	streams          map[string][]StoredEvent
+	snapshots        map[string]payment.Snapshot
+	snapshotInterval int64
+}
+
+type EventStoreOption func(*PaymentEventStore)
+
+// WithSnapshotInterval sets how many events may follow the latest snapshot
+// before a new one is taken. Zero or less disables snapshots.
+func WithSnapshotInterval(events int) EventStoreOption {
+	return func(s *PaymentEventStore) {
+		s.snapshotInterval = int64(events)
+	}
+}
+
+func NewPaymentEventStore(opts ...EventStoreOption) *PaymentEventStore {
+	s := &PaymentEventStore{
+		streams:          make(map[string][]StoredEvent),
+		snapshots:        make(map[string]payment.Snapshot),
+		snapshotInterval: defaultSnapshotInterval,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Save starts the stream of a new payment. It fails with
+// ErrConcurrentModification if the payment already has one.
+func (s *PaymentEventStore) Save(ctx context.Context, p *payment.Payment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.streams[p.ID().String()]; exists {
+		return payment.ErrConcurrentModification
+	}
+
+	return s.append(p, 0)
+}
+
+func (s *PaymentEventStore) FindByID(ctx context.Context, id payment.PaymentID) (*payment.Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.load(id.String())
+}
+
+func (s *PaymentEventStore) FindAll(ctx context.Context) ([]*payment.Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	payments := make([]*payment.Payment, 0, len(s.streams))
+	for id := range s.streams {
+		p, err := s.load(id)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, nil
+}
+
+// FindByFilter replays every stream before filtering, so it costs as much as
+// FindAll.
+func (s *PaymentEventStore) FindByFilter(ctx context.Context, filter payment.PaymentFilter) ([]*payment.Payment, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	payments, err := s.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return selectPayments(payments, filter)
+}
+
+// Update appends the payment's uncommitted events, provided its version is
+// still the length of the stored stream.
+func (s *PaymentEventStore) Update(ctx context.Context, p *payment.Payment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream, exists := s.streams[p.ID().String()]
+	if !exists {
+		return payment.ErrPaymentNotFound
+	}
+
+	if int64(len(stream)) != p.Version() {
+		return payment.ErrConcurrentModification
+	}
+
+	return s.append(p, p.Version())
+}
+
+func (s *PaymentEventStore) Delete(ctx context.Context, id payment.PaymentID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.streams[id.String()]; !exists {
+		return payment.ErrPaymentNotFound
+	}
+
+	delete(s.streams, id.String())
+	delete(s.snapshots, id.String())
+	return nil
+}
+
+// Events returns a payment's full history, oldest first.
+func (s *PaymentEventStore) Events(ctx context.Context, id payment.PaymentID) ([]payment.DomainEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stream, exists := s.streams[id.String()]
+	if !exists {
+		return nil, payment.ErrPaymentNotFound
+	}
+
+	return decodeEvents(stream)
+}
+
+// append must be called with the write lock held and the stream verified to
+// be at version expected.
+func (s *PaymentEventStore) append(p *payment.Payment, expected int64) error {
+	events := p.UncommittedEvents()
+	if len(events) == 0 {
+		if expected == 0 {
+			return errors.New("payment has no events to start its stream")
+		}
+		return nil
+	}
+
+	now := time.Now()
+	stored := make([]StoredEvent, 0, len(events))
+	for i, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", event.EventType(), err)
+		}
+		stored = append(stored, StoredEvent{
+			Version:    expected + int64(i) + 1,
+			Type:       event.EventType(),
+			Data:       data,
+			RecordedAt: now,
+		})
+	}
+
+	id := p.ID().String()
+	s.streams[id] = append(s.streams[id], stored...)
+	p.MarkEventsCommitted()
+	p.SetVersion(int64(len(s.streams[id])))
+
+	if s.snapshotInterval > 0 && p.Version()-s.snapshots[id].Version >= s.snapshotInterval {
+		s.snapshots[id] = p.ToSnapshot()
+	}
+
+	return nil
+}
+
+// load must be called with the lock held.
+func (s *PaymentEventStore) load(id string) (*payment.Payment, error) {
+	stream, exists := s.streams[id]
+	if !exists {
+		return nil, payment.ErrPaymentNotFound
+	}
+
+	var from *payment.Snapshot
+	if snapshot, ok := s.snapshots[id]; ok {
+		from = &snapshot
+		stream = stream[snapshot.Version:]
+	}
+
+	events, err := decodeEvents(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment.Replay(from, events)
+}
+
+func decodeEvents(stream []StoredEvent) ([]payment.DomainEvent, error) {
+	events := make([]payment.DomainEvent, 0, len(stream))
+	for _, stored := range stream {
+		event, err := payment.DecodeEvent(stored.Type, stored.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: event %d: %w", payment.ErrInvalidEventStream, stored.Version, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
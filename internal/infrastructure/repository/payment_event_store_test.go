@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-ddd/internal/domain/payment"
+)
+
+func TestPaymentEventStore_SaveAndUpdate(t *testing.T) {
+	store := NewPaymentEventStore()
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.50, "USD", "Test payment")
+	if err := store.Save(ctx, testPayment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Save(ctx, testPayment); !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Errorf("expected saving an existing stream to fail, got %v", err)
+	}
+
+	// Two updates without pulling events must not append the first twice.
+	testPayment.Process()
+	if err := store.Update(ctx, testPayment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testPayment.Complete()
+	if err := store.Update(ctx, testPayment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if testPayment.Version() != 3 {
+		t.Errorf("expected version 3, got %d", testPayment.Version())
+	}
+
+	events, err := store.Events(ctx, testPayment.ID())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var types []string
+	for _, event := range events {
+		types = append(types, event.EventType())
+	}
+	if len(types) != 3 || types[0] != "payment.created" || types[1] != "payment.processed" || types[2] != "payment.completed" {
+		t.Errorf("unexpected stream %v", types)
+	}
+
+	stored, err := store.FindByID(ctx, testPayment.ID())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.Status() != payment.PaymentStatusCompleted || stored.Version() != 3 || !stored.Amount().Equal(testPayment.Amount()) {
+		t.Errorf("expected completed v3 payment, got %v v%d", stored.Status(), stored.Version())
+	}
+}
+
+func TestPaymentEventStore_ExpectedVersion(t *testing.T) {
+	store := NewPaymentEventStore()
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.50, "USD", "Test payment")
+	store.Save(ctx, testPayment)
+
+	first, _ := store.FindByID(ctx, testPayment.ID())
+	second, _ := store.FindByID(ctx, testPayment.ID())
+
+	first.Process()
+	if err := store.Update(ctx, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	second.Cancel()
+	if err := store.Update(ctx, second); !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification, got %v", err)
+	}
+
+	if err := store.Update(ctx, mustCreatePayment(1, "USD", "never saved")); !errors.Is(err, payment.ErrPaymentNotFound) {
+		t.Errorf("expected ErrPaymentNotFound, got %v", err)
+	}
+}
+
+func TestPaymentEventStore_Snapshots(t *testing.T) {
+	store := NewPaymentEventStore(WithSnapshotInterval(2))
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.00, "USD", "Test payment")
+	store.Save(ctx, testPayment)
+	testPayment.Authorize(time.Now().Add(time.Hour))
+	testPayment.Capture(mustCreateAmount(80.00, "USD"))
+	store.Update(ctx, testPayment)
+	testPayment.Refund(mustCreateAmount(30.00, "USD"), "damaged")
+	store.Update(ctx, testPayment)
+
+	snapshot, ok := store.snapshots[testPayment.ID().String()]
+	if !ok || snapshot.Version != 3 {
+		t.Fatalf("expected a snapshot at version 3, got %+v", snapshot)
+	}
+
+	// Replay must start from the snapshot: a corrupt event before it is
+	// never read.
+	store.streams[testPayment.ID().String()][0].Data = []byte("not json")
+
+	stored, err := store.FindByID(ctx, testPayment.ID())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.Status() != payment.PaymentStatusPartiallyRefunded || stored.Version() != 4 {
+		t.Errorf("expected partially refunded v4 payment, got %v v%d", stored.Status(), stored.Version())
+	}
+	if refunded, _ := stored.RefundedAmount(); !refunded.Equal(mustCreateAmount(30.00, "USD")) || !stored.CapturedAmount().Equal(mustCreateAmount(80.00, "USD")) {
+		t.Errorf("unexpected amounts: captured %s, refunded %s", stored.CapturedAmount(), refunded)
+	}
+
+	if _, err := store.Events(ctx, testPayment.ID()); !errors.Is(err, payment.ErrInvalidEventStream) {
+		t.Errorf("expected full history to hit the corrupt event, got %v", err)
+	}
+}
+
+func TestPaymentEventStore_WithService(t *testing.T) {
+	store := NewPaymentEventStore()
+	var dispatched int
+	service := payment.NewService(store, payment.WithEventDispatcher(payment.EventDispatcherFunc(
+		func(ctx context.Context, events []payment.DomainEvent) error {
+			dispatched += len(events)
+			return nil
+		},
+	)))
+	ctx := context.Background()
+
+	created, err := service.CreatePayment(ctx, mustCreateAmount(25.00, "EUR"), "Test payment")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.ProcessPayment(ctx, created.ID()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.CompletePayment(ctx, created.ID()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	all, err := store.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) != 1 || all[0].Status() != payment.PaymentStatusCompleted {
+		t.Errorf("expected one completed payment, got %v", all)
+	}
+	completed, err := store.FindByFilter(ctx, payment.PaymentFilter{Statuses: []payment.PaymentStatus{payment.PaymentStatusCompleted}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(completed) != 1 || completed[0].ID() != created.ID() {
+		t.Errorf("expected the payment to match a completed filter, got %v", completed)
+	}
+	if dispatched != 3 {
+		t.Errorf("expected 3 dispatched events, got %d", dispatched)
+	}
+
+	if err := store.Delete(ctx, created.ID()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.FindByID(ctx, created.ID()); !errors.Is(err, payment.ErrPaymentNotFound) {
+		t.Errorf("expected ErrPaymentNotFound, got %v", err)
+	}
+}
+
+func mustCreateAmount(amount float64, currency string) payment.Amount {
+	amt, err := payment.NewAmount(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return amt
+}
@@ -2,20 +2,21 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"sync"
 
 	"go-ddd/internal/domain/payment"
 )
 
+// PaymentMemoryRepository keeps snapshots rather than the payments handed to
+// it, so callers only change stored state through Save and Update.
 type PaymentMemoryRepository struct {
 	mu       sync.RWMutex
-	payments map[string]*payment.Payment
+	payments map[string]payment.Snapshot
 }
 
 func NewPaymentMemoryRepository() *PaymentMemoryRepository {
 	return &PaymentMemoryRepository{
-		payments: make(map[string]*payment.Payment),
+		payments: make(map[string]payment.Snapshot),
 	}
 }
 
@@ -23,7 +24,12 @@ func (r *PaymentMemoryRepository) Save(ctx context.Context, p *payment.Payment)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.payments[p.ID().String()] = p
+	if _, exists := r.payments[p.ID().String()]; exists {
+		return payment.ErrConcurrentModification
+	}
+
+	p.SetVersion(1)
+	r.payments[p.ID().String()] = p.ToSnapshot()
 	return nil
 }
 
@@ -31,12 +37,12 @@ func (r *PaymentMemoryRepository) FindByID(ctx context.Context, id payment.Payme
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	p, exists := r.payments[id.String()]
+	snapshot, exists := r.payments[id.String()]
 	if !exists {
-		return nil, errors.New("payment not found")
+		return nil, payment.ErrPaymentNotFound
 	}
 
-	return p, nil
+	return payment.FromSnapshot(snapshot)
 }
 
 func (r *PaymentMemoryRepository) FindAll(ctx context.Context) ([]*payment.Payment, error) {
@@ -44,22 +50,56 @@ func (r *PaymentMemoryRepository) FindAll(ctx context.Context) ([]*payment.Payme
 	defer r.mu.RUnlock()
 
 	payments := make([]*payment.Payment, 0, len(r.payments))
-	for _, p := range r.payments {
+	for _, snapshot := range r.payments {
+		p, err := payment.FromSnapshot(snapshot)
+		if err != nil {
+			return nil, err
+		}
 		payments = append(payments, p)
 	}
 
 	return payments, nil
 }
 
+func (r *PaymentMemoryRepository) FindByFilter(ctx context.Context, filter payment.PaymentFilter) ([]*payment.Payment, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	payments, err := r.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return selectPayments(payments, filter)
+}
+
+// selectPayments keeps the payments matching filter and pages them in its
+// order, for adapters that can only list every payment.
+func selectPayments(payments []*payment.Payment, filter payment.PaymentFilter) ([]*payment.Payment, error) {
+	matching := payments[:0]
+	for _, p := range payments {
+		if filter.Matches(p) {
+			matching = append(matching, p)
+		}
+	}
+	return filter.Paginate(matching)
+}
+
 func (r *PaymentMemoryRepository) Update(ctx context.Context, p *payment.Payment) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.payments[p.ID().String()]; !exists {
-		return errors.New("payment not found")
+	stored, exists := r.payments[p.ID().String()]
+	if !exists {
+		return payment.ErrPaymentNotFound
+	}
+
+	if stored.Version != p.Version() {
+		return payment.ErrConcurrentModification
 	}
 
-	r.payments[p.ID().String()] = p
+	p.SetVersion(stored.Version + 1)
+	r.payments[p.ID().String()] = p.ToSnapshot()
 	return nil
 }
 
@@ -68,7 +108,7 @@ func (r *PaymentMemoryRepository) Delete(ctx context.Context, id payment.Payment
 	defer r.mu.Unlock()
 
 	if _, exists := r.payments[id.String()]; !exists {
-		return errors.New("payment not found")
+		return payment.ErrPaymentNotFound
 	}
 
 	delete(r.payments, id.String())
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"sync"
 	"testing"
 
@@ -64,6 +66,24 @@ func TestPaymentMemoryRepository_Save(t *testing.T) {
 	}
 }
 
+func TestPaymentMemoryRepository_SaveExisting(t *testing.T) {
+	repo := NewPaymentMemoryRepository()
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.50, "USD", "Test payment")
+	repo.Save(ctx, testPayment)
+	testPayment.Process()
+	repo.Update(ctx, testPayment)
+
+	if err := repo.Save(ctx, testPayment); !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification, got %v", err)
+	}
+	stored, _ := repo.FindByID(ctx, testPayment.ID())
+	if stored.Version() != 2 || testPayment.Version() != 2 {
+		t.Errorf("expected the stored payment to stay at version 2, got %d", stored.Version())
+	}
+}
+
 func TestPaymentMemoryRepository_FindByID(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -112,6 +132,9 @@ func TestPaymentMemoryRepository_FindByID(t *testing.T) {
 				if err.Error() != tt.errMsg {
 					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
 				}
+				if !errors.Is(err, payment.ErrPaymentNotFound) {
+					t.Errorf("expected ErrPaymentNotFound, got %v", err)
+				}
 				return
 			}
 
@@ -242,6 +265,9 @@ func TestPaymentMemoryRepository_Update(t *testing.T) {
 				if err.Error() != tt.errMsg {
 					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
 				}
+				if !errors.Is(err, payment.ErrPaymentNotFound) {
+					t.Errorf("expected ErrPaymentNotFound, got %v", err)
+				}
 				return
 			}
 
@@ -264,6 +290,71 @@ func TestPaymentMemoryRepository_Update(t *testing.T) {
 	}
 }
 
+func TestPaymentMemoryRepository_UpdateVersion(t *testing.T) {
+	repo := NewPaymentMemoryRepository()
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.50, "USD", "Versioned payment")
+	if err := repo.Save(ctx, testPayment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if testPayment.Version() != 1 {
+		t.Fatalf("expected version 1 after save, got %d", testPayment.Version())
+	}
+
+	testPayment.Process()
+	if err := repo.Update(ctx, testPayment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if testPayment.Version() != 2 {
+		t.Fatalf("expected version 2 after update, got %d", testPayment.Version())
+	}
+
+	// Simulate a writer still holding the version it originally loaded.
+	testPayment.SetVersion(1)
+	err := repo.Update(ctx, testPayment)
+	if !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Errorf("expected ErrConcurrentModification, got %v", err)
+	}
+}
+
+func TestPaymentMemoryRepository_StoresCopies(t *testing.T) {
+	repo := NewPaymentMemoryRepository()
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.50, "USD", "Test payment")
+	if err := repo.Save(ctx, testPayment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Neither the saved nor a loaded instance may change the store without Update.
+	testPayment.Process()
+	loaded, err := repo.FindByID(ctx, testPayment.ID())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded == testPayment {
+		t.Fatal("expected a fresh instance on read")
+	}
+	if loaded.Status() != payment.PaymentStatusPending {
+		t.Errorf("expected stored status pending, got %v", loaded.Status())
+	}
+
+	loaded.Cancel()
+	reloaded, _ := repo.FindByID(ctx, testPayment.ID())
+	if reloaded.Status() != payment.PaymentStatusPending {
+		t.Errorf("expected stored status pending, got %v", reloaded.Status())
+	}
+
+	// Two writers that loaded the same version: the second one loses.
+	if err := repo.Update(ctx, loaded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Update(ctx, testPayment); !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Errorf("expected ErrConcurrentModification, got %v", err)
+	}
+}
+
 func TestPaymentMemoryRepository_Delete(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -311,6 +402,9 @@ func TestPaymentMemoryRepository_Delete(t *testing.T) {
 				if err.Error() != tt.errMsg {
 					t.Errorf("expected error message %q, got %q", tt.errMsg, err.Error())
 				}
+				if !errors.Is(err, payment.ErrPaymentNotFound) {
+					t.Errorf("expected ErrPaymentNotFound, got %v", err)
+				}
 				return
 			}
 
@@ -399,6 +493,74 @@ func TestPaymentMemoryRepository_ConcurrentAccess(t *testing.T) {
 	}
 }
 
+func TestPaymentMemoryRepository_FindByFilter(t *testing.T) {
+	repo := NewPaymentMemoryRepository()
+	ctx := context.Background()
+
+	for _, p := range []*payment.Payment{
+		mustCreatePayment(30, "USD", "Coffee beans"),
+		mustCreatePayment(10, "USD", "Coffee filters"),
+		mustCreatePayment(20, "USD", "Tea"),
+		mustCreatePayment(15, "EUR", "Coffee mug"),
+	} {
+		if err := repo.Save(ctx, p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	processed, _ := repo.FindAll(ctx)
+	for _, p := range processed {
+		if p.Description() == "Tea" {
+			p.Process()
+			repo.Update(ctx, p)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filter  payment.PaymentFilter
+		want    []string
+		wantErr error
+	}{
+		{
+			name:   "description in amount order",
+			filter: payment.PaymentFilter{Currency: "USD", Description: "coffee", SortBy: payment.SortByAmount},
+			want:   []string{"Coffee filters", "Coffee beans"},
+		},
+		{
+			name:   "status set",
+			filter: payment.PaymentFilter{Statuses: []payment.PaymentStatus{payment.PaymentStatusProcessing}},
+			want:   []string{"Tea"},
+		},
+		{
+			name:   "descending with limit",
+			filter: payment.PaymentFilter{SortBy: payment.SortByAmount, Sort: payment.SortDescending, Limit: 2},
+			want:   []string{"Coffee beans", "Tea"},
+		},
+		{
+			name:    "invalid filter",
+			filter:  payment.PaymentFilter{Limit: -1},
+			wantErr: payment.ErrInvalidFilter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.FindByFilter(ctx, tt.filter)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			var got []string
+			for _, p := range result {
+				got = append(got, p.Description())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
 func mustCreatePayment(amount float64, currency, description string) *payment.Payment {
 	amt, err := payment.NewAmount(amount, currency)
 	if err != nil {
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"go-ddd/internal/domain/payment"
+)
+
+const paymentColumns = `snapshot, version`
+
+// PaymentSQLRepository stores each payment as a JSON snapshot alongside the
+// columns it is filtered and sorted by. The version column is a row version:
+// Update only writes a row whose version is still the one the payment was
+// loaded with. The schema is created by Migrate.
+type PaymentSQLRepository struct {
+	db Executor
+}
+
+func NewPaymentSQLRepository(db Executor) *PaymentSQLRepository {
+	return &PaymentSQLRepository{db: db}
+}
+
+// Save inserts a new payment. It fails with ErrConcurrentModification if a
+// payment with the same ID is already stored.
+func (r *PaymentSQLRepository) Save(ctx context.Context, p *payment.Payment) error {
+	snapshot := p.ToSnapshot()
+	snapshot.Version = 1
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO payments (id, status, currency, amount_minor, description, created_at, updated_at, version, snapshot)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO NOTHING`,
+		snapshot.ID, snapshot.Status, snapshot.Currency, snapshot.Amount, snapshot.Description,
+		snapshot.CreatedAt.UnixNano(), snapshot.UpdatedAt.UnixNano(), snapshot.Version, string(data))
+	if err != nil {
+		return err
+	}
+	if inserted, err := result.RowsAffected(); err != nil {
+		return err
+	} else if inserted == 0 {
+		return payment.ErrConcurrentModification
+	}
+
+	p.SetVersion(snapshot.Version)
+	return nil
+}
+
+func (r *PaymentSQLRepository) FindByID(ctx context.Context, id payment.PaymentID) (*payment.Payment, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1`, id.String())
+	p, err := scanPayment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, payment.ErrPaymentNotFound
+	}
+	return p, err
+}
+
+func (r *PaymentSQLRepository) FindAll(ctx context.Context) ([]*payment.Payment, error) {
+	return r.query(ctx, `SELECT `+paymentColumns+` FROM payments`)
+}
+
+// FindByFilter runs the whole filter as one query. Descriptions are matched
+// with the database's own case folding, which SQLite applies to ASCII
+// letters only.
+func (r *PaymentSQLRepository) FindByFilter(ctx context.Context, filter payment.PaymentFilter) ([]*payment.Payment, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	var where conditions
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = status.String()
+		}
+		in(&where, "status", statuses, false)
+	}
+	if filter.Currency != "" {
+		where.add("currency = " + where.arg(strings.ToUpper(filter.Currency)))
+	}
+	if filter.MinAmount != nil {
+		where.add("currency = " + where.arg(filter.MinAmount.Currency()) + " AND amount_minor >= " + where.arg(filter.MinAmount.MinorUnits()))
+	}
+	if filter.MaxAmount != nil {
+		where.add("currency = " + where.arg(filter.MaxAmount.Currency()) + " AND amount_minor <= " + where.arg(filter.MaxAmount.MinorUnits()))
+	}
+	if filter.CreatedFrom != nil {
+		where.add("created_at >= " + where.arg(filter.CreatedFrom.UnixNano()))
+	}
+	if filter.CreatedTo != nil {
+		where.add("created_at <= " + where.arg(filter.CreatedTo.UnixNano()))
+	}
+	if filter.UpdatedFrom != nil {
+		where.add("updated_at >= " + where.arg(filter.UpdatedFrom.UnixNano()))
+	}
+	if filter.UpdatedTo != nil {
+		where.add("updated_at <= " + where.arg(filter.UpdatedTo.UnixNano()))
+	}
+	if filter.Description != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Description) + "%"
+		where.add(`LOWER(description) LIKE LOWER(` + where.arg(pattern) + `) ESCAPE '\'`)
+	}
+
+	keys, direction := []string{"created_at", "id"}, "ASC"
+	switch filter.SortBy {
+	case payment.SortByUpdatedAt:
+		keys = []string{"updated_at", "id"}
+	case payment.SortByAmount:
+		keys = []string{"currency", "amount_minor", "id"}
+	}
+	if filter.Sort == payment.SortDescending {
+		direction = "DESC"
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := payment.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		var position []string
+		switch cursor.SortBy {
+		case payment.SortByAmount:
+			position = []string{where.arg(cursor.Amount.Currency()), where.arg(cursor.Amount.MinorUnits())}
+		default:
+			position = []string{where.arg(cursor.At.UnixNano())}
+		}
+		position = append(position, where.arg(cursor.ID.String()))
+		where.add(after(keys, position, direction))
+	}
+
+	query := `SELECT ` + paymentColumns + ` FROM payments` + where.where() + ` ORDER BY ` + orderBy(keys, direction)
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + where.arg(filter.Limit)
+	}
+
+	return r.query(ctx, query, where.args...)
+}
+
+func (r *PaymentSQLRepository) query(ctx context.Context, query string, args ...interface{}) ([]*payment.Payment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*payment.Payment
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
+
+// Update writes the payment if its stored row version is still the one it
+// was loaded with, and bumps the version.
+func (r *PaymentSQLRepository) Update(ctx context.Context, p *payment.Payment) error {
+	snapshot := p.ToSnapshot()
+	snapshot.Version = p.Version() + 1
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE payments
+		SET status = $1, currency = $2, amount_minor = $3, description = $4, created_at = $5, updated_at = $6, version = $7, snapshot = $8
+		WHERE id = $9 AND version = $10`,
+		snapshot.Status, snapshot.Currency, snapshot.Amount, snapshot.Description,
+		snapshot.CreatedAt.UnixNano(), snapshot.UpdatedAt.UnixNano(), snapshot.Version, string(data),
+		snapshot.ID, p.Version())
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		var exists bool
+		err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM payments WHERE id = $1)`, snapshot.ID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return payment.ErrPaymentNotFound
+		}
+		return payment.ErrConcurrentModification
+	}
+
+	p.SetVersion(snapshot.Version)
+	return nil
+}
+
+func (r *PaymentSQLRepository) Delete(ctx context.Context, id payment.PaymentID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM payments WHERE id = $1`, id.String())
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return payment.ErrPaymentNotFound
+	}
+	return nil
+}
+
+// scanPayment rebuilds a payment from a row of paymentColumns. The row
+// version is authoritative over the one inside the snapshot.
+func scanPayment(row scanner) (*payment.Payment, error) {
+	var data []byte
+	var version int64
+	if err := row.Scan(&data, &version); err != nil {
+		return nil, err
+	}
+
+	var snapshot payment.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	snapshot.Version = version
+	return payment.FromSnapshot(snapshot)
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go-ddd/internal/domain/payment"
+)
+
+func TestPaymentSQLRepository_SaveAndFind(t *testing.T) {
+	repo := NewPaymentSQLRepository(openTestDB(t))
+	ctx := context.Background()
+
+	p := mustCreatePayment(100.50, "USD", "Test payment")
+	p.Authorize(time.Now().Add(time.Hour))
+	p.Capture(mustCreateAmount(60, "USD"))
+	if err := repo.Save(ctx, p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Version() != 1 {
+		t.Errorf("expected version 1, got %d", p.Version())
+	}
+
+	found, err := repo.FindByID(ctx, p.ID())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, got := p.ToSnapshot(), found.ToSnapshot()
+	if got.Status != want.Status || *got.Captured != *want.Captured || !got.CreatedAt.Equal(want.CreatedAt) || got.Version != 1 {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	if err := repo.Save(ctx, mustCreatePayment(1, "USD", "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Save(ctx, p); !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Errorf("expected ErrConcurrentModification saving a stored ID, got %v", err)
+	}
+	if _, err := repo.FindByID(ctx, payment.NewPaymentID()); !errors.Is(err, payment.ErrPaymentNotFound) {
+		t.Errorf("expected ErrPaymentNotFound, got %v", err)
+	}
+
+	all, err := repo.FindAll(ctx)
+	if err != nil || len(all) != 2 {
+		t.Errorf("expected 2 payments, got %d (%v)", len(all), err)
+	}
+}
+
+func TestPaymentSQLRepository_Update(t *testing.T) {
+	repo := NewPaymentSQLRepository(openTestDB(t))
+	ctx := context.Background()
+
+	p := mustCreatePayment(100, "USD", "Test payment")
+	repo.Save(ctx, p)
+	first, _ := repo.FindByID(ctx, p.ID())
+	second, _ := repo.FindByID(ctx, p.ID())
+
+	first.Process()
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Version() != 2 {
+		t.Errorf("expected version 2, got %d", first.Version())
+	}
+
+	second.Cancel()
+	if err := repo.Update(ctx, second); !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Errorf("expected ErrConcurrentModification for a stale payment, got %v", err)
+	}
+	stored, _ := repo.FindByID(ctx, p.ID())
+	if stored.Status() != payment.PaymentStatusProcessing || stored.Version() != 2 {
+		t.Errorf("expected the first update to stand, got %s at version %d", stored.Status(), stored.Version())
+	}
+
+	if err := repo.Delete(ctx, p.ID()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Update(ctx, first); !errors.Is(err, payment.ErrPaymentNotFound) {
+		t.Errorf("expected ErrPaymentNotFound updating a deleted payment, got %v", err)
+	}
+	if err := repo.Delete(ctx, p.ID()); !errors.Is(err, payment.ErrPaymentNotFound) {
+		t.Errorf("expected ErrPaymentNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestPaymentSQLRepository_FindByFilter(t *testing.T) {
+	repo := NewPaymentSQLRepository(openTestDB(t))
+	memory := NewPaymentMemoryRepository()
+	ctx := context.Background()
+
+	descriptions := []string{"Coffee beans", "Tea", "COFFEE mug", "Rent", "Gift CARD_50%"}
+	currencies := []string{"USD", "EUR", "JPY"}
+	for i := range 60 {
+		p := mustCreatePayment(float64(10+i%7*5), currencies[i%3], descriptions[i%5])
+		if i%5 == 0 {
+			p.Process()
+		}
+		snapshot := p.ToSnapshot()
+		snapshot.CreatedAt = indexStart.Add(time.Duration(i%20) * time.Minute)
+		snapshot.UpdatedAt = snapshot.CreatedAt.Add(time.Duration(60-i) * time.Second)
+		p, _ = payment.FromSnapshot(snapshot)
+		if err := repo.Save(ctx, p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		memory.Save(ctx, p)
+	}
+
+	minimum, _ := payment.NewAmount(20, "USD")
+	maximum, _ := payment.NewAmount(35, "USD")
+	from, to := indexStart.Add(5*time.Minute), indexStart.Add(12*time.Minute)
+	filters := map[string]payment.PaymentFilter{
+		"everything":         {},
+		"statuses":           {Statuses: []payment.PaymentStatus{payment.PaymentStatusProcessing, payment.PaymentStatusFailed}},
+		"currency":           {Currency: "eur", SortBy: payment.SortByUpdatedAt},
+		"amount range":       {MinAmount: &minimum, MaxAmount: &maximum, SortBy: payment.SortByAmount, Sort: payment.SortDescending},
+		"created range":      {CreatedFrom: &from, CreatedTo: &to, Sort: payment.SortDescending},
+		"updated range":      {UpdatedFrom: &from, UpdatedTo: &to, SortBy: payment.SortByUpdatedAt},
+		"description":        {Description: "coffee", SortBy: payment.SortByAmount},
+		"limited by amount":  {SortBy: payment.SortByAmount, Limit: 7},
+		"limited with match": {Description: "tea", Limit: 3},
+		"literal wildcards":  {Description: "d_50%", Limit: 5},
+		"escaped wildcard":   {Description: "c_f"},
+	}
+
+	ids := func(payments []*payment.Payment) []string {
+		result := make([]string, len(payments))
+		for i, p := range payments {
+			result[i] = p.ID().String()
+		}
+		return result
+	}
+
+	for name, filter := range filters {
+		t.Run(name, func(t *testing.T) {
+			want, err := memory.FindByFilter(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := repo.FindByFilter(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(ids(got), ids(want)) {
+				t.Errorf("expected %d payments %v, got %d %v", len(want), ids(want), len(got), ids(got))
+			}
+
+			var paged []*payment.Payment
+			service := payment.NewService(repo)
+			filter.Limit = 4
+			for {
+				page, err := service.ListPayments(ctx, filter)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				paged = append(paged, page.Payments...)
+				if filter.Cursor = page.NextCursor; filter.Cursor == "" {
+					break
+				}
+			}
+			filter.Limit = 0
+			all, _ := memory.FindByFilter(ctx, filter)
+			if !slices.Equal(ids(paged), ids(all)) {
+				t.Errorf("expected paging to list %d payments, got %d", len(all), len(paged))
+			}
+		})
+	}
+
+	if _, err := repo.FindByFilter(ctx, payment.PaymentFilter{Cursor: "!"}); !errors.Is(err, payment.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The SQL adapters are written for PostgreSQL and stay within the subset
+// SQLite also accepts, so they can be tested without a server. Timestamps are
+// stored as Unix nanoseconds rather than TIMESTAMPTZ, whose microsecond
+// precision could not hold the positions that cursors encode.
+
+type migration struct {
+	version    int
+	statements []string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		statements: []string{
+			`CREATE TABLE payments (
+				id           TEXT PRIMARY KEY,
+				status       TEXT NOT NULL,
+				currency     TEXT NOT NULL,
+				amount_minor BIGINT NOT NULL,
+				description  TEXT NOT NULL,
+				created_at   BIGINT NOT NULL,
+				updated_at   BIGINT NOT NULL,
+				version      BIGINT NOT NULL,
+				snapshot     JSONB NOT NULL
+			)`,
+			`CREATE INDEX payments_created_at ON payments (created_at, id)`,
+			`CREATE INDEX payments_updated_at ON payments (updated_at, id)`,
+			`CREATE INDEX payments_amount ON payments (currency, amount_minor, id)`,
+			`CREATE INDEX payments_status ON payments (status, created_at, id)`,
+		},
+	},
+	{
+		version: 2,
+		statements: []string{
+			`CREATE TABLE audit_entries (
+				id            TEXT PRIMARY KEY,
+				sequence      BIGINT NOT NULL UNIQUE,
+				entity_type   TEXT NOT NULL,
+				entity_id     TEXT NOT NULL,
+				action        TEXT NOT NULL,
+				user_id       TEXT NOT NULL,
+				recorded_at   BIGINT NOT NULL,
+				old_data      JSONB NOT NULL,
+				new_data      JSONB NOT NULL,
+				metadata      JSONB NOT NULL,
+				previous_hash TEXT NOT NULL,
+				hash          TEXT NOT NULL,
+				key_id        TEXT NOT NULL,
+				signature     BYTEA
+			)`,
+			`CREATE INDEX audit_entries_recorded_at ON audit_entries (recorded_at, id)`,
+			`CREATE INDEX audit_entries_entity ON audit_entries (entity_type, entity_id, recorded_at, id)`,
+			`CREATE INDEX audit_entries_entity_type ON audit_entries (entity_type, recorded_at, id)`,
+			`CREATE INDEX audit_entries_user_id ON audit_entries (user_id, recorded_at, id)`,
+			`CREATE INDEX audit_entries_action ON audit_entries (action, recorded_at, id)`,
+			// The last entry appended, kept apart from audit_entries so the
+			// chain continues from it even after it is archived.
+			`CREATE TABLE audit_chain_head (
+				id       SMALLINT PRIMARY KEY CHECK (id = 1),
+				sequence BIGINT NOT NULL,
+				entry    JSONB NOT NULL
+			)`,
+		},
+	},
+	{
+		version: 3,
+		statements: []string{
+			`CREATE TABLE outbox_messages (
+				id              TEXT PRIMARY KEY,
+				aggregate_type  TEXT NOT NULL,
+				aggregate_id    TEXT NOT NULL,
+				event_type      TEXT NOT NULL,
+				payload         JSONB NOT NULL,
+				occurred_at     BIGINT NOT NULL,
+				status          TEXT NOT NULL,
+				attempts        BIGINT NOT NULL,
+				next_attempt_at BIGINT NOT NULL,
+				last_error      TEXT NOT NULL
+			)`,
+			`CREATE INDEX outbox_messages_status ON outbox_messages (status, occurred_at, id)`,
+		},
+	},
+	{
+		version: 4,
+		statements: []string{
+			`CREATE TABLE legal_holds (
+				entity_type TEXT NOT NULL,
+				entity_id   TEXT NOT NULL,
+				reason      TEXT NOT NULL,
+				placed_by   TEXT NOT NULL,
+				placed_at   BIGINT NOT NULL,
+				PRIMARY KEY (entity_type, entity_id)
+			)`,
+		},
+	},
+}
+
+// Migrate brings the schema of the SQL repositories up to date, applying
+// each migration not yet recorded in schema_migrations in its own
+// transaction.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		err := inTransaction(ctx, db, func(tx *sql.Tx) error {
+			for _, statement := range m.statements {
+				if _, err := tx.ExecContext(ctx, statement); err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", m.version, err)
+		}
+	}
+	return nil
+}
+
+// Executor runs statements for the SQL repositories. *sql.DB and *sql.Tx
+// both implement it, so a repository works on its own or as part of a
+// transaction such as a SQLUnitOfWork's.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// beginner is an Executor that can start a transaction of its own, such as
+// a *sql.DB or *sql.Conn.
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+func inTransaction(ctx context.Context, db beginner, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// atomically runs fn so that its statements take effect together or not at
+// all: in a transaction of its own if db can begin one, and otherwise within
+// a savepoint of the transaction db is part of, whose failure leaves the
+// rest of that transaction intact.
+func atomically(ctx context.Context, db Executor, fn func(tx Executor) error) error {
+	if db, ok := db.(beginner); ok {
+		return inTransaction(ctx, db, func(tx *sql.Tx) error { return fn(tx) })
+	}
+
+	if _, err := db.ExecContext(ctx, `SAVEPOINT repository`); err != nil {
+		return err
+	}
+	if err := fn(db); err != nil {
+		if _, rollbackErr := db.ExecContext(ctx, `ROLLBACK TO SAVEPOINT repository`); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	_, err := db.ExecContext(ctx, `RELEASE SAVEPOINT repository`)
+	return err
+}
+
+// uniqueViolation reports whether err is a unique constraint violation and
+// names the constraint or columns violated. database/sql has no portable
+// error codes, so it recognises the messages of PostgreSQL and SQLite.
+func uniqueViolation(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	message := err.Error()
+	for _, prefix := range []string{"duplicate key value violates unique constraint ", "UNIQUE constraint failed: "} {
+		if _, constraint, found := strings.Cut(message, prefix); found {
+			return constraint, true
+		}
+	}
+	return "", false
+}
+
+// conditions builds a WHERE clause with numbered placeholders, which are
+// numbered in the order they appear in the query text.
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// arg adds value to the query's arguments and returns its placeholder.
+func (c *conditions) arg(value interface{}) string {
+	c.args = append(c.args, value)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+func (c *conditions) add(clause string) {
+	c.clauses = append(c.clauses, clause)
+}
+
+// in restricts column to values or, with not, excludes them.
+func in[T ~string](c *conditions, column string, values []T, not bool) {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = c.arg(string(value))
+	}
+	operator := " IN ("
+	if not {
+		operator = " NOT IN ("
+	}
+	c.add(column + operator + strings.Join(placeholders, ", ") + ")")
+}
+
+func (c *conditions) where() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, for use with
+// ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// after is the keyset condition for rows past position, the placeholders of
+// a row's keys, in the direction keys are ordered in.
+func after(keys, position []string, direction string) string {
+	operator := " > "
+	if direction == "DESC" {
+		operator = " < "
+	}
+	return "(" + strings.Join(keys, ", ") + ")" + operator + "(" + strings.Join(position, ", ") + ")"
+}
+
+func orderBy(keys []string, direction string) string {
+	order := make([]string, len(keys))
+	for i, key := range keys {
+		order[i] = key + " " + direction
+	}
+	return strings.Join(order, ", ")
+}
+
+// scanner is a *sql.Row or *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
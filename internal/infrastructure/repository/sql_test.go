@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB opens a migrated SQLite database in a temporary directory.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db")+"?_pragma=busy_timeout(5000)&_txlock=immediate")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+func TestMigrate(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("expected migrating again to be a no-op, got %v", err)
+	}
+
+	var version, applied int
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version), COUNT(*) FROM schema_migrations`).Scan(&version, &applied); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := migrations[len(migrations)-1].version; version != want || applied != len(migrations) {
+		t.Errorf("expected %d migrations up to version %d, got %d up to %d", len(migrations), want, applied, version)
+	}
+
+	for _, table := range []string{"payments", "audit_entries", "audit_chain_head", "outbox_messages", "legal_holds"} {
+		if _, err := db.ExecContext(ctx, `SELECT COUNT(*) FROM `+table); err != nil {
+			t.Errorf("expected table %s: %v", table, err)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go-ddd/internal/application"
+	"go-ddd/internal/application/outbox"
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/domain/payment"
+)
+
+// MemoryUnitOfWork stages writes made inside Do and applies them to the
+// underlying memory repositories only when the callback succeeds. Payments
+// changed by someone else since the transaction read them fail the commit
+// with ErrConcurrentModification. Audit entries join the hash chain when the
+// transaction commits.
+type MemoryUnitOfWork struct {
+	payments *PaymentMemoryRepository
+	audits   *AuditMemoryRepository
+	outbox   *OutboxMemoryRepository
+}
+
+func NewMemoryUnitOfWork(payments *PaymentMemoryRepository, audits *AuditMemoryRepository, outbox *OutboxMemoryRepository) *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{
+		payments: payments,
+		audits:   audits,
+		outbox:   outbox,
+	}
+}
+
+func (u *MemoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos application.Repositories) error) error {
+	payments := &paymentMemoryTx{
+		base:     u.payments,
+		staged:   make(map[string]payment.Snapshot),
+		deleted:  make(map[string]bool),
+		expected: make(map[string]int64),
+	}
+	audits := &auditMemoryTx{
+		base:   u.audits,
+		staged: make(map[string]audit.EntrySnapshot),
+	}
+	messages := &outboxMemoryTx{
+		OutboxMemoryRepository: u.outbox,
+	}
+
+	if err := fn(ctx, application.Repositories{Payments: payments, Audits: audits, Outbox: messages}); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	u.payments.mu.Lock()
+	defer u.payments.mu.Unlock()
+	u.audits.mu.Lock()
+	defer u.audits.mu.Unlock()
+	u.outbox.mu.Lock()
+	defer u.outbox.mu.Unlock()
+
+	if err := payments.check(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	if err := audits.apply(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	payments.apply()
+	u.outbox.add(messages.staged...)
+	return nil
+}
+
+// paymentMemoryTx is a payment.Repository over a PaymentMemoryRepository that
+// keeps its own writes private until apply. expected records the stored
+// version each touched payment had when the transaction first saw it.
+type paymentMemoryTx struct {
+	base     *PaymentMemoryRepository
+	staged   map[string]payment.Snapshot
+	deleted  map[string]bool
+	expected map[string]int64
+}
+
+func (t *paymentMemoryTx) current(id string) (payment.Snapshot, bool) {
+	if t.deleted[id] {
+		return payment.Snapshot{}, false
+	}
+	if snapshot, exists := t.staged[id]; exists {
+		return snapshot, true
+	}
+
+	t.base.mu.RLock()
+	defer t.base.mu.RUnlock()
+
+	snapshot, exists := t.base.payments[id]
+	return snapshot, exists
+}
+
+func (t *paymentMemoryTx) track(id string) {
+	if _, tracked := t.expected[id]; tracked {
+		return
+	}
+
+	t.base.mu.RLock()
+	defer t.base.mu.RUnlock()
+
+	t.expected[id] = t.base.payments[id].Version
+}
+
+func (t *paymentMemoryTx) Save(ctx context.Context, p *payment.Payment) error {
+	if _, exists := t.current(p.ID().String()); exists {
+		return payment.ErrConcurrentModification
+	}
+
+	t.track(p.ID().String())
+	p.SetVersion(1)
+	t.staged[p.ID().String()] = p.ToSnapshot()
+	delete(t.deleted, p.ID().String())
+	return nil
+}
+
+func (t *paymentMemoryTx) FindByID(ctx context.Context, id payment.PaymentID) (*payment.Payment, error) {
+	snapshot, exists := t.current(id.String())
+	if !exists {
+		return nil, payment.ErrPaymentNotFound
+	}
+
+	return payment.FromSnapshot(snapshot)
+}
+
+func (t *paymentMemoryTx) FindAll(ctx context.Context) ([]*payment.Payment, error) {
+	t.base.mu.RLock()
+	ids := make([]string, 0, len(t.base.payments)+len(t.staged))
+	for id := range t.base.payments {
+		if _, staged := t.staged[id]; !staged {
+			ids = append(ids, id)
+		}
+	}
+	t.base.mu.RUnlock()
+	for id := range t.staged {
+		ids = append(ids, id)
+	}
+
+	payments := make([]*payment.Payment, 0, len(ids))
+	for _, id := range ids {
+		snapshot, exists := t.current(id)
+		if !exists {
+			continue
+		}
+		p, err := payment.FromSnapshot(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, nil
+}
+
+// FindByFilter sees the transaction's own writes, as FindAll does.
+func (t *paymentMemoryTx) FindByFilter(ctx context.Context, filter payment.PaymentFilter) ([]*payment.Payment, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	payments, err := t.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return selectPayments(payments, filter)
+}
+
+func (t *paymentMemoryTx) Update(ctx context.Context, p *payment.Payment) error {
+	stored, exists := t.current(p.ID().String())
+	if !exists {
+		return payment.ErrPaymentNotFound
+	}
+
+	if stored.Version != p.Version() {
+		return payment.ErrConcurrentModification
+	}
+
+	t.track(p.ID().String())
+	p.SetVersion(stored.Version + 1)
+	t.staged[p.ID().String()] = p.ToSnapshot()
+	return nil
+}
+
+func (t *paymentMemoryTx) Delete(ctx context.Context, id payment.PaymentID) error {
+	if _, exists := t.current(id.String()); !exists {
+		return payment.ErrPaymentNotFound
+	}
+
+	t.track(id.String())
+	delete(t.staged, id.String())
+	t.deleted[id.String()] = true
+	return nil
+}
+
+// check must be called with the base lock held.
+func (t *paymentMemoryTx) check() error {
+	for id, version := range t.expected {
+		if t.base.payments[id].Version != version {
+			return payment.ErrConcurrentModification
+		}
+	}
+	return nil
+}
+
+// apply must be called with the base lock held.
+func (t *paymentMemoryTx) apply() {
+	for id := range t.deleted {
+		delete(t.base.payments, id)
+	}
+	for id, snapshot := range t.staged {
+		t.base.payments[id] = snapshot
+	}
+}
+
+// auditMemoryTx is an audit.Repository over an AuditMemoryRepository whose
+// saves become visible to others only on apply. Entries are linked into the
+// hash chain at commit, in the order they were saved, so transactions
+// committing in turn never conflict over the head of the log.
+type auditMemoryTx struct {
+	base   *AuditMemoryRepository
+	staged map[string]audit.EntrySnapshot
+	order  []string
+}
+
+func (t *auditMemoryTx) Save(ctx context.Context, entry *audit.AuditEntry) error {
+	id := entry.ID().String()
+	if _, exists := t.staged[id]; exists {
+		return fmt.Errorf("%w: %s", audit.ErrDuplicateEntry, id)
+	}
+	if _, err := t.base.FindByID(ctx, entry.ID()); err == nil {
+		return fmt.Errorf("%w: %s", audit.ErrDuplicateEntry, id)
+	}
+
+	t.staged[id] = entry.ToSnapshot()
+	t.order = append(t.order, id)
+	return nil
+}
+
+func (t *auditMemoryTx) FindByID(ctx context.Context, id audit.AuditID) (*audit.AuditEntry, error) {
+	if snapshot, exists := t.staged[id.String()]; exists {
+		return audit.FromSnapshot(snapshot)
+	}
+	return t.base.FindByID(ctx, id)
+}
+
+func (t *auditMemoryTx) FindByEntityID(ctx context.Context, entityType audit.EntityType, entityID string) ([]*audit.AuditEntry, error) {
+	result, err := t.base.FindByEntityID(ctx, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, snapshot := range t.staged {
+		if snapshot.EntityType != string(entityType) || snapshot.EntityID != entityID {
+			continue
+		}
+		entry, err := audit.FromSnapshot(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entry)
+	}
+
+	audit.SortEntries(result, audit.SortAscending)
+	return result, nil
+}
+
+// FindByFilter pages through the stored and staged entries together, since
+// staged ones may fall anywhere in the requested order.
+func (t *auditMemoryTx) FindByFilter(ctx context.Context, filter audit.AuditFilter) ([]*audit.AuditEntry, error) {
+	unlimited := filter
+	unlimited.Limit = 0
+	result, err := t.base.FindByFilter(ctx, unlimited)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, snapshot := range t.staged {
+		entry, err := audit.FromSnapshot(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Matches(entry) {
+			result = append(result, entry)
+		}
+	}
+
+	return filter.Paginate(result)
+}
+
+// apply must be called with the base lock held. It either appends every
+// staged entry or, if one cannot be chained, none.
+func (t *auditMemoryTx) apply() error {
+	entries := make([]*audit.AuditEntry, 0, len(t.order))
+	for _, id := range t.order {
+		entry, err := audit.FromSnapshot(t.staged[id])
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+
+	return t.base.append(entries...)
+}
+
+// outboxMemoryTx stages added messages until commit. Delivery bookkeeping is
+// the relay's business and goes straight to the underlying repository.
+type outboxMemoryTx struct {
+	*OutboxMemoryRepository
+	staged []outbox.Message
+}
+
+func (t *outboxMemoryTx) Add(ctx context.Context, messages ...outbox.Message) error {
+	t.staged = append(t.staged, messages...)
+	return nil
+}
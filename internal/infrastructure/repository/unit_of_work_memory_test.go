@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go-ddd/internal/application"
+	"go-ddd/internal/application/outbox"
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/domain/payment"
+)
+
+func TestMemoryUnitOfWork_Commit(t *testing.T) {
+	payments := NewPaymentMemoryRepository()
+	audits := NewAuditMemoryRepository()
+	messages := NewOutboxMemoryRepository()
+	uow := NewMemoryUnitOfWork(payments, audits, messages)
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.50, "USD", "Test payment")
+	message, _ := outbox.NewMessage("payment", testPayment.ID().String(), "payment.created", testPayment.ToSnapshot())
+	entry := audit.NewAuditEntry(audit.EntityTypePayment, testPayment.ID().String(), audit.ActionTypeCreated, "user-123")
+
+	err := uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		if err := repos.Payments.Save(ctx, testPayment); err != nil {
+			return err
+		}
+		if err := repos.Audits.Save(ctx, entry); err != nil {
+			return err
+		}
+		if err := repos.Outbox.Add(ctx, message); err != nil {
+			return err
+		}
+
+		// Staged writes are visible inside the transaction only.
+		if _, err := repos.Payments.FindByID(ctx, testPayment.ID()); err != nil {
+			t.Errorf("expected staged payment to be readable, got %v", err)
+		}
+		history, _ := repos.Audits.FindByEntityID(ctx, audit.EntityTypePayment, testPayment.ID().String())
+		if len(history) != 1 {
+			t.Errorf("expected 1 staged audit entry, got %d", len(history))
+		}
+		if _, err := payments.FindByID(ctx, testPayment.ID()); !errors.Is(err, payment.ErrPaymentNotFound) {
+			t.Errorf("expected payment to be invisible before commit, got %v", err)
+		}
+		if pending, _ := messages.Pending(ctx, time.Now(), 0); len(pending) != 0 {
+			t.Errorf("expected outbox message to be invisible before commit, got %d", len(pending))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := payments.FindByID(ctx, testPayment.ID()); err != nil {
+		t.Errorf("expected committed payment, got %v", err)
+	}
+	if _, err := audits.FindByID(ctx, entry.ID()); err != nil {
+		t.Errorf("expected committed audit entry, got %v", err)
+	}
+	if pending, _ := messages.Pending(ctx, time.Now(), 0); len(pending) != 1 || pending[0].ID != message.ID {
+		t.Errorf("expected committed outbox message, got %v", pending)
+	}
+}
+
+func TestMemoryUnitOfWork_Rollback(t *testing.T) {
+	payments := NewPaymentMemoryRepository()
+	audits := NewAuditMemoryRepository()
+	messages := NewOutboxMemoryRepository()
+	uow := NewMemoryUnitOfWork(payments, audits, messages)
+	ctx := context.Background()
+
+	existing := mustCreatePayment(10.00, "USD", "Existing payment")
+	payments.Save(ctx, existing)
+
+	created := mustCreatePayment(100.50, "USD", "Test payment")
+	auditFailure := errors.New("audit store unavailable")
+
+	err := uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		if err := repos.Payments.Save(ctx, created); err != nil {
+			return err
+		}
+		p, err := repos.Payments.FindByID(ctx, existing.ID())
+		if err != nil {
+			return err
+		}
+		p.Process()
+		if err := repos.Payments.Update(ctx, p); err != nil {
+			return err
+		}
+		if err := repos.Payments.Delete(ctx, existing.ID()); err != nil {
+			return err
+		}
+		message, _ := outbox.NewMessage("payment", created.ID().String(), "payment.created", created.ToSnapshot())
+		repos.Outbox.Add(ctx, message)
+		repos.Audits.Save(ctx, audit.NewAuditEntry(audit.EntityTypePayment, created.ID().String(), audit.ActionTypeCreated, "user-123"))
+		return auditFailure
+	})
+	if !errors.Is(err, auditFailure) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+
+	if _, err := payments.FindByID(ctx, created.ID()); !errors.Is(err, payment.ErrPaymentNotFound) {
+		t.Errorf("expected created payment to be rolled back, got %v", err)
+	}
+	stored, err := payments.FindByID(ctx, existing.ID())
+	if err != nil {
+		t.Fatalf("expected existing payment to survive, got %v", err)
+	}
+	if stored.Status() != payment.PaymentStatusPending || stored.Version() != 1 {
+		t.Errorf("expected untouched pending v1 payment, got %v v%d", stored.Status(), stored.Version())
+	}
+	history, _ := audits.FindByEntityID(ctx, audit.EntityTypePayment, created.ID().String())
+	if len(history) != 0 {
+		t.Errorf("expected no audit entries, got %d", len(history))
+	}
+	if all, _ := messages.All(ctx); len(all) != 0 {
+		t.Errorf("expected no outbox messages, got %d", len(all))
+	}
+}
+
+func TestMemoryUnitOfWork_CommitConflict(t *testing.T) {
+	payments := NewPaymentMemoryRepository()
+	uow := NewMemoryUnitOfWork(payments, NewAuditMemoryRepository(), NewOutboxMemoryRepository())
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.50, "USD", "Test payment")
+	payments.Save(ctx, testPayment)
+
+	err := uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		p, err := repos.Payments.FindByID(ctx, testPayment.ID())
+		if err != nil {
+			return err
+		}
+		p.Process()
+		if err := repos.Payments.Update(ctx, p); err != nil {
+			return err
+		}
+
+		// Another writer commits first.
+		other, _ := payments.FindByID(ctx, testPayment.ID())
+		other.Cancel()
+		return payments.Update(ctx, other)
+	})
+	if !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification, got %v", err)
+	}
+
+	stored, _ := payments.FindByID(ctx, testPayment.ID())
+	if stored.Status() != payment.PaymentStatusCancelled {
+		t.Errorf("expected the other writer's change to win, got %v", stored.Status())
+	}
+}
+
+func TestMemoryUnitOfWork_SaveExisting(t *testing.T) {
+	payments := NewPaymentMemoryRepository()
+	uow := NewMemoryUnitOfWork(payments, NewAuditMemoryRepository(), NewOutboxMemoryRepository())
+	ctx := context.Background()
+
+	stored := mustCreatePayment(100.50, "USD", "Test payment")
+	payments.Save(ctx, stored)
+	created := mustCreatePayment(10.00, "USD", "Created payment")
+
+	err := uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		if err := repos.Payments.Save(ctx, stored); !errors.Is(err, payment.ErrConcurrentModification) {
+			t.Errorf("expected ErrConcurrentModification saving a stored payment, got %v", err)
+		}
+		if err := repos.Payments.Save(ctx, created); err != nil {
+			return err
+		}
+		return repos.Payments.Save(ctx, created)
+	})
+	if !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Errorf("expected ErrConcurrentModification saving a payment twice, got %v", err)
+	}
+}
+
+func TestMemoryUnitOfWork_ChainsAuditEntries(t *testing.T) {
+	audits := NewAuditMemoryRepository()
+	uow := NewMemoryUnitOfWork(NewPaymentMemoryRepository(), audits, NewOutboxMemoryRepository())
+	ctx := context.Background()
+
+	// Both transactions stage entries before either commits.
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+			repos.Audits.Save(ctx, audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeCreated, "user-123"))
+			<-release
+			return repos.Audits.Save(ctx, audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeProcessed, "user-123"))
+		})
+	}()
+
+	duplicate := audit.NewAuditEntry(audit.EntityTypePayment, "payment-2", audit.ActionTypeCreated, "user-123")
+	err := uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		if err := repos.Audits.Save(ctx, duplicate); err != nil {
+			return err
+		}
+		if err := repos.Audits.Save(ctx, duplicate); !errors.Is(err, audit.ErrDuplicateEntry) {
+			t.Errorf("expected ErrDuplicateEntry, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := audit.NewService(audits).Verify(ctx); err != nil {
+		t.Errorf("expected intact chain, got %v", err)
+	}
+	if entries, _ := audits.FindByFilter(ctx, audit.AuditFilter{}); len(entries) != 3 {
+		t.Errorf("expected 3 entries, got %d", len(entries))
+	}
+}
+
+func TestMemoryUnitOfWork_FiltersPayments(t *testing.T) {
+	payments := NewPaymentMemoryRepository()
+	uow := NewMemoryUnitOfWork(payments, NewAuditMemoryRepository(), NewOutboxMemoryRepository())
+	ctx := context.Background()
+
+	kept := mustCreatePayment(10, "USD", "Kept")
+	removed := mustCreatePayment(20, "USD", "Removed")
+	payments.Save(ctx, kept)
+	payments.Save(ctx, removed)
+
+	var got []string
+	uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		repos.Payments.Save(ctx, mustCreatePayment(30, "USD", "Staged"))
+		repos.Payments.Save(ctx, mustCreatePayment(40, "EUR", "Other currency"))
+		repos.Payments.Delete(ctx, removed.ID())
+
+		found, err := repos.Payments.FindByFilter(ctx, payment.PaymentFilter{Currency: "USD", SortBy: payment.SortByAmount})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, p := range found {
+			got = append(got, p.Description())
+		}
+		return errors.New("rollback")
+	})
+
+	if !slices.Equal(got, []string{"Kept", "Staged"}) {
+		t.Errorf("expected stored and staged payments without deleted ones, got %v", got)
+	}
+}
+
+func TestMemoryUnitOfWork_PagesAuditEntries(t *testing.T) {
+	audits := NewAuditMemoryRepository()
+	uow := NewMemoryUnitOfWork(NewPaymentMemoryRepository(), audits, NewOutboxMemoryRepository())
+	ctx := context.Background()
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minute int) *audit.AuditEntry {
+		snapshot := audit.NewAuditEntry(audit.EntityTypePayment, "payment-1", audit.ActionTypeUpdated, "user-123").ToSnapshot()
+		snapshot.Timestamp = start.Add(time.Duration(minute) * time.Minute)
+		entry, _ := audit.FromSnapshot(snapshot)
+		return entry
+	}
+	for _, minute := range []int{3, 0, 2} {
+		audits.Save(ctx, at(minute))
+	}
+
+	var minutes []int
+	uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		repos.Audits.Save(ctx, at(1))
+
+		filter := audit.AuditFilter{Limit: 2}
+		for {
+			page, err := audit.NewService(repos.Audits).GetAuditPage(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, entry := range page.Entries {
+				minutes = append(minutes, int(entry.Timestamp().Sub(start).Minutes()))
+			}
+			if filter.Cursor = page.NextCursor; filter.Cursor == "" {
+				return nil
+			}
+		}
+	})
+
+	if !slices.Equal(minutes, []int{0, 1, 2, 3}) {
+		t.Errorf("expected stored and staged entries in one order, got %v", minutes)
+	}
+
+	history, _ := audits.FindByEntityID(ctx, audit.EntityTypePayment, "payment-1")
+	for i := 1; i < len(history); i++ {
+		if history[i].Timestamp().Before(history[i-1].Timestamp()) {
+			t.Fatalf("expected chronological history, got %v before %v", history[i-1].Timestamp(), history[i].Timestamp())
+		}
+	}
+}
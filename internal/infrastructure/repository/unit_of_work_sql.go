@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"go-ddd/internal/application"
+)
+
+// SQLUnitOfWork runs each Do in a database transaction and hands the callback
+// SQL repositories bound to it, so payments, audit entries and outbox
+// messages commit together. Payments changed by someone else since the
+// transaction read them fail Update with ErrConcurrentModification, and
+// audit entries saved in the transaction hold the chain head until it ends.
+type SQLUnitOfWork struct {
+	db *sql.DB
+}
+
+func NewSQLUnitOfWork(db *sql.DB) *SQLUnitOfWork {
+	return &SQLUnitOfWork{db: db}
+}
+
+func (u *SQLUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos application.Repositories) error) error {
+	return inTransaction(ctx, u.db, func(tx *sql.Tx) error {
+		return fn(ctx, application.Repositories{
+			Payments: NewPaymentSQLRepository(tx),
+			Audits:   NewAuditSQLRepository(tx),
+			Outbox:   NewOutboxSQLRepository(tx),
+		})
+	})
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-ddd/internal/application"
+	"go-ddd/internal/application/outbox"
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/domain/payment"
+)
+
+func TestSQLUnitOfWork_Commit(t *testing.T) {
+	db := openTestDB(t)
+	payments, audits, messages := NewPaymentSQLRepository(db), NewAuditSQLRepository(db), NewOutboxSQLRepository(db)
+	uow := NewSQLUnitOfWork(db)
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.50, "USD", "Test payment")
+	message, _ := outbox.NewMessage("payment", testPayment.ID().String(), "payment.created", testPayment.ToSnapshot())
+	entry := audit.NewAuditEntry(audit.EntityTypePayment, testPayment.ID().String(), audit.ActionTypeCreated, "user-123")
+
+	err := uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		if err := repos.Payments.Save(ctx, testPayment); err != nil {
+			return err
+		}
+		if err := repos.Audits.Save(ctx, entry); err != nil {
+			return err
+		}
+		if err := repos.Outbox.Add(ctx, message); err != nil {
+			return err
+		}
+
+		// Writes are visible inside the transaction only.
+		if _, err := repos.Payments.FindByID(ctx, testPayment.ID()); err != nil {
+			t.Errorf("expected the payment to be readable, got %v", err)
+		}
+		history, _ := repos.Audits.FindByEntityID(ctx, audit.EntityTypePayment, testPayment.ID().String())
+		if len(history) != 1 {
+			t.Errorf("expected 1 audit entry, got %d", len(history))
+		}
+		if _, err := payments.FindByID(ctx, testPayment.ID()); !errors.Is(err, payment.ErrPaymentNotFound) {
+			t.Errorf("expected payment to be invisible before commit, got %v", err)
+		}
+		if pending, _ := messages.Pending(ctx, time.Now(), 0); len(pending) != 0 {
+			t.Errorf("expected outbox message to be invisible before commit, got %d", len(pending))
+		}
+
+		// A failed save rolls back to its savepoint, leaving earlier writes.
+		if err := repos.Audits.Save(ctx, entry); !errors.Is(err, audit.ErrDuplicateEntry) {
+			t.Errorf("expected ErrDuplicateEntry, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := payments.FindByID(ctx, testPayment.ID()); err != nil {
+		t.Errorf("expected committed payment, got %v", err)
+	}
+	stored, err := audits.FindByID(ctx, entry.ID())
+	if err != nil {
+		t.Fatalf("expected committed audit entry, got %v", err)
+	}
+	if stored.Sequence() != 1 || entry.Sequence() != 0 {
+		t.Errorf("expected the stored entry to be linked and the caller's left alone, got %d and %d", stored.Sequence(), entry.Sequence())
+	}
+	if pending, _ := messages.Pending(ctx, time.Now(), 0); len(pending) != 1 || pending[0].ID != message.ID {
+		t.Errorf("expected committed outbox message, got %v", pending)
+	}
+}
+
+func TestSQLUnitOfWork_Rollback(t *testing.T) {
+	db := openTestDB(t)
+	payments, audits, messages := NewPaymentSQLRepository(db), NewAuditSQLRepository(db), NewOutboxSQLRepository(db)
+	uow := NewSQLUnitOfWork(db)
+	ctx := context.Background()
+
+	existing := mustCreatePayment(10.00, "USD", "Existing payment")
+	payments.Save(ctx, existing)
+
+	created := mustCreatePayment(100.50, "USD", "Test payment")
+	auditFailure := errors.New("audit store unavailable")
+
+	err := uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		if err := repos.Payments.Save(ctx, created); err != nil {
+			return err
+		}
+		p, err := repos.Payments.FindByID(ctx, existing.ID())
+		if err != nil {
+			return err
+		}
+		p.Process()
+		if err := repos.Payments.Update(ctx, p); err != nil {
+			return err
+		}
+		if err := repos.Payments.Delete(ctx, existing.ID()); err != nil {
+			return err
+		}
+		message, _ := outbox.NewMessage("payment", created.ID().String(), "payment.created", created.ToSnapshot())
+		repos.Outbox.Add(ctx, message)
+		repos.Audits.Save(ctx, audit.NewAuditEntry(audit.EntityTypePayment, created.ID().String(), audit.ActionTypeCreated, "user-123"))
+		return auditFailure
+	})
+	if !errors.Is(err, auditFailure) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+
+	if _, err := payments.FindByID(ctx, created.ID()); !errors.Is(err, payment.ErrPaymentNotFound) {
+		t.Errorf("expected created payment to be rolled back, got %v", err)
+	}
+	stored, err := payments.FindByID(ctx, existing.ID())
+	if err != nil {
+		t.Fatalf("expected existing payment to survive, got %v", err)
+	}
+	if stored.Status() != payment.PaymentStatusPending || stored.Version() != 1 {
+		t.Errorf("expected untouched pending v1 payment, got %v v%d", stored.Status(), stored.Version())
+	}
+	history, _ := audits.FindByEntityID(ctx, audit.EntityTypePayment, created.ID().String())
+	if len(history) != 0 {
+		t.Errorf("expected no audit entries, got %d", len(history))
+	}
+	if pending, _ := messages.Pending(ctx, time.Now(), 0); len(pending) != 0 {
+		t.Errorf("expected no outbox messages, got %d", len(pending))
+	}
+}
+
+func TestSQLUnitOfWork_StaleUpdate(t *testing.T) {
+	db := openTestDB(t)
+	payments := NewPaymentSQLRepository(db)
+	uow := NewSQLUnitOfWork(db)
+	ctx := context.Background()
+
+	testPayment := mustCreatePayment(100.50, "USD", "Test payment")
+	payments.Save(ctx, testPayment)
+	stale, _ := payments.FindByID(ctx, testPayment.ID())
+
+	// Another writer commits first.
+	other, _ := payments.FindByID(ctx, testPayment.ID())
+	other.Cancel()
+	payments.Update(ctx, other)
+
+	err := uow.Do(ctx, func(ctx context.Context, repos application.Repositories) error {
+		stale.Process()
+		return repos.Payments.Update(ctx, stale)
+	})
+	if !errors.Is(err, payment.ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification, got %v", err)
+	}
+
+	stored, _ := payments.FindByID(ctx, testPayment.ID())
+	if stored.Status() != payment.PaymentStatusCancelled {
+		t.Errorf("expected the other writer's change to win, got %v", stored.Status())
+	}
+}
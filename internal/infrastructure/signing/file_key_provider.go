@@ -0,0 +1,238 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go-ddd/internal/domain/audit"
+)
+
+const (
+	privateKeySuffix = ".key"
+	publicKeySuffix  = ".pub"
+	currentKeyFile   = "current"
+)
+
+// FileKeyProvider is an audit.KeyProvider for local use that keeps Ed25519
+// keys as PEM files in a directory: <id>.key holds a PKCS #8 private key,
+// <id>.pub its PKIX public key, and the file "current" names the key new
+// signatures are made with. Key IDs are derived from the public key.
+type FileKeyProvider struct {
+	dir string
+
+	mu      sync.RWMutex
+	current string
+	private map[string]ed25519.PrivateKey
+	public  map[string]ed25519.PublicKey
+}
+
+// NewFileKeyProvider loads the keys in dir, creating dir and a first key if
+// there are none.
+func NewFileKeyProvider(dir string) (*FileKeyProvider, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	p := &FileKeyProvider{
+		dir:     dir,
+		private: make(map[string]ed25519.PrivateKey),
+		public:  make(map[string]ed25519.PublicKey),
+	}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+
+	if p.current == "" {
+		if _, err := p.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (p *FileKeyProvider) SigningKey() (string, ed25519.PrivateKey, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	key, ok := p.private[p.current]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: signing key %q has no private key", audit.ErrUnknownKey, p.current)
+	}
+	return p.current, key, nil
+}
+
+func (p *FileKeyProvider) PublicKey(keyID string) (ed25519.PublicKey, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return audit.PublicKeys(p.public).PublicKey(keyID)
+}
+
+// PublicKeys returns every key the provider can verify with, for handing to
+// whoever checks an exported log.
+func (p *FileKeyProvider) PublicKeys() audit.PublicKeys {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	keys := make(audit.PublicKeys, len(p.public))
+	for id, key := range p.public {
+		keys[id] = key
+	}
+	return keys
+}
+
+// Rotate generates a new key and makes it the signing key. Earlier keys stay
+// in the directory so entries they signed still verify.
+func (p *FileKeyProvider) Rotate() (string, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	id := KeyID(public)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFile(filepath.Join(p.dir, id+privateKeySuffix), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return "", err
+	}
+	der, err = x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFile(filepath.Join(p.dir, id+publicKeySuffix), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644); err != nil {
+		return "", err
+	}
+	if err := writeFile(filepath.Join(p.dir, currentKeyFile), []byte(id+"\n"), 0o600); err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.private[id] = private
+	p.public[id] = public
+	p.current = id
+	return id, nil
+}
+
+func (p *FileKeyProvider) load() error {
+	public, err := LoadPublicKeys(p.dir)
+	if err != nil {
+		return err
+	}
+	p.public = public
+
+	paths, err := filepath.Glob(filepath.Join(p.dir, "*"+privateKeySuffix))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		block, err := readPEM(path, "PRIVATE KEY")
+		if err != nil {
+			return err
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		private, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return fmt.Errorf("%s is not an Ed25519 key", path)
+		}
+		id := KeyID(private.Public().(ed25519.PublicKey))
+		p.private[id] = private
+		p.public[id] = private.Public().(ed25519.PublicKey)
+	}
+
+	current, err := os.ReadFile(filepath.Join(p.dir, currentKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read current key: %w", err)
+	}
+	p.current = strings.TrimSpace(string(current))
+	return nil
+}
+
+// LoadPublicKeys reads the public keys in dir, which is all an offline
+// verifier needs.
+func LoadPublicKeys(dir string) (audit.PublicKeys, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+publicKeySuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(audit.PublicKeys, len(paths))
+	for _, path := range paths {
+		block, err := readPEM(path, "PUBLIC KEY")
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		public, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+		}
+		keys[KeyID(public)] = public
+	}
+
+	return keys, nil
+}
+
+// KeyID names a public key by the first 8 bytes of its SHA-256.
+func KeyID(public ed25519.PublicKey) string {
+	sum := sha256.Sum256(public)
+	return "ed25519-" + hex.EncodeToString(sum[:8])
+}
+
+func readPEM(path, blockType string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s does not contain a PEM %s", path, blockType)
+	}
+	return block, nil
+}
+
+// writeFile replaces path atomically, so a crash never leaves a partial key
+// or a "current" file naming a key that was not written.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,70 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-ddd/internal/domain/audit"
+)
+
+func TestFileKeyProvider(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+
+	provider, err := NewFileKeyProvider(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first, key, err := provider.SigningKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	signature := ed25519.Sign(key, []byte("entry"))
+
+	info, err := os.Stat(filepath.Join(dir, first+privateKeySuffix))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected private key to be 0600, got %v", info.Mode().Perm())
+	}
+
+	second, err := provider.Rotate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second == first {
+		t.Fatal("expected rotation to change the key ID")
+	}
+
+	// A fresh provider picks up the rotated key as current.
+	reloaded, err := NewFileKeyProvider(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if current, _, _ := reloaded.SigningKey(); current != second {
+		t.Errorf("expected current key %s, got %s", second, current)
+	}
+
+	// The retired key still verifies, from the public key files alone.
+	public, err := LoadPublicKeys(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(public) != 2 {
+		t.Fatalf("expected 2 public keys, got %d", len(public))
+	}
+	retired, err := public.PublicKey(first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ed25519.Verify(retired, []byte("entry"), signature) {
+		t.Error("expected the retired key to verify its signature")
+	}
+
+	if _, err := reloaded.PublicKey("ed25519-unknown"); !errors.Is(err, audit.ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
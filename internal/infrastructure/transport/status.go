@@ -0,0 +1,76 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/domain/currency"
+	"go-ddd/internal/domain/payment"
+)
+
+// statusClientClosedRequest is the de facto status for requests the caller
+// abandoned before a response was written.
+const statusClientClosedRequest = 499
+
+type errorMapping struct {
+	target   error
+	httpCode int
+	grpcCode codes.Code
+}
+
+var errorMappings = []errorMapping{
+	{payment.ErrPaymentNotFound, http.StatusNotFound, codes.NotFound},
+	{audit.ErrAuditEntryNotFound, http.StatusNotFound, codes.NotFound},
+	{audit.ErrLegalHoldNotFound, http.StatusNotFound, codes.NotFound},
+	{payment.ErrInvalidAmount, http.StatusBadRequest, codes.InvalidArgument},
+	{currency.ErrUnknownCurrency, http.StatusBadRequest, codes.InvalidArgument},
+	{currency.ErrEmptyCurrency, http.StatusBadRequest, codes.InvalidArgument},
+	{payment.ErrInvalidAuthorizationExpiry, http.StatusBadRequest, codes.InvalidArgument},
+	{payment.ErrRefundReasonRequired, http.StatusBadRequest, codes.InvalidArgument},
+	{payment.ErrInvalidFilter, http.StatusBadRequest, codes.InvalidArgument},
+	{payment.ErrInvalidCursor, http.StatusBadRequest, codes.InvalidArgument},
+	{audit.ErrInvalidCursor, http.StatusBadRequest, codes.InvalidArgument},
+	{audit.ErrInvalidFilter, http.StatusBadRequest, codes.InvalidArgument},
+	{audit.ErrInvalidQuery, http.StatusBadRequest, codes.InvalidArgument},
+	{audit.ErrInvalidSnapshot, http.StatusBadRequest, codes.InvalidArgument},
+	{payment.ErrInvalidTransition{}, http.StatusConflict, codes.FailedPrecondition},
+	{payment.ErrAuthorizationExpired, http.StatusConflict, codes.FailedPrecondition},
+	{audit.ErrUnderLegalHold, http.StatusConflict, codes.FailedPrecondition},
+	{payment.ErrConcurrentModification, http.StatusConflict, codes.Aborted},
+	{audit.ErrOutOfSequence, http.StatusConflict, codes.Aborted},
+	{audit.ErrDuplicateEntry, http.StatusConflict, codes.AlreadyExists},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, codes.DeadlineExceeded},
+	{context.Canceled, statusClientClosedRequest, codes.Canceled},
+}
+
+// HTTPStatus maps a domain or application error to the HTTP status code a
+// handler should respond with. Unrecognised errors map to 500.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	for _, m := range errorMappings {
+		if errors.Is(err, m.target) {
+			return m.httpCode
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode maps a domain or application error to a gRPC status code.
+// Unrecognised errors map to codes.Internal.
+func GRPCCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	for _, m := range errorMappings {
+		if errors.Is(err, m.target) {
+			return m.grpcCode
+		}
+	}
+	return codes.Internal
+}
@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"go-ddd/internal/domain/audit"
+	"go-ddd/internal/domain/payment"
+)
+
+func TestStatusMapping(t *testing.T) {
+	_, amountErr := payment.ParseAmount("1.001", "USD")
+	_, currencyErr := payment.ParseAmount("1.00", "XYZ")
+	_, queryErr := audit.ParseQuery("action IN (")
+	filterErr := audit.AuditFilter{Limit: -1}.Validate()
+	paymentFilterErr := payment.PaymentFilter{Limit: -1}.Validate()
+	_, paymentCursorErr := payment.DecodeCursor("!")
+
+	tests := []struct {
+		name     string
+		err      error
+		wantHTTP int
+		wantGRPC codes.Code
+	}{
+		{name: "nil", err: nil, wantHTTP: http.StatusOK, wantGRPC: codes.OK},
+		{name: "payment not found", err: payment.ErrPaymentNotFound, wantHTTP: http.StatusNotFound, wantGRPC: codes.NotFound},
+		{name: "wrapped payment not found", err: fmt.Errorf("failed to get payment: %w", payment.ErrPaymentNotFound), wantHTTP: http.StatusNotFound, wantGRPC: codes.NotFound},
+		{name: "audit entry not found", err: audit.ErrAuditEntryNotFound, wantHTTP: http.StatusNotFound, wantGRPC: codes.NotFound},
+		{name: "legal hold not found", err: audit.ErrLegalHoldNotFound, wantHTTP: http.StatusNotFound, wantGRPC: codes.NotFound},
+		{name: "invalid amount", err: fmt.Errorf("invalid amount: %w", amountErr), wantHTTP: http.StatusBadRequest, wantGRPC: codes.InvalidArgument},
+		{name: "unknown currency", err: currencyErr, wantHTTP: http.StatusBadRequest, wantGRPC: codes.InvalidArgument},
+		{name: "invalid payment filter", err: paymentFilterErr, wantHTTP: http.StatusBadRequest, wantGRPC: codes.InvalidArgument},
+		{name: "invalid payment cursor", err: paymentCursorErr, wantHTTP: http.StatusBadRequest, wantGRPC: codes.InvalidArgument},
+		{name: "invalid audit cursor", err: fmt.Errorf("%w: bad position", audit.ErrInvalidCursor), wantHTTP: http.StatusBadRequest, wantGRPC: codes.InvalidArgument},
+		{name: "invalid audit filter", err: filterErr, wantHTTP: http.StatusBadRequest, wantGRPC: codes.InvalidArgument},
+		{name: "invalid audit query", err: queryErr, wantHTTP: http.StatusBadRequest, wantGRPC: codes.InvalidArgument},
+		{name: "invalid audit snapshot", err: fmt.Errorf("%w: missing ID", audit.ErrInvalidSnapshot), wantHTTP: http.StatusBadRequest, wantGRPC: codes.InvalidArgument},
+		{name: "invalid transition", err: payment.ErrInvalidTransition{From: payment.PaymentStatusCompleted, Event: payment.EventCancel}, wantHTTP: http.StatusConflict, wantGRPC: codes.FailedPrecondition},
+		{name: "authorization expired", err: payment.ErrAuthorizationExpired, wantHTTP: http.StatusConflict, wantGRPC: codes.FailedPrecondition},
+		{name: "under legal hold", err: fmt.Errorf("%w: entry-1", audit.ErrUnderLegalHold), wantHTTP: http.StatusConflict, wantGRPC: codes.FailedPrecondition},
+		{name: "concurrent modification", err: payment.ErrConcurrentModification, wantHTTP: http.StatusConflict, wantGRPC: codes.Aborted},
+		{name: "audit sequence taken", err: fmt.Errorf("%w: sequence 7 of entry entry-1 is taken", audit.ErrOutOfSequence), wantHTTP: http.StatusConflict, wantGRPC: codes.Aborted},
+		{name: "duplicate audit entry", err: fmt.Errorf("%w: entry-1", audit.ErrDuplicateEntry), wantHTTP: http.StatusConflict, wantGRPC: codes.AlreadyExists},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, wantHTTP: http.StatusGatewayTimeout, wantGRPC: codes.DeadlineExceeded},
+		{name: "unknown error", err: errors.New("boom"), wantHTTP: http.StatusInternalServerError, wantGRPC: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.wantHTTP {
+				t.Errorf("expected HTTP status %d, got %d", tt.wantHTTP, got)
+			}
+			if got := GRPCCode(tt.err); got != tt.wantGRPC {
+				t.Errorf("expected gRPC code %v, got %v", tt.wantGRPC, got)
+			}
+		})
+	}
+}
@@ -1,38 +1,77 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"go-ddd/internal/application"
+	"go-ddd/internal/application/eventbus"
+	"go-ddd/internal/application/outbox"
 	"go-ddd/internal/domain/audit"
 	"go-ddd/internal/domain/payment"
+	"go-ddd/internal/infrastructure/archive"
+	"go-ddd/internal/infrastructure/messaging"
 	"go-ddd/internal/infrastructure/repository"
+	"go-ddd/internal/infrastructure/signing"
 )
 
 func main() {
 	ctx := context.Background()
 
 	paymentRepo := repository.NewPaymentMemoryRepository()
-	auditRepo := repository.NewAuditMemoryRepository()
+	legalHolds := repository.NewLegalHoldMemoryRepository()
+	auditRepo := repository.NewAuditMemoryRepository(repository.WithLegalHolds(legalHolds))
 
-	paymentDomainService := payment.NewService(paymentRepo)
-	auditDomainService := audit.NewService(auditRepo)
+	outboxRepo := repository.NewOutboxMemoryRepository()
 
-	paymentAppService := application.NewPaymentApplicationService(paymentDomainService, auditDomainService)
+	unitOfWork := repository.NewMemoryUnitOfWork(paymentRepo, auditRepo, outboxRepo)
 
-	fmt.Println("=== Payment Service with Audit Demo ===\n")
+	keyDir, err := os.MkdirTemp("", "audit-keys-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(keyDir)
+
+	keys, err := signing.NewFileKeyProvider(keyDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// In production the salt comes from configuration so hashed values stay
+	// comparable across restarts.
+	salt := make([]byte, 32)
+	rand.Read(salt)
+	redactor, err := audit.NewRedactor(salt, audit.WithDefaultPolicy(audit.RedactionPolicy{
+		Detectors: audit.DefaultDetectors(),
+	}))
+	if err != nil {
+		log.Fatal(err)
+	}
+	audits := audit.NewService(auditRepo, audit.WithKeyProvider(keys), audit.WithRedactor(redactor))
+
+	bus := eventbus.New()
+	application.NewAuditSubscriber(audits).Subscribe(bus)
+
+	paymentAppService := application.NewPaymentApplicationService(unitOfWork, application.ReadModel{Payments: paymentRepo, Audits: auditRepo}, bus,
+		application.WithPayloadRedactor(redactor))
+
+	fmt.Println("=== Payment Service with Audit Demo ===")
+	fmt.Println()
 
 	userID := "user-123"
 
 	fmt.Println("1. Creating a payment...")
-	p, err := paymentAppService.CreatePayment(ctx, 100.50, "USD", "Online purchase", userID)
+	p, err := paymentAppService.CreatePayment(ctx, "100.50", "USD", "Online purchase for jane@example.com", userID)
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Printf("Created payment: ID=%s, Amount=%.2f %s, Status=%s\n\n",
-		p.ID().String(), p.Amount().Value(), p.Amount().Currency(), p.Status().String())
+	fmt.Printf("Created payment: ID=%s, Amount=%s, Status=%s\n\n",
+		p.ID().String(), p.Amount().String(), p.Status().String())
 
 	paymentID := p.ID().String()
 
@@ -41,14 +80,28 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("Payment processed successfully\n")
+	fmt.Println("Payment processed successfully")
+	fmt.Println()
 
 	fmt.Println("3. Completing the payment...")
 	err = paymentAppService.CompletePayment(ctx, paymentID, userID)
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("Payment completed successfully\n")
+	fmt.Println("Payment completed successfully")
+
+	page, err := paymentAppService.ListPayments(ctx, payment.PaymentFilter{
+		Statuses: []payment.PaymentStatus{payment.PaymentStatusCompleted},
+		Currency: "USD",
+		SortBy:   payment.SortByAmount,
+		Sort:     payment.SortDescending,
+		Limit:    10,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Found %d completed USD payments\n", len(page.Payments))
+	fmt.Println()
 
 	fmt.Println("4. Retrieving payment audit history...")
 	auditEntries, err := paymentAppService.GetPaymentAuditHistory(ctx, paymentID)
@@ -60,10 +113,99 @@ func main() {
 	for i, entry := range auditEntries {
 		fmt.Printf("  %d. Action: %s, User: %s, Time: %s\n",
 			i+1, entry.Action(), entry.UserID(), entry.Timestamp().Format("2006-01-02 15:04:05"))
-		if len(entry.NewData()) > 0 {
-			fmt.Printf("     New Data: %+v\n", entry.NewData())
+		for _, change := range entry.Changes() {
+			fmt.Printf("     %s\n", change)
 		}
 	}
 
+	query := `action IN (created, processed) AND newData.amount > 100`
+	matching, err := audits.GetAuditsByQuery(ctx, query)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Query %q matches %d entries\n", query, len(matching))
+
+	fmt.Println()
+	fmt.Println("5. Relaying outbox events...")
+	publisher := messaging.NewMemoryPublisher()
+	published, err := outbox.NewRelay(outboxRepo, publisher).RunOnce(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Published %d events:\n", published)
+	for i, message := range publisher.Messages() {
+		fmt.Printf("  %d. %s for %s %s\n", i+1, message.EventType, message.AggregateType, message.AggregateID)
+	}
+
+	fmt.Println()
+	fmt.Println("6. Reconciling the audit log with stored payments...")
+	reconciler := application.NewPaymentReconciler(auditRepo, paymentRepo, audit.WithReconcileRedactor(redactor))
+	inconsistencies, err := reconciler.ReconcileAll(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Found %d inconsistencies\n", len(inconsistencies))
+	for _, inconsistency := range inconsistencies {
+		fmt.Printf("  - %s\n", inconsistency)
+	}
+
+	fmt.Println()
+	fmt.Println("7. Verifying the audit log...")
+	if err := audits.Verify(ctx); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Audit chain and signatures are intact")
+
+	var exported bytes.Buffer
+	if err := audits.Export(ctx, &exported); err != nil {
+		log.Fatal(err)
+	}
+	publicKeys, err := signing.LoadPublicKeys(keyDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	checkpoint, verified, err := audit.VerifyExport(&exported, publicKeys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Exported log verified offline: %d entries up to sequence %d, signed by %s\n", verified, checkpoint.Sequence, checkpoint.KeyID)
+
+	fmt.Println()
+	fmt.Println("8. Archiving audit entries past their retention...")
+	archiveDir, err := os.MkdirTemp("", "audit-archive-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(archiveDir)
+
+	archiveStore, err := archive.NewFileStore(archiveDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Intermediate status changes need not stay online; everything else about
+	// a payment is kept for seven years.
+	retention := audit.RetentionPolicy{Rules: []audit.RetentionRule{
+		{EntityType: audit.EntityTypePayment, Retain: 7 * 365 * 24 * time.Hour},
+		{EntityType: audit.EntityTypePayment, Action: audit.ActionTypeProcessed},
+	}}
+	archiver := audit.NewArchiver(audits, auditRepo, archiveStore, legalHolds, retention)
+	archived, err := archiver.RunOnce(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Archived %d entries to %s\n", archived.Archived, archived.Location)
+	if err := audits.Verify(ctx); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Audit chain still verifies across the archived entries")
+	if inconsistencies, err = reconciler.ReconcileAll(ctx); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Reconciliation still finds %d inconsistencies\n", len(inconsistencies))
+
+	if err := bus.Close(ctx); err != nil {
+		log.Fatal(err)
+	}
+
 	fmt.Println("\n=== Demo completed successfully! ===")
 }